@@ -0,0 +1,97 @@
+package commands
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"slsh/config"
+	"slsh/slurm"
+	"slsh/utils"
+)
+
+// AliasCommand implements the 'alias' command
+type AliasCommand struct {
+	config *config.Config
+}
+
+// NewAliasCommand creates a new alias command
+func NewAliasCommand(cfg *config.Config) *AliasCommand {
+	return &AliasCommand{
+		config: cfg,
+	}
+}
+
+// Execute executes the alias command
+func (a *AliasCommand) Execute(cmd *slurm.Command, shell ShellInterface) error {
+	if name, remove := cmd.Options["-d"]; remove {
+		if name == "" && len(cmd.Args) > 0 {
+			name = cmd.Args[0]
+		}
+		if name == "" {
+			return fmt.Errorf("usage: alias -d <name>")
+		}
+		shell.RemoveAlias(name)
+		fmt.Printf("Alias %s removed\n", name)
+		return nil
+	}
+
+	if len(cmd.Args) == 0 {
+		a.listAliases(shell.GetAliases())
+		return nil
+	}
+
+	if len(cmd.Args) == 1 {
+		command, exists := shell.GetAliases()[cmd.Args[0]]
+		if !exists {
+			return fmt.Errorf("alias not found: %s", cmd.Args[0])
+		}
+		fmt.Printf("%s = %s\n", cmd.Args[0], command)
+		return nil
+	}
+
+	name := cmd.Args[0]
+	command := strings.Join(cmd.Args[1:], " ")
+	shell.AddAlias(name, command)
+	fmt.Printf("Alias %s = %s\n", name, command)
+	return nil
+}
+
+// listAliases prints all aliases sorted by name
+func (a *AliasCommand) listAliases(aliases map[string]string) {
+	if len(aliases) == 0 {
+		fmt.Println("No aliases defined")
+		return
+	}
+
+	names := make([]string, 0, len(aliases))
+	for name := range aliases {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	table := utils.NewTable([]string{"Alias", "Command"}, a.config.ColorOutput)
+	for _, name := range names {
+		table.AddRow([]string{name, aliases[name]})
+	}
+	table.Print()
+}
+
+// Description returns the command description
+func (a *AliasCommand) Description() string {
+	return "Manage command aliases"
+}
+
+// Usage returns the command usage
+func (a *AliasCommand) Usage() string {
+	return `alias [name [command]]
+alias -d <name>
+
+Without arguments, lists all aliases. With a name, shows that alias.
+With a name and a command, defines a new alias.
+
+Examples:
+  alias                           # List aliases
+  alias myrun "run -N 4 -p gpu"   # Define an alias
+  alias -d myrun                  # Remove an alias`
+}
@@ -0,0 +1,166 @@
+package commands
+
+import (
+	"fmt"
+	"time"
+
+	"slsh/config"
+	"slsh/slurm"
+	"slsh/utils"
+)
+
+// AllocCommand implements the 'alloc' command
+type AllocCommand struct {
+	client *slurm.Client
+	config *config.Config
+}
+
+// NewAllocCommand creates a new alloc command
+func NewAllocCommand(client *slurm.Client, cfg *config.Config) *AllocCommand {
+	return &AllocCommand{
+		client: client,
+		config: cfg,
+	}
+}
+
+// Execute executes the alloc command
+func (a *AllocCommand) Execute(cmd *slurm.Command, shell ShellInterface) error {
+	if len(cmd.Args) > 0 {
+		return fmt.Errorf("usage: alloc [options]; use run to start commands in the allocation")
+	}
+	if shell == nil {
+		return fmt.Errorf("allocations need an interactive shell")
+	}
+	if alloc := shell.Allocation(); alloc != nil {
+		return fmt.Errorf("already inside allocation %s; release it first", alloc.JobID)
+	}
+
+	jobOpts := parseJobOptions(cmd, nil)
+	if err := resolveNodeLists(jobOpts); err != nil {
+		return err
+	}
+	if jobOpts.Array != "" {
+		return fmt.Errorf("job arrays run as batch jobs; use submit --array")
+	}
+	applyDefaults(jobOpts, a.config, nil)
+
+	fmt.Println("Requesting an allocation:")
+	printResourceRequest(jobOpts, nil)
+	fmt.Println("Waiting for resources (Ctrl+C to give up)...")
+
+	// salloc names the job as soon as it is queued, so that it can be
+	// followed while it waits
+	printLine := streamPrinter(a.config.ColorOutput)
+	tracked := false
+	alloc, err := a.client.Allocate(jobOpts, func(line slurm.OutputLine) {
+		if jobID, ok := slurm.ParseSubmittedJobID(line.Text); ok && !tracked {
+			shell.TrackJob(jobID, "alloc")
+			tracked = true
+		}
+		printLine(line)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get an allocation: %v", err)
+	}
+	if !tracked {
+		shell.TrackJob(alloc.JobID, "alloc")
+	}
+	shell.SetAllocation(alloc)
+
+	fmt.Println()
+	description := "Inside allocation " + alloc.JobID
+	if alloc.NodeList != "" {
+		description += " on " + alloc.NodeList
+	}
+	if !alloc.End.IsZero() {
+		description += " until " + alloc.End.Format(time.TimeOnly)
+	}
+	fmt.Println(utils.FormatSuccess(description, a.config.ColorOutput))
+	fmt.Println("'run' starts job steps in the allocation; 'release' ends it")
+	return nil
+}
+
+// Description returns the command description
+func (a *AllocCommand) Description() string {
+	return "Obtain an allocation with salloc and run job steps in it"
+}
+
+// Usage returns the command usage
+func (a *AllocCommand) Usage() string {
+	return `alloc [options]
+
+Obtain resources with salloc and keep them for the rest of the session.
+Inside the allocation, run starts commands as job steps on the granted
+nodes without waiting in the queue, and the prompt shows the job ID and
+the walltime left. release gives the resources back; leaving the shell
+releases them too.
+
+Your configured defaults apply to the allocation, not to the steps run
+in it.
+
+Options:
+  -J, --job-name <name>           Job name
+  -p, --partition <partition>     Partition to use
+  -N, --nodes <count>             Number of nodes
+  -c, --cpus-per-task <count>     CPUs per task
+  --mem <memory>                  Memory per node
+  -t, --time <time>               Time limit (HH:MM:SS)
+  -A, --account <account>         Account to charge
+  -w, --nodelist <hosts>          Allocate these nodes
+
+Examples:
+  alloc -N 2 -t 2:00:00           # Two nodes for two hours
+  run hostname                    # Runs on the allocated nodes
+  release                         # Give the nodes back`
+}
+
+// ReleaseCommand implements the 'release' command
+type ReleaseCommand struct {
+	client *slurm.Client
+	config *config.Config
+}
+
+// NewReleaseCommand creates a new release command
+func NewReleaseCommand(client *slurm.Client, cfg *config.Config) *ReleaseCommand {
+	return &ReleaseCommand{
+		client: client,
+		config: cfg,
+	}
+}
+
+// Execute executes the release command
+func (r *ReleaseCommand) Execute(cmd *slurm.Command, shell ShellInterface) error {
+	alloc := currentAllocation(shell)
+	if alloc == nil {
+		return fmt.Errorf("not inside an allocation")
+	}
+
+	if _, err := r.client.CancelJob(alloc.JobID); err != nil {
+		return fmt.Errorf("failed to release allocation %s: %v", alloc.JobID, err)
+	}
+	shell.SetAllocation(nil)
+
+	fmt.Printf("Released allocation %s\n", alloc.JobID)
+	return nil
+}
+
+// Description returns the command description
+func (r *ReleaseCommand) Description() string {
+	return "End the allocation obtained with alloc"
+}
+
+// Usage returns the command usage
+func (r *ReleaseCommand) Usage() string {
+	return `release
+
+Cancel the allocation the shell is inside of, giving its resources back,
+and leave allocation mode.`
+}
+
+// currentAllocation returns the allocation the shell is inside of, or nil
+func currentAllocation(shell ShellInterface) *slurm.Allocation {
+	if shell == nil {
+		return nil
+	}
+	return shell.Allocation()
+}
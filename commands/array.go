@@ -0,0 +1,259 @@
+package commands
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"slsh/config"
+	"slsh/slurm"
+	"slsh/utils"
+)
+
+// failedTaskStates are the states of array tasks worth resubmitting
+var failedTaskStates = map[string]bool{
+	slurm.JobStateFailed:  true,
+	slurm.JobStateTimeout: true,
+	"OUT_OF_MEMORY":       true,
+	"NODE_FAIL":           true,
+	"BOOT_FAIL":           true,
+	"DEADLINE":            true,
+	"PREEMPTED":           true,
+}
+
+// arrayOptions are the options of the array command itself; all others
+// are job options for resubmitted tasks
+var arrayOptions = map[string]bool{
+	"--resubmit": true,
+	"--failed":   true,
+}
+
+// ArrayCommand implements the 'array' command
+type ArrayCommand struct {
+	client *slurm.Client
+	config *config.Config
+}
+
+// NewArrayCommand creates a new array command
+func NewArrayCommand(client *slurm.Client, cfg *config.Config) *ArrayCommand {
+	return &ArrayCommand{
+		client: client,
+		config: cfg,
+	}
+}
+
+// Execute executes the array command
+func (a *ArrayCommand) Execute(cmd *slurm.Command, shell ShellInterface) error {
+	if len(cmd.Args) == 0 {
+		return fmt.Errorf("usage: array <job_id> [--failed] [--resubmit [script]]")
+	}
+
+	// A task ID stands for its array
+	jobID, _, _ := strings.Cut(cmd.Args[0], "_")
+	if !jobIDPattern.MatchString(jobID) {
+		return fmt.Errorf("invalid job ID: %s", cmd.Args[0])
+	}
+
+	tasks, err := a.client.ArrayTasks(jobID)
+	if err != nil {
+		return fmt.Errorf("failed to get array tasks: %v", err)
+	}
+	if len(tasks) == 0 {
+		return fmt.Errorf("job %s is not a job array known to sacct", jobID)
+	}
+
+	if _, ok := cmd.Options["--resubmit"]; ok {
+		return a.resubmit(cmd, jobID, tasks, shell)
+	}
+
+	fmt.Printf("Array %s (%s): %s\n", jobID, tasks[0].Name, countOf(len(tasks), "task"))
+	fmt.Printf("  %s\n", summarizeTasks(tasks))
+
+	var shown []slurm.ArrayTask
+	for _, task := range tasks {
+		if hasOption(cmd, "--failed") && !failedTaskStates[task.State] {
+			continue
+		}
+		if task.State != slurm.JobStatePending {
+			shown = append(shown, task)
+		}
+	}
+	if len(shown) == 0 {
+		return nil
+	}
+
+	fmt.Println()
+	table := utils.NewTable([]string{"TASK", "STATE", "EXIT", "ELAPSED", "NODE"}, a.config.ColorOutput)
+	for _, task := range shown {
+		exit := "-"
+		if slurm.IsTerminalJobState(task.State) {
+			exit = formatExitCode(task.ExitCode, task.Signal)
+		}
+		table.AddRow([]string{
+			fmt.Sprint(task.Index),
+			utils.FormatJobState(task.State, a.config.ColorOutput),
+			exit,
+			slurm.FormatSlurmDuration(task.Elapsed),
+			task.NodeList,
+		})
+	}
+	table.Print()
+	return nil
+}
+
+// resubmit submits the failed tasks of an array again, as a new array of
+// just those indices. The script is the one the controller reports for
+// the array unless another is given.
+func (a *ArrayCommand) resubmit(cmd *slurm.Command, jobID string, tasks []slurm.ArrayTask, shell ShellInterface) error {
+	var failed []int
+	var last slurm.ArrayTask
+	for _, task := range tasks {
+		if failedTaskStates[task.State] {
+			failed = append(failed, task.Index)
+			last = task
+		}
+	}
+	if len(failed) == 0 {
+		fmt.Printf("No failed tasks in array %s\n", jobID)
+		return nil
+	}
+
+	script := cmd.Options["--resubmit"]
+	if script == "" {
+		fields, err := a.client.ShowJob(jobID)
+		if err != nil {
+			return fmt.Errorf("failed to look up the script: %v", err)
+		}
+		script = nullValue(fields["Command"])
+		if script == "" {
+			return fmt.Errorf("the controller no longer knows the script of job %s; use --resubmit <script>", jobID)
+		}
+	}
+
+	// The new array asks for what the failed tasks had, unless the
+	// command line says otherwise
+	opts := &slurm.JobOptions{
+		Name:      last.Name,
+		Partition: last.Partition,
+		Account:   last.Account,
+		WorkDir:   last.WorkDir,
+		Array:     slurm.FormatArrayIndices(failed),
+	}
+	if _, err := slurm.ParseSlurmDuration(last.TimeLimit); err == nil {
+		opts.Time = last.TimeLimit
+	}
+
+	override := parseJobOptions(cmd, arrayOptions)
+	if err := resolveNodeLists(override); err != nil {
+		return err
+	}
+	mergeJobOptions(opts, override)
+	if err := validateArray(opts.Array); err != nil {
+		return err
+	}
+
+	fmt.Printf("Resubmitting %s of array %s: %s\n", countOf(len(failed), "task"), jobID, slurm.FormatArrayIndices(failed))
+	fmt.Printf("  %-11s %s\n", "Script:", script)
+	printResourceRequest(opts, nil)
+
+	result, err := a.client.SubmitJob(script, opts)
+	if err != nil {
+		return fmt.Errorf("failed to submit job: %v", err)
+	}
+	if result.Output != "" {
+		fmt.Print(result.Output)
+	}
+
+	if newID, _, ok := slurm.ParseSubmittedJob(result.Output); ok && shell != nil {
+		shell.TrackJob(newID, opts.Name)
+	}
+	return nil
+}
+
+// summarizeTasks counts the tasks of an array by state, listing the
+// indices of tasks that failed, as in "8 COMPLETED, 2 FAILED [3,7]"
+func summarizeTasks(tasks []slurm.ArrayTask) string {
+	indices := make(map[string][]int)
+	for _, task := range tasks {
+		indices[task.State] = append(indices[task.State], task.Index)
+	}
+
+	states := make([]string, 0, len(indices))
+	for state := range indices {
+		states = append(states, state)
+	}
+	sort.Slice(states, func(i, j int) bool {
+		ri, rj := taskStateRank(states[i]), taskStateRank(states[j])
+		if ri != rj {
+			return ri < rj
+		}
+		return states[i] < states[j]
+	})
+
+	parts := make([]string, len(states))
+	for i, state := range states {
+		parts[i] = fmt.Sprintf("%d %s", len(indices[state]), state)
+		if failedTaskStates[state] {
+			parts[i] += " [" + slurm.FormatArrayIndices(indices[state]) + "]"
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// taskStateRank orders states from finished to waiting
+func taskStateRank(state string) int {
+	switch {
+	case state == slurm.JobStateCompleted:
+		return 0
+	case slurm.IsTerminalJobState(state):
+		return 1
+	case state == slurm.JobStateRunning:
+		return 3
+	case state == slurm.JobStatePending:
+		return 4
+	}
+	return 2
+}
+
+// nullValue returns value, or nothing when scontrol prints it as unset
+func nullValue(value string) string {
+	if value == "(null)" || value == "N/A" {
+		return ""
+	}
+	return value
+}
+
+// Flags returns the options that take no value
+func (a *ArrayCommand) Flags() []string {
+	return []string{"--failed"}
+}
+
+// Description returns the command description
+func (a *ArrayCommand) Description() string {
+	return "Summarize the tasks of a job array"
+}
+
+// Usage returns the command usage
+func (a *ArrayCommand) Usage() string {
+	return `array <job_id> [--failed]
+array <job_id> --resubmit [script] [options]
+
+Show how many tasks of a job array are in each state, with the indices
+of the tasks that failed, and the state and exit code of every task that
+has started.
+
+With --resubmit, the failed tasks (FAILED, TIMEOUT, OUT_OF_MEMORY,
+NODE_FAIL, ...) are submitted again as a new array of just those indices.
+The script is the one the array ran, as long as the controller remembers
+it; otherwise give it after --resubmit. Job options override those of
+the original tasks.
+
+Options:
+  --failed                        List only the failed tasks
+  --resubmit [script]             Resubmit the failed tasks
+
+Examples:
+  array 1005                      # Summary and exit codes of array 1005
+  array %last --failed            # Failed tasks of the last submitted job
+  array 1005 --resubmit -t 2:00:00  # Retry failed tasks with more time`
+}
@@ -1,9 +1,9 @@
 package commands
 
 import (
-	"fmt"
 	"sort"
 
+	"slsh/config"
 	"slsh/slurm"
 )
 
@@ -14,15 +14,37 @@ type CommandHandler interface {
 	Usage() string
 }
 
+// FlagCommand is implemented by commands with options that never take a
+// value, such as --all
+type FlagCommand interface {
+	Flags() []string
+}
+
+// HandlerFlags returns the options of a handler that never take a value
+func HandlerFlags(handler CommandHandler) map[string]bool {
+	flagCommand, ok := handler.(FlagCommand)
+	if !ok {
+		return nil
+	}
+	flags := make(map[string]bool)
+	for _, flag := range flagCommand.Flags() {
+		flags[flag] = true
+	}
+	return flags
+}
+
 // ShellInterface defines the interface that commands can use to interact with the shell
 type ShellInterface interface {
-	GetConfig() interface{}
-	GetHistory() interface{}
+	GetConfig() *config.Config
 	GetClient() *slurm.Client
 	Stop()
 	AddAlias(name, command string)
 	RemoveAlias(name string)
 	GetAliases() map[string]string
+	TrackJob(jobID, name string)
+	Confirm(question string) bool
+	Allocation() *slurm.Allocation
+	SetAllocation(alloc *slurm.Allocation)
 }
 
 // Registry manages command registration and execution
@@ -59,6 +81,16 @@ func (r *Registry) GetCommand(name string) (CommandHandler, bool) {
 	return handler, exists
 }
 
+// Flags returns the options of a command that never take a value. Slurm
+// and system commands have none, so their options reach them as typed.
+func (r *Registry) Flags(name string) map[string]bool {
+	handler, exists := r.commands[name]
+	if !exists {
+		return nil
+	}
+	return HandlerFlags(handler)
+}
+
 // GetCommandNames returns a sorted list of all command names
 func (r *Registry) GetCommandNames() []string {
 	names := make([]string, 0, len(r.commands))
@@ -94,21 +126,14 @@ func (r *Registry) executeSystemCommand(cmd *slurm.Command, shell ShellInterface
 	}
 	
 	if isSlurmCommand {
-		// Execute as Slurm command
+		// Interactive commands get the terminal, everything else is
+		// printed as it arrives
 		args := buildArgs(cmd)
-		result, err := client.Execute(cmd.Name, args...)
-		if err != nil {
-			return err
-		}
-		
-		if result.Output != "" {
-			fmt.Print(result.Output)
-		}
-		if result.Error != "" {
-			fmt.Print(result.Error)
+		if slurm.NeedsTerminal(cmd.Name, args) {
+			return client.ExecuteTerminal(cmd.Name, args...)
 		}
-		
-		return nil
+		_, err := client.ExecuteStream(streamPrinter(shell.GetConfig().ColorOutput), cmd.Name, args...)
+		return err
 	}
 	
 	// Execute as interactive system command
@@ -121,15 +146,41 @@ func buildArgs(cmd *slurm.Command) []string {
 	var args []string
 	
 	// Add options
-	for opt, value := range cmd.Options {
+	for _, opt := range optionOrder(cmd) {
 		args = append(args, opt)
-		if value != "" {
+		if value := cmd.Options[opt]; value != "" {
 			args = append(args, value)
 		}
 	}
 	
+	// Keep the command after "--" from being read as options
+	if cmd.EndOfOptions {
+		args = append(args, "--")
+	}
+	
 	// Add positional arguments
 	args = append(args, cmd.Args...)
 	
 	return args
+}
+
+// optionOrder returns the options of a command in the order they were
+// given, followed by any set without an order, sorted
+func optionOrder(cmd *slurm.Command) []string {
+	opts := make([]string, 0, len(cmd.Options))
+	seen := make(map[string]bool)
+	for _, opt := range cmd.OptionOrder {
+		if _, ok := cmd.Options[opt]; ok && !seen[opt] {
+			opts = append(opts, opt)
+			seen[opt] = true
+		}
+	}
+	var rest []string
+	for opt := range cmd.Options {
+		if !seen[opt] {
+			rest = append(rest, opt)
+		}
+	}
+	sort.Strings(rest)
+	return append(opts, rest...)
 }
\ No newline at end of file
@@ -2,36 +2,297 @@ package commands
 
 import (
 	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	
+	"slsh/config"
 	"slsh/slurm"
+	"slsh/slurm/hostlist"
 )
 
+// jobIDPattern matches a job ID or a single array task, as in "1234_5"
+var jobIDPattern = regexp.MustCompile(`^\d+(_\d+)?$`)
+
+// jobRangePattern matches a range of job IDs such as "1001-1005"
+var jobRangePattern = regexp.MustCompile(`^\d+-\d+$`)
+
 type CancelCommand struct {
 	client *slurm.Client
+	config *config.Config
 }
 
-func NewCancelCommand(client *slurm.Client) *CancelCommand {
-	return &CancelCommand{client: client}
+func NewCancelCommand(client *slurm.Client, cfg *config.Config) *CancelCommand {
+	return &CancelCommand{client: client, config: cfg}
 }
 
 func (c *CancelCommand) Execute(cmd *slurm.Command, shell ShellInterface) error {
-	if len(cmd.Args) == 0 {
-		return fmt.Errorf("usage: cancel <job_id>")
+	selected, err := parseJobSelectors(cmd.Args)
+	if err != nil {
+		return err
 	}
 	
-	jobID := cmd.Args[0]
-	_, err := c.client.CancelJob(jobID)
+	filter := cancelFilter(cmd)
+	if len(selected) == 0 && filter == nil {
+		return fmt.Errorf("usage: cancel <job_id>... | -p <partition> | -t <state> | -n <pattern>")
+	}
+	
+	// A single job is cancelled right away, as it always was
+	if filter == nil && len(selected) == 1 {
+		for jobID := range selected {
+			if _, err := c.client.CancelJob(jobID); err != nil {
+				return fmt.Errorf("failed to cancel job: %v", err)
+			}
+			fmt.Printf("Job %s cancelled\n", jobID)
+		}
+		return nil
+	}
+	
+	jobs, err := c.selectJobs(selected, filter)
 	if err != nil {
-		return fmt.Errorf("failed to cancel job: %v", err)
+		return fmt.Errorf("failed to list jobs: %v", err)
+	}
+	if len(jobs) == 0 {
+		fmt.Println("No matching jobs in the queue")
+		return nil
 	}
 	
-	fmt.Printf("Job %s cancelled\n", jobID)
+	printJobTable(jobs, c.config.ColorOutput)
+	
+	var targets []string
+	for _, job := range jobs {
+		targets = append(targets, cancelTargets(job, selected)...)
+	}
+	count := countOf(countTargets(targets), "job")
+	
+	if c.config.ConfirmDangerous && !hasOption(cmd, "-y", "--yes") {
+		if shell == nil {
+			return fmt.Errorf("cancelling %s needs confirmation; use --yes", count)
+		}
+		if !shell.Confirm(fmt.Sprintf("Cancel %s?", count)) {
+			fmt.Println("No jobs cancelled")
+			return nil
+		}
+	}
+	
+	if _, err := c.client.CancelJobs(targets); err != nil {
+		return fmt.Errorf("failed to cancel jobs: %v", err)
+	}
+	
+	fmt.Printf("Cancelled %s\n", count)
 	return nil
 }
 
+// cancelFilter builds a queue filter from the selection options, or
+// returns nil when none was given. Only the current user's jobs are
+// selected unless -u names other users.
+func cancelFilter(cmd *slurm.Command) *slurm.JobFilter {
+	filter := &slurm.JobFilter{}
+	found := false
+	
+	if value, ok := optionValue(cmd, "-p", "--partition"); ok {
+		filter.Partitions = splitList(value)
+		found = true
+	}
+	if value, ok := optionValue(cmd, "-t", "--state"); ok {
+		filter.States = splitList(value)
+		found = true
+	}
+	if value, ok := optionValue(cmd, "-n", "--name"); ok {
+		filter.Names = splitList(value)
+		found = true
+	}
+	if value, ok := optionValue(cmd, "-A", "--account"); ok {
+		filter.Accounts = splitList(value)
+		found = true
+	}
+	if value, ok := optionValue(cmd, "-u", "--user"); ok {
+		filter.Users = splitList(value)
+		found = true
+	}
+	
+	if !found {
+		return nil
+	}
+	if len(filter.Users) == 0 {
+		if user := os.Getenv("USER"); user != "" {
+			filter.Users = []string{user}
+		}
+	}
+	return filter
+}
+
+// parseJobSelectors expands job ID arguments into the set of jobs and
+// array tasks they name. Arguments may be comma separated lists, ranges
+// such as "1001-1005" and array task expressions such as "1234_[4-9]".
+func parseJobSelectors(args []string) (map[string]bool, error) {
+	selected := make(map[string]bool)
+	
+	for _, arg := range args {
+		for _, term := range splitOutsideBrackets(arg) {
+			if jobRangePattern.MatchString(term) {
+				term = "[" + term + "]"
+			}
+			
+			ids, err := hostlist.Expand(term)
+			if err != nil {
+				return nil, fmt.Errorf("invalid job selector %q: %v", term, err)
+			}
+			for _, id := range ids {
+				if !jobIDPattern.MatchString(id) {
+					return nil, fmt.Errorf("invalid job ID: %s", id)
+				}
+				selected[id] = true
+			}
+		}
+	}
+	
+	return selected, nil
+}
+
+// splitOutsideBrackets splits s at commas that are not inside brackets
+func splitOutsideBrackets(s string) []string {
+	var terms []string
+	depth, start := 0, 0
+	
+	for i, r := range s {
+		switch r {
+		case '[':
+			depth++
+		case ']':
+			depth--
+		case ',':
+			if depth == 0 {
+				terms = append(terms, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	terms = append(terms, s[start:])
+	
+	var result []string
+	for _, term := range terms {
+		if term = strings.TrimSpace(term); term != "" {
+			result = append(result, term)
+		}
+	}
+	return result
+}
+
+// selectJobs returns the queued jobs matching both the selected IDs and
+// the filter
+func (c *CancelCommand) selectJobs(selected map[string]bool, filter *slurm.JobFilter) ([]slurm.Job, error) {
+	if filter == nil {
+		filter = &slurm.JobFilter{}
+	}
+	
+	for id := range selected {
+		base, _, _ := strings.Cut(id, "_")
+		if !slices.Contains(filter.JobIDs, base) {
+			filter.JobIDs = append(filter.JobIDs, base)
+		}
+	}
+	slices.Sort(filter.JobIDs)
+	
+	jobs, err := c.client.ListJobs(filter)
+	if err != nil {
+		return nil, err
+	}
+	if len(selected) == 0 {
+		return jobs, nil
+	}
+	
+	var result []slurm.Job
+	for _, job := range jobs {
+		if len(cancelTargets(job, selected)) > 0 {
+			result = append(result, job)
+		}
+	}
+	return result, nil
+}
+
+// cancelTargets returns the scancel arguments that cancel the selected
+// part of a queued job. squeue lists the pending tasks of an array as one
+// entry such as "1234_[5-20%4]", of which only some may be selected.
+// Without selected IDs the whole entry is cancelled.
+func cancelTargets(job slurm.Job, selected map[string]bool) []string {
+	base, task, _ := strings.Cut(job.ID, "_")
+	if len(selected) == 0 || selected[base] {
+		return []string{arrayExpression(job.ID)}
+	}
+	if !strings.HasPrefix(task, "[") {
+		if selected[job.ID] {
+			return []string{job.ID}
+		}
+		return nil
+	}
+	
+	tasks, err := hostlist.Expand(arrayExpression(job.ID))
+	if err != nil {
+		return nil
+	}
+	var targets []string
+	for _, id := range tasks {
+		if selected[id] {
+			targets = append(targets, id)
+		}
+	}
+	return targets
+}
+
+// countTargets returns the number of jobs and array tasks that targets
+// name, counting each task of an expression such as "1234_[5-20]"
+func countTargets(targets []string) int {
+	count := 0
+	for _, target := range targets {
+		tasks, err := hostlist.Expand(target)
+		if err != nil || !strings.Contains(target, "[") {
+			count++
+			continue
+		}
+		count += len(tasks)
+	}
+	return count
+}
+
+// arrayExpression drops the throttle from a pending array entry, as in
+// "1234_[5-20%4]", leaving an expression scancel accepts
+func arrayExpression(jobID string) string {
+	if i := strings.Index(jobID, "%"); i >= 0 && strings.HasSuffix(jobID, "]") {
+		return jobID[:i] + "]"
+	}
+	return jobID
+}
+
+// Flags returns the options that take no value
+func (c *CancelCommand) Flags() []string {
+	return []string{"-y", "--yes"}
+}
+
 func (c *CancelCommand) Description() string {
-	return "Cancel a job"
+	return "Cancel jobs"
 }
 
 func (c *CancelCommand) Usage() string {
-	return "cancel <job_id> - Cancel a running or pending job"
-}
\ No newline at end of file
+	return `cancel <job_id>... [options]
+
+Cancel jobs by ID or by selecting them from the queue. IDs may be lists,
+ranges and array tasks. Selecting more than one job shows the affected
+jobs and asks for confirmation when confirm_dangerous_operations is set.
+
+Options:
+  -p, --partition <name>   Select jobs in the partition
+  -t, --state <state>      Select jobs in the state (e.g. pending, PD)
+  -n, --name <pattern>     Select jobs whose name matches the glob
+  -A, --account <name>     Select jobs charged to the account
+  -u, --user <name>        Select jobs of another user (default: you)
+  -y, --yes                Do not ask for confirmation
+
+Examples:
+  cancel 12345                  # Cancel a job
+  cancel 12345,12350-12355      # Cancel a list and a range
+  cancel 12345_[4-9]            # Cancel array tasks 4 to 9
+  cancel -p gpu -t pending      # Cancel your pending jobs in gpu
+  cancel -n 'sweep_*' --yes     # Cancel matching jobs without asking`
+}
@@ -0,0 +1,116 @@
+package commands
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"slsh/config"
+	"slsh/slurm"
+)
+
+// ConfigCommand implements the 'config' command
+type ConfigCommand struct {
+	config *config.Config
+}
+
+// NewConfigCommand creates a new config command
+func NewConfigCommand(cfg *config.Config) *ConfigCommand {
+	return &ConfigCommand{
+		config: cfg,
+	}
+}
+
+// Execute executes the config command
+func (c *ConfigCommand) Execute(cmd *slurm.Command, shell ShellInterface) error {
+	if len(cmd.Args) == 0 {
+		c.config.Print()
+		return nil
+	}
+
+	switch cmd.Args[0] {
+	case "show":
+		c.config.Print()
+	case "path":
+		fmt.Println(config.GetConfigPath())
+	case "save":
+		if err := c.config.Save(); err != nil {
+			return err
+		}
+		fmt.Printf("Configuration saved to %s\n", config.GetConfigPath())
+	case "set":
+		if len(cmd.Args) != 3 {
+			return fmt.Errorf("usage: config set <key> <value>")
+		}
+		return c.set(cmd.Args[1], cmd.Args[2])
+	default:
+		return fmt.Errorf("unknown config subcommand: %s", cmd.Args[0])
+	}
+
+	return nil
+}
+
+// set updates a single job default
+func (c *ConfigCommand) set(key, value string) error {
+	switch key {
+	case "partition":
+		c.config.UpdateDefaults(value, 0, 0, "", "")
+	case "nodes":
+		nodes := parseInt(value)
+		if nodes <= 0 {
+			return fmt.Errorf("invalid node count: %s", value)
+		}
+		c.config.UpdateDefaults("", nodes, 0, "", "")
+	case "cpus":
+		cpus := parseInt(value)
+		if cpus <= 0 {
+			return fmt.Errorf("invalid CPU count: %s", value)
+		}
+		c.config.UpdateDefaults("", 0, cpus, "", "")
+	case "memory":
+		c.config.UpdateDefaults("", 0, 0, value, "")
+	case "time":
+		c.config.UpdateDefaults("", 0, 0, "", value)
+	case "qos":
+		c.config.DefaultQoS = value
+	case "account":
+		c.config.DefaultAccount = value
+	default:
+		if account, ok := strings.CutPrefix(key, "budget."); ok && account != "" {
+			hours, err := strconv.ParseFloat(value, 64)
+			if err != nil || hours < 0 {
+				return fmt.Errorf("invalid budget: %s", value)
+			}
+			c.config.SetBudget(account, hours)
+			break
+		}
+		return fmt.Errorf("unknown config key: %s", key)
+	}
+
+	fmt.Printf("%s = %s\n", key, value)
+	return nil
+}
+
+// Description returns the command description
+func (c *ConfigCommand) Description() string {
+	return "Show or change shell configuration"
+}
+
+// Usage returns the command usage
+func (c *ConfigCommand) Usage() string {
+	return `config [show|path|save|set <key> <value>]
+
+Show or change the shell configuration. Changes made with 'set' apply
+to the current session until saved with 'config save'.
+
+Keys:
+  partition, nodes, cpus, memory, time, qos, account
+  budget.<account>    Billing-hours the account may use per period,
+                      shown by 'usage --budget'; 0 removes the budget
+
+Examples:
+  config                      # Show configuration
+  config set partition gpu    # Change default partition
+  config set budget.physics 50000
+  config save                 # Write configuration to disk`
+}
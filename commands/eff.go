@@ -0,0 +1,339 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"slsh/config"
+	"slsh/slurm"
+	"slsh/utils"
+)
+
+// Thresholds below or above which eff warns about a job
+const (
+	lowCPUEfficiency    = 0.5
+	lowMemoryEfficiency = 0.25
+	highMemoryUse       = 0.9
+	lowWalltimeUse      = 0.25
+)
+
+// defaultEffRange is how far back eff summarizes without --since
+const defaultEffRange = 7 * 24 * time.Hour
+
+// EffCommand implements the 'eff' command
+type EffCommand struct {
+	client *slurm.Client
+	config *config.Config
+}
+
+// NewEffCommand creates a new eff command
+func NewEffCommand(client *slurm.Client, cfg *config.Config) *EffCommand {
+	return &EffCommand{
+		client: client,
+		config: cfg,
+	}
+}
+
+// Execute executes the eff command
+func (e *EffCommand) Execute(cmd *slurm.Command, shell ShellInterface) error {
+	switch len(cmd.Args) {
+	case 0:
+		return e.summary(cmd)
+	case 1:
+		jobID := cmd.Args[0]
+		if !jobIDPattern.MatchString(jobID) {
+			return fmt.Errorf("invalid job ID: %s", jobID)
+		}
+		if hasOption(cmd, "--since", "-S", "--until", "-E") {
+			return fmt.Errorf("--since and --until summarize a range; leave out the job ID")
+		}
+		usage, err := e.client.JobUsage(jobID)
+		if err != nil {
+			return fmt.Errorf("failed to get job usage: %v", err)
+		}
+		printEfficiency(usage, e.config.ColorOutput)
+		return nil
+	default:
+		return fmt.Errorf("usage: eff <job_id> | eff [--since time] [--until time]")
+	}
+}
+
+// printEfficiency prints how well a job used what it asked for, with
+// advice on what to ask for next time
+func printEfficiency(u *slurm.JobUsage, useColor bool) {
+	title := "Job " + u.ID
+	if u.Name != "" {
+		title += " (" + u.Name + ")"
+	}
+	if useColor {
+		title = utils.ColorBold + title + utils.ColorReset
+	}
+	fmt.Printf("%s: %s\n", title, utils.FormatJobState(u.State, useColor))
+
+	if u.State == slurm.JobStatePending || u.Elapsed <= 0 {
+		fmt.Println("  The job has not run yet")
+		return
+	}
+
+	field := func(label, value string) {
+		fmt.Printf("  %-11s %s\n", label+":", value)
+	}
+
+	resources := fmt.Sprintf("%s, %s", countOf(u.Nodes, "node"), countOf(u.CPUs, "CPU"))
+	if u.ReqMemMB > 0 {
+		resources += fmt.Sprintf(", %s memory (%s per CPU)", formatMB(u.ReqMemMB), formatMB(u.MemoryPerCPUMB()))
+	}
+	field("Resources", resources)
+
+	if eff, ok := u.CPUEfficiency(); ok {
+		field("CPU", fmt.Sprintf("%-7s %s of %s core-walltime", formatRatio(eff),
+			slurm.FormatSlurmDuration(u.TotalCPU), slurm.FormatSlurmDuration(u.CoreWalltime())))
+	}
+	if eff, ok := u.MemoryEfficiency(); ok {
+		field("Memory", fmt.Sprintf("%-7s %s of %s per node (MaxRSS)", formatRatio(eff),
+			formatMB(u.MaxRSSMB), formatMB(u.MemoryPerNodeMB())))
+	}
+	if use, ok := u.WalltimeUse(); ok {
+		field("Walltime", fmt.Sprintf("%-7s %s of %s", formatRatio(use),
+			slurm.FormatSlurmDuration(u.Elapsed), slurm.FormatSlurmDuration(u.TimeLimit)))
+	} else {
+		field("Walltime", slurm.FormatSlurmDuration(u.Elapsed)+" (no limit)")
+	}
+
+	if !slurm.IsTerminalJobState(u.State) {
+		fmt.Println()
+		fmt.Println(utils.FormatInfo("The job is still running; CPU time and memory are only accounted as its steps end", useColor))
+		return
+	}
+
+	warnings := efficiencyWarnings(u)
+	fmt.Println()
+	if len(warnings) == 0 {
+		fmt.Println(utils.FormatSuccess("The job used what it asked for well", useColor))
+		return
+	}
+	for _, warning := range warnings {
+		fmt.Println(utils.FormatWarning(warning, useColor))
+	}
+}
+
+// efficiencyWarnings explains how a finished job's request could be
+// sized better
+func efficiencyWarnings(u *slurm.JobUsage) []string {
+	var warnings []string
+
+	if eff, ok := u.CPUEfficiency(); ok && eff < lowCPUEfficiency && u.CPUs > 1 {
+		used := max(int((u.TotalCPU+u.Elapsed-1)/u.Elapsed), 1)
+		warnings = append(warnings, fmt.Sprintf("Used about %d of %d CPUs on average (%s); ask for fewer, or check that the program runs in parallel",
+			used, u.CPUs, formatRatio(eff)))
+	}
+
+	perNode := u.MemoryPerNodeMB()
+	if eff, ok := u.MemoryEfficiency(); ok {
+		switch {
+		case u.State == "OUT_OF_MEMORY":
+			warnings = append(warnings, fmt.Sprintf("Ran out of memory with %s per node; ask for more, e.g. --mem=%s",
+				formatMB(perNode), formatMemOption(2*perNode)))
+		case eff >= highMemoryUse:
+			warnings = append(warnings, fmt.Sprintf("Used %s of its memory; close to the limit of %s per node",
+				formatRatio(eff), formatMB(perNode)))
+		case eff < lowMemoryEfficiency && perNode >= 1024:
+			warnings = append(warnings, fmt.Sprintf("Used at most %s of %s per node (%s); --mem=%s would do",
+				formatMB(u.MaxRSSMB), formatMB(perNode), formatRatio(eff), formatMemOption(u.MaxRSSMB*5/4)))
+		}
+	}
+
+	if use, ok := u.WalltimeUse(); ok {
+		switch {
+		case u.State == slurm.JobStateTimeout:
+			warnings = append(warnings, fmt.Sprintf("Hit the time limit of %s; ask for more time or save checkpoints",
+				slurm.FormatSlurmDuration(u.TimeLimit)))
+		case use < lowWalltimeUse && u.State == slurm.JobStateCompleted && u.TimeLimit >= time.Hour:
+			suggested := (u.Elapsed*3/2 + time.Minute - 1).Truncate(time.Minute)
+			warnings = append(warnings, fmt.Sprintf("Ran for %s of its %s limit; shorter limits such as -t %s start sooner",
+				formatRatio(use), slurm.FormatSlurmDuration(u.TimeLimit), slurm.FormatSlurmDuration(suggested)))
+		}
+	}
+
+	return warnings
+}
+
+// summary reports the efficiency of the user's jobs that ended in a time
+// range, job by job and in total
+func (e *EffCommand) summary(cmd *slurm.Command) error {
+	now := time.Now()
+	since, until, err := timeRange(cmd, now.Add(-defaultEffRange), now)
+	if err != nil {
+		return err
+	}
+
+	filter := &slurm.JobFilter{Since: since, Until: until}
+	user, _ := optionValue(cmd, "-u", "--user")
+	if user == "" {
+		user = os.Getenv("USER")
+	}
+	if user != "" {
+		filter.Users = []string{user}
+	}
+	if value, ok := optionValue(cmd, "-p", "--partition"); ok {
+		filter.Partitions = splitList(value)
+	}
+	if value, ok := optionValue(cmd, "-A", "--account"); ok {
+		filter.Accounts = splitList(value)
+	}
+
+	usages, err := e.client.ListUsage(filter)
+	if err != nil {
+		return fmt.Errorf("failed to get job usage: %v", err)
+	}
+
+	// Only jobs that ran and ended say anything about their request
+	var jobs []slurm.JobUsage
+	for _, u := range usages {
+		if slurm.IsTerminalJobState(u.State) && u.Elapsed > 0 {
+			jobs = append(jobs, u)
+		}
+	}
+
+	owner := "Jobs"
+	if user != "" {
+		owner = user + "'s jobs"
+	}
+	fmt.Printf("%s from %s to %s: %s\n", owner, since.Format("2006-01-02 15:04"), until.Format("2006-01-02 15:04"), countOf(len(jobs), "job"))
+	if len(jobs) == 0 {
+		return nil
+	}
+	fmt.Println()
+
+	useColor := e.config.ColorOutput
+	mark := func(text string, low bool) string {
+		if low && useColor {
+			return utils.ColorYellow + text + utils.ColorReset
+		}
+		return text
+	}
+
+	var allocated, used time.Duration
+	var lowCPU, lowMemory, outOfMemory, timedOut int
+
+	table := utils.NewTable([]string{"JOBID", "NAME", "STATE", "CPUS", "ELAPSED", "CPU", "MEMORY", "WALLTIME"}, useColor)
+	for i := range jobs {
+		u := &jobs[i]
+		allocated += u.CoreWalltime()
+		used += u.TotalCPU
+
+		cpu, memory, walltime := "-", "-", "-"
+		if eff, ok := u.CPUEfficiency(); ok {
+			low := eff < lowCPUEfficiency && u.CPUs > 1
+			if low {
+				lowCPU++
+			}
+			cpu = mark(formatRatio(eff), low)
+		}
+		if eff, ok := u.MemoryEfficiency(); ok {
+			low := eff < lowMemoryEfficiency && u.MemoryPerNodeMB() >= 1024
+			if low {
+				lowMemory++
+			}
+			memory = mark(formatRatio(eff), low || u.State == "OUT_OF_MEMORY")
+		}
+		if use, ok := u.WalltimeUse(); ok {
+			walltime = mark(formatRatio(use), u.State == slurm.JobStateTimeout)
+		}
+		switch u.State {
+		case "OUT_OF_MEMORY":
+			outOfMemory++
+		case slurm.JobStateTimeout:
+			timedOut++
+		}
+
+		table.AddRow([]string{
+			u.ID,
+			u.Name,
+			utils.FormatJobState(u.State, useColor),
+			fmt.Sprint(u.CPUs),
+			slurm.FormatSlurmDuration(u.Elapsed),
+			cpu,
+			memory,
+			walltime,
+		})
+	}
+	table.Print()
+
+	fmt.Println()
+	total := fmt.Sprintf("%.1f core-hours allocated, %.1f used", allocated.Hours(), used.Hours())
+	if allocated > 0 {
+		total += fmt.Sprintf(" (%s CPU efficiency)", formatRatio(float64(used)/float64(allocated)))
+	}
+	fmt.Println(total)
+
+	warn := func(n int, text string) {
+		if n > 0 {
+			fmt.Println(utils.FormatWarning(fmt.Sprintf("%s %s", countOf(n, "job"), text), useColor))
+		}
+	}
+	warn(lowCPU, "used less than half of the CPUs requested")
+	warn(lowMemory, "used less than a quarter of the memory requested")
+	warn(outOfMemory, "ran out of memory")
+	warn(timedOut, "hit the time limit")
+	if lowCPU+lowMemory+outOfMemory+timedOut > 0 {
+		fmt.Println("Run 'eff <job_id>' for advice on a job")
+	}
+	return nil
+}
+
+// formatRatio formats a ratio as a percentage
+func formatRatio(ratio float64) string {
+	return fmt.Sprintf("%.1f%%", ratio*100)
+}
+
+// formatMB formats a size in megabytes
+func formatMB(mb int64) string {
+	return utils.FormatMemory(mb * 1024 * 1024)
+}
+
+// formatMemOption formats a size in megabytes as a --mem value, rounded
+// up to whole gigabytes from a gigabyte on
+func formatMemOption(mb int64) string {
+	if mb < 1024 {
+		return fmt.Sprintf("%dM", max(mb, 1))
+	}
+	return fmt.Sprintf("%dG", (mb+1023)/1024)
+}
+
+// Description returns the command description
+func (e *EffCommand) Description() string {
+	return "Show how efficiently jobs used their CPUs, memory and time"
+}
+
+// Usage returns the command usage
+func (e *EffCommand) Usage() string {
+	return `eff <job_id>
+eff [--since time] [--until time] [-u user] [-p partition] [-A account]
+
+For a single job, compare what it used with what it asked for, from
+accounting:
+  CPU       TotalCPU against Elapsed × AllocCPUS
+  Memory    MaxRSS against the memory requested per node
+  Walltime  Elapsed against the time limit
+and warn about requests that were much too large or too small.
+
+Without a job ID, summarize the jobs of a user (yourself by default) that
+ended in a time range, the last 7 days unless --since says otherwise.
+Times are dates (2024-01-15), dates and times (2024-01-15T08:00), today,
+yesterday, or ages such as 12h, 7d or 2w.
+
+Options:
+  --since <time>                  Start of the range (default 7d)
+  --until <time>                  End of the range (default now)
+  -u, --user <user>               Whose jobs to summarize
+  -p, --partition <partition>     Only jobs in these partitions
+  -A, --account <account>         Only jobs charged to these accounts
+
+Examples:
+  eff %last                       # How well did my last job do?
+  eff 999                         # Efficiency of job 999
+  eff --since 30d                 # My jobs of the last 30 days
+  eff --since 2024-01-01 --until 2024-02-01 -p gpu`
+}
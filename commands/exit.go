@@ -0,0 +1,36 @@
+package commands
+
+import (
+	"fmt"
+
+	"slsh/slurm"
+)
+
+// ExitCommand implements the 'exit' and 'quit' commands
+type ExitCommand struct {
+	shell ShellInterface
+}
+
+// NewExitCommand creates a new exit command
+func NewExitCommand(shell ShellInterface) *ExitCommand {
+	return &ExitCommand{
+		shell: shell,
+	}
+}
+
+// Execute executes the exit command
+func (e *ExitCommand) Execute(cmd *slurm.Command, shell ShellInterface) error {
+	fmt.Println("Goodbye!")
+	e.shell.Stop()
+	return nil
+}
+
+// Description returns the command description
+func (e *ExitCommand) Description() string {
+	return "Exit the shell"
+}
+
+// Usage returns the command usage
+func (e *ExitCommand) Usage() string {
+	return "exit - Save history and exit the shell"
+}
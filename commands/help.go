@@ -91,15 +91,35 @@ func (h *HelpCommand) showGeneralHelp() {
 	fmt.Println("  srun, sbatch, scancel, squeue, sinfo, sacct, scontrol, etc.")
 	fmt.Println()
 	
+	// Job references
+	fmt.Println("Job References:")
+	fmt.Println("===============")
+	fmt.Println("Jobs submitted in this session can be named in any command:")
+	fmt.Println("  %last          The latest job (also %-1)")
+	fmt.Println("  %-2            The job before it")
+	fmt.Println("  %<name>        The latest job with that name (globs allowed)")
+	fmt.Println()
+	
 	// Usage examples
 	fmt.Println("Usage Examples:")
 	fmt.Println("===============")
 	fmt.Println("  run hostname                    # Execute hostname on cluster")
 	fmt.Println("  run -N 2 -p gpu nvidia-smi     # Run on 2 GPU nodes")
 	fmt.Println("  submit my_job.sh               # Submit batch job")
+	fmt.Println("  submit -- python train.py      # Submit a command as a batch job")
+	fmt.Println("  template use gpu --set n=10    # Submit a saved job template")
+	fmt.Println("  pipeline run chain.json        # Submit dependent jobs from a file")
+	fmt.Println("  logs %last -f                  # Follow the output of the last job")
+	fmt.Println("  alloc -N 2 -t 1:00:00          # Hold two nodes and run steps on them")
+	fmt.Println("  eff %last                      # How efficiently the last job ran")
+	fmt.Println("  jhist --since 7d -t FAILED     # Your jobs that failed this week")
+	fmt.Println("  usage -A physics --by user     # Core- and GPU-hours this month")
 	fmt.Println("  queue                          # Show job queue")
 	fmt.Println("  status 12345                   # Check job 12345 status")
 	fmt.Println("  cancel 12345                   # Cancel job 12345")
+	fmt.Println("  status %last                   # Check the job submitted last")
+	fmt.Println("  cancel -p gpu -t pending       # Cancel your pending gpu jobs")
+	fmt.Println("  array 12345 --resubmit         # Retry the failed tasks of an array")
 	fmt.Println("  nodes                          # Show node information")
 	fmt.Println("  config                         # Show configuration")
 	fmt.Println("  alias myrun \"run -N 4 -p gpu\"   # Create custom alias")
@@ -1,17 +1,19 @@
 package commands
 
 import (
-
-	"slsh/shell"
 	"slsh/slurm"
-	
 )
 
+// HistoryPrinter is the part of the shell history used by commands
+type HistoryPrinter interface {
+	PrintHistory(showTimestamp bool, showDuration bool)
+}
+
 type HistoryCommand struct {
-	history *shell.History
+	history HistoryPrinter
 }
 
-func NewHistoryCommand(history *shell.History) *HistoryCommand {
+func NewHistoryCommand(history HistoryPrinter) *HistoryCommand {
 	return &HistoryCommand{history: history}
 }
 
@@ -33,6 +35,11 @@ func (h *HistoryCommand) Execute(cmd *slurm.Command, shell ShellInterface) error
 	return nil
 }
 
+// Flags returns the options that take no value
+func (h *HistoryCommand) Flags() []string {
+	return []string{"-t", "--time", "-d", "--duration"}
+}
+
 func (h *HistoryCommand) Description() string {
 	return "Show command history"
 }
@@ -0,0 +1,180 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"slsh/config"
+	"slsh/slurm"
+	"slsh/utils"
+)
+
+// defaultJhistRange is how far back jhist looks without --since
+const defaultJhistRange = 7 * 24 * time.Hour
+
+// maxHistoryCommand is the length at which submitting commands are cut off
+const maxHistoryCommand = 40
+
+// JobHistory is the part of the shell history jhist links jobs to
+type JobHistory interface {
+	FindJob(jobID string) (index int, command string, ok bool)
+}
+
+// JhistCommand implements the 'jhist' command
+type JhistCommand struct {
+	client  *slurm.Client
+	config  *config.Config
+	history JobHistory
+}
+
+// NewJhistCommand creates a new jhist command
+func NewJhistCommand(client *slurm.Client, cfg *config.Config, history JobHistory) *JhistCommand {
+	return &JhistCommand{
+		client:  client,
+		config:  cfg,
+		history: history,
+	}
+}
+
+// Execute executes the jhist command
+func (j *JhistCommand) Execute(cmd *slurm.Command, shell ShellInterface) error {
+	if len(cmd.Args) > 0 {
+		return fmt.Errorf("usage: jhist [--since time] [--until time] [--state states] [--partition partitions] [--name patterns]")
+	}
+
+	filter, err := jhistFilter(cmd, time.Now())
+	if err != nil {
+		return err
+	}
+
+	records, err := j.client.ListAccounting(filter)
+	if err != nil {
+		return fmt.Errorf("failed to get job history: %v", err)
+	}
+	if len(records) == 0 {
+		fmt.Printf("No jobs between %s and %s\n", filter.Since.Format("2006-01-02 15:04"), filter.Until.Format("2006-01-02 15:04"))
+		return nil
+	}
+
+	useColor := j.config.ColorOutput
+	table := utils.NewTable([]string{"JOBID", "NAME", "PARTITION", "STATE", "EXIT", "START", "ELAPSED", "NODELIST", "SUBMITTED BY"}, useColor)
+	states := make([]string, 0, len(records))
+	for _, record := range records {
+		states = append(states, record.State)
+
+		start := "-"
+		if !record.StartTime.IsZero() {
+			start = record.StartTime.Format("2006-01-02 15:04")
+		}
+		elapsed := "-"
+		if record.Elapsed > 0 || !record.StartTime.IsZero() {
+			elapsed = slurm.FormatSlurmDuration(record.Elapsed)
+		}
+		exit := "-"
+		if slurm.IsTerminalJobState(record.State) {
+			exit = formatExitCode(record.ExitCode, record.Signal)
+		}
+		nodes := record.NodeList
+		if nodes == "" {
+			nodes = "-"
+		}
+
+		table.AddRow([]string{
+			record.ID,
+			record.Name,
+			record.Partition,
+			utils.FormatJobState(record.State, useColor),
+			exit,
+			start,
+			elapsed,
+			nodes,
+			j.submittedBy(record.ID),
+		})
+	}
+	table.Print()
+	printStateSummary(states, useColor)
+	return nil
+}
+
+// submittedBy describes the shell history entry that submitted a job, as
+// in "#12 submit train.sh", or "-" for jobs submitted elsewhere
+func (j *JhistCommand) submittedBy(jobID string) string {
+	if j.history == nil {
+		return "-"
+	}
+	index, command, ok := j.history.FindJob(jobID)
+	if !ok {
+		return "-"
+	}
+	if runes := []rune(command); len(runes) > maxHistoryCommand {
+		command = string(runes[:maxHistoryCommand-3]) + "..."
+	}
+	return fmt.Sprintf("#%d %s", index, command)
+}
+
+// jhistFilter builds the accounting filter of a jhist command line. Only
+// the current user's jobs are listed unless users are given.
+func jhistFilter(cmd *slurm.Command, now time.Time) (*slurm.JobFilter, error) {
+	since, until, err := timeRange(cmd, now.Add(-defaultJhistRange), now)
+	if err != nil {
+		return nil, err
+	}
+	filter := &slurm.JobFilter{Since: since, Until: until}
+
+	users, _ := optionValue(cmd, "-u", "--user")
+	filter.Users = splitList(users)
+	if len(filter.Users) == 0 {
+		if user := os.Getenv("USER"); user != "" {
+			filter.Users = []string{user}
+		}
+	}
+
+	states, _ := optionValue(cmd, "-t", "--state")
+	for _, state := range splitList(states) {
+		filter.States = append(filter.States, slurm.NormalizeJobState(state))
+	}
+
+	partitions, _ := optionValue(cmd, "-p", "--partition")
+	filter.Partitions = splitList(partitions)
+
+	accounts, _ := optionValue(cmd, "-A", "--account")
+	filter.Accounts = splitList(accounts)
+
+	names, _ := optionValue(cmd, "-n", "--name")
+	filter.Names = splitList(names)
+
+	return filter, nil
+}
+
+// Description returns the command description
+func (j *JhistCommand) Description() string {
+	return "Show your past Slurm jobs from accounting"
+}
+
+// Usage returns the command usage
+func (j *JhistCommand) Usage() string {
+	return `jhist [OPTIONS]
+
+List your jobs from accounting (sacct) with their state, exit code, run
+time and nodes, for the last 7 days unless --since says otherwise. Jobs
+submitted from this shell show the history entry that submitted them.
+
+Times are dates (2024-01-15), dates and times (2024-01-15T08:00), today,
+yesterday, or ages such as 12h, 7d or 2w.
+
+Options:
+  --since <time>                Start of the range (default 7d)
+  --until <time>                End of the range (default now)
+  -t, --state <states>          States, e.g. FAILED,TIMEOUT or F,TO
+  -p, --partition <partitions>  Partitions, comma separated
+  -n, --name <patterns>         Job names, * and ? match any characters
+  -u, --user <users>            Users, comma separated
+  -A, --account <accounts>      Accounts, comma separated
+
+Examples:
+  jhist                         # Your jobs of the last week
+  jhist --since 30d -t FAILED,TIMEOUT
+  jhist --since yesterday -p gpu --name 'train_*'
+  history                       # The commands that submitted them`
+}
@@ -3,27 +3,40 @@ package commands
 import (
 	"fmt"
 	"os"
+
+	"slsh/config"
 	"slsh/slurm"
 )
 
 type JobsCommand struct {
 	client *slurm.Client
+	config *config.Config
 }
 
-func NewJobsCommand(client *slurm.Client) *JobsCommand {
-	return &JobsCommand{client: client}
+func NewJobsCommand(client *slurm.Client, cfg *config.Config) *JobsCommand {
+	return &JobsCommand{client: client, config: cfg}
 }
 
 func (j *JobsCommand) Execute(cmd *slurm.Command, shell ShellInterface) error {
-	user := os.Getenv("USER")
-	result, err := j.client.GetQueue(user)
+	if interval, watch := watchInterval(cmd); watch {
+		return watchView(j.client, j, cmd, interval, j.config.ColorOutput)
+	}
+	return j.render(cmd, nil)
+}
+
+// render prints the jobs once, highlighting changes in watch mode
+func (j *JobsCommand) render(cmd *slurm.Command, changes *changeTracker) error {
+	filter := &slurm.JobFilter{}
+	if user := os.Getenv("USER"); user != "" {
+		filter.Users = []string{user}
+	}
+	
+	jobs, err := j.client.ListJobs(filter)
 	if err != nil {
 		return fmt.Errorf("failed to get jobs: %v", err)
 	}
 	
-	if result.Output != "" {
-		fmt.Print(result.Output)
-	}
+	printJobTableChanges(jobs, j.config.ColorOutput, changes)
 	return nil
 }
 
@@ -32,5 +45,5 @@ func (j *JobsCommand) Description() string {
 }
 
 func (j *JobsCommand) Usage() string {
-	return "jobs - Show all your jobs"
+	return "jobs [--watch [seconds]] - Show all your jobs"
 }
@@ -0,0 +1,202 @@
+package commands
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"slsh/slurm"
+	"slsh/utils"
+)
+
+// printJobTable prints jobs in a squeue-like table
+func printJobTable(jobs []slurm.Job, useColor bool) {
+	printJobTableChanges(jobs, useColor, nil)
+}
+
+// printJobTableChanges prints jobs, highlighting the states that changed
+// since the previous watch refresh
+func printJobTableChanges(jobs []slurm.Job, useColor bool, changes *changeTracker) {
+	if len(jobs) == 0 {
+		fmt.Println("No jobs found")
+		return
+	}
+
+	table := utils.NewTable([]string{
+		"JOBID", "NAME", "USER", "STATE", "PARTITION", "NODES", "CPUS", "TIME", "LIMIT", "NODELIST(REASON)",
+	}, useColor)
+
+	for _, job := range jobs {
+		state := utils.FormatJobState(job.State, useColor)
+		if changes.changed(job.ID, job.State) {
+			state = utils.Highlight(state, useColor)
+		}
+		
+		table.AddRow([]string{
+			job.ID,
+			job.Name,
+			job.User,
+			state,
+			job.Partition,
+			fmt.Sprintf("%d", job.Nodes),
+			fmt.Sprintf("%d", job.CPUs),
+			job.TimeUsed,
+			job.TimeLimit,
+			jobLocation(job),
+		})
+	}
+
+	table.Print()
+}
+
+// jobLocation returns the node list of a job, or its pending reason
+func jobLocation(job slurm.Job) string {
+	if job.NodeList != "" {
+		return job.NodeList
+	}
+	if job.Reason != "" && !strings.EqualFold(job.Reason, "None") {
+		return "(" + job.Reason + ")"
+	}
+	return ""
+}
+
+// jobColumns compares two jobs by one sortable column
+var jobColumns = map[string]func(a, b slurm.Job) int{
+	"jobid":     func(a, b slurm.Job) int { return compareJobIDs(a.ID, b.ID) },
+	"name":      func(a, b slurm.Job) int { return strings.Compare(a.Name, b.Name) },
+	"user":      func(a, b slurm.Job) int { return strings.Compare(a.User, b.User) },
+	"account":   func(a, b slurm.Job) int { return strings.Compare(a.Account, b.Account) },
+	"state":     func(a, b slurm.Job) int { return strings.Compare(a.State, b.State) },
+	"partition": func(a, b slurm.Job) int { return strings.Compare(a.Partition, b.Partition) },
+	"nodes":     func(a, b slurm.Job) int { return cmp.Compare(a.Nodes, b.Nodes) },
+	"cpus":      func(a, b slurm.Job) int { return cmp.Compare(a.CPUs, b.CPUs) },
+	"time":      func(a, b slurm.Job) int { return compareDurations(a.TimeUsed, b.TimeUsed) },
+	"limit":     func(a, b slurm.Job) int { return compareDurations(a.TimeLimit, b.TimeLimit) },
+	"nodelist":  func(a, b slurm.Job) int { return strings.Compare(jobLocation(a), jobLocation(b)) },
+	"submit":    func(a, b slurm.Job) int { return a.SubmitTime.Compare(b.SubmitTime) },
+	"start":     func(a, b slurm.Job) int { return a.StartTime.Compare(b.StartTime) },
+}
+
+// sortJobs sorts jobs by comma separated column names. A leading '-'
+// sorts a column in descending order; reverse flips the whole order.
+func sortJobs(jobs []slurm.Job, keys string, reverse bool) error {
+	type sortKey struct {
+		compare    func(a, b slurm.Job) int
+		descending bool
+	}
+
+	var sortKeys []sortKey
+	for _, key := range splitList(keys) {
+		descending := strings.HasPrefix(key, "-")
+		name := strings.ToLower(strings.TrimLeft(key, "-+"))
+		compare, ok := jobColumns[name]
+		if !ok {
+			return fmt.Errorf("unknown sort column: %s", name)
+		}
+		sortKeys = append(sortKeys, sortKey{compare, descending != reverse})
+	}
+
+	slices.SortStableFunc(jobs, func(a, b slurm.Job) int {
+		for _, key := range sortKeys {
+			if c := key.compare(a, b); c != 0 {
+				if key.descending {
+					return -c
+				}
+				return c
+			}
+		}
+		return 0
+	})
+	return nil
+}
+
+// compareJobIDs orders job IDs numerically, array tasks after their job
+func compareJobIDs(a, b string) int {
+	baseA, taskA, _ := strings.Cut(a, "_")
+	baseB, taskB, _ := strings.Cut(b, "_")
+	if c := cmp.Compare(leadingInt(baseA), leadingInt(baseB)); c != 0 {
+		return c
+	}
+	if c := cmp.Compare(leadingInt(strings.TrimPrefix(taskA, "[")), leadingInt(strings.TrimPrefix(taskB, "["))); c != 0 {
+		return c
+	}
+	return strings.Compare(a, b)
+}
+
+// leadingInt parses the digits at the start of s
+func leadingInt(s string) int {
+	n := 0
+	for _, char := range s {
+		if char < '0' || char > '9' {
+			break
+		}
+		n = n*10 + int(char-'0')
+	}
+	return n
+}
+
+// compareDurations orders Slurm time values. Unlimited sorts last and
+// values that cannot be parsed, such as "N/A", first.
+func compareDurations(a, b string) int {
+	return cmp.Compare(durationRank(a), durationRank(b))
+}
+
+// durationRank converts a Slurm time value to a sortable number
+func durationRank(value string) time.Duration {
+	switch strings.ToUpper(value) {
+	case "UNLIMITED", "INFINITE":
+		return time.Duration(math.MaxInt64)
+	}
+	d, err := slurm.ParseSlurmDuration(value)
+	if err != nil {
+		return -1
+	}
+	return d
+}
+
+// printJobSummary prints the number of jobs in each state, most common first
+func printJobSummary(jobs []slurm.Job, useColor bool) {
+	states := make([]string, 0, len(jobs))
+	for _, job := range jobs {
+		states = append(states, job.State)
+	}
+	printStateSummary(states, useColor)
+}
+
+// printStateSummary prints the number of jobs and how many are in each of
+// the given states, the most common first
+func printStateSummary(jobStates []string, useColor bool) {
+	counts := make(map[string]int)
+	for _, state := range jobStates {
+		counts[state]++
+	}
+
+	states := make([]string, 0, len(counts))
+	for state := range counts {
+		states = append(states, state)
+	}
+	slices.SortFunc(states, func(a, b string) int {
+		if c := cmp.Compare(counts[b], counts[a]); c != 0 {
+			return c
+		}
+		return strings.Compare(a, b)
+	})
+
+	parts := make([]string, 0, len(states))
+	for _, state := range states {
+		parts = append(parts, fmt.Sprintf("%d %s", counts[state], utils.FormatJobState(state, useColor)))
+	}
+
+	fmt.Printf("\n%s: %s\n", countOf(len(jobStates), "job"), strings.Join(parts, ", "))
+}
+
+// countOf describes a number of things, as in "1 job" or "8 CPUs"
+func countOf(n int, thing string) string {
+	if n == 1 {
+		return "1 " + thing
+	}
+	return fmt.Sprintf("%d %ss", n, thing)
+}
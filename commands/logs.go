@@ -0,0 +1,335 @@
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"slsh/config"
+	"slsh/slurm"
+	"slsh/utils"
+)
+
+// defaultLogLines is how many lines of each file logs prints
+const defaultLogLines = 20
+
+// followInterval is how often logs -f looks for new output
+const followInterval = time.Second
+
+// followStateChecks is how many reads of new output pass between checks
+// of the job's state, to spare the controller
+const followStateChecks = 5
+
+// LogsCommand implements the 'logs' command
+type LogsCommand struct {
+	client *slurm.Client
+	config *config.Config
+}
+
+// NewLogsCommand creates a new logs command
+func NewLogsCommand(client *slurm.Client, cfg *config.Config) *LogsCommand {
+	return &LogsCommand{
+		client: client,
+		config: cfg,
+	}
+}
+
+// logFile is an output file of a job and how much of it has been printed
+type logFile struct {
+	label  string
+	path   string
+	offset int64
+}
+
+// Execute executes the logs command
+func (l *LogsCommand) Execute(cmd *slurm.Command, shell ShellInterface) error {
+	if len(cmd.Args) != 1 {
+		return fmt.Errorf("usage: logs <job_id> [-n lines] [-f] [--err]")
+	}
+	jobID := cmd.Args[0]
+	if !jobIDPattern.MatchString(jobID) {
+		return fmt.Errorf("invalid job ID: %s", jobID)
+	}
+
+	lines := defaultLogLines
+	if value, ok := optionValue(cmd, "-n", "--lines"); ok {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return fmt.Errorf("invalid number of lines: %s", value)
+		}
+		lines = n
+	}
+
+	detail, err := l.client.JobDetail(jobID)
+	if err != nil {
+		return fmt.Errorf("failed to get job details: %v", err)
+	}
+
+	stdout, stderr := detail.OutputPaths()
+	var files []*logFile
+	switch {
+	case hasOption(cmd, "--err"):
+		files = []*logFile{{label: "stderr", path: stderr}}
+	case stderr == stdout:
+		files = []*logFile{{label: "stdout and stderr", path: stdout}}
+	default:
+		files = []*logFile{{label: "stdout", path: stdout}, {label: "stderr", path: stderr}}
+	}
+
+	useColor := l.config.ColorOutput
+	if detail.State == slurm.JobStatePending {
+		fmt.Printf("Job %s has not started yet; it will write to:\n", jobID)
+		for _, file := range files {
+			fmt.Printf("  %-18s %s\n", file.label+":", file.path)
+		}
+		if !hasOption(cmd, "-f", "--follow") {
+			return nil
+		}
+	} else {
+		for i, file := range files {
+			if i > 0 {
+				fmt.Println()
+			}
+			printLogHeader(file, useColor)
+			if err := printTail(file, lines); err != nil {
+				fmt.Println(utils.FormatWarning(err.Error(), useColor))
+			}
+		}
+	}
+
+	if !hasOption(cmd, "-f", "--follow") {
+		return nil
+	}
+	if slurm.IsTerminalJobState(detail.State) {
+		fmt.Printf("\nJob %s has already ended (%s)\n", jobID, detail.State)
+		return nil
+	}
+	return l.follow(jobID, files)
+}
+
+// follow prints what the job appends to its files until it ends or the
+// user interrupts
+func (l *LogsCommand) follow(jobID string, files []*logFile) error {
+	ctx, cancel := l.client.Interruptible()
+	defer cancel()
+
+	useColor := l.config.ColorOutput
+	var current *logFile
+	printNew := func() {
+		for _, file := range files {
+			data, err := readFrom(file)
+			if err != nil || len(data) == 0 {
+				continue
+			}
+			// Like tail, name the file whenever the output switches files
+			if len(files) > 1 && file != current {
+				fmt.Println()
+				printLogHeader(file, useColor)
+			}
+			current = file
+			os.Stdout.Write(data)
+		}
+	}
+
+	ticker := time.NewTicker(followInterval)
+	defer ticker.Stop()
+
+	for polls := 1; ; polls++ {
+		select {
+		case <-ctx.Done():
+			fmt.Println()
+			return nil
+		case <-ticker.C:
+		}
+
+		printNew()
+		if polls%followStateChecks != 0 {
+			continue
+		}
+
+		// squeue forgets jobs soon after they end, so a job it no longer
+		// lists has ended too
+		jobs, err := l.client.ListJobs(&slurm.JobFilter{JobIDs: []string{jobID}})
+		if err != nil || ctx.Err() != nil {
+			continue
+		}
+		if len(jobs) == 0 || slurm.IsTerminalJobState(jobs[0].State) {
+			printNew()
+			state := "ended"
+			if len(jobs) > 0 {
+				state = jobs[0].State
+			} else if record, _, err := l.client.JobSteps(jobID); err == nil && record != nil {
+				state = record.State
+			}
+			fmt.Printf("\nJob %s has ended (%s)\n", jobID, utils.FormatJobState(state, useColor))
+			return nil
+		}
+	}
+}
+
+// printLogHeader names a file before its contents
+func printLogHeader(file *logFile, useColor bool) {
+	header := fmt.Sprintf("==> %s (%s) <==", file.path, file.label)
+	if useColor {
+		header = utils.ColorBold + header + utils.ColorReset
+	}
+	fmt.Println(header)
+}
+
+// printTail prints the last lines of a file and remembers where it ends,
+// so that following continues from there
+func printTail(file *logFile, lines int) error {
+	f, err := os.Open(file.path)
+	if os.IsNotExist(err) {
+		return fmt.Errorf("%s does not exist (yet)", file.path)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %v", file.path, err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %v", file.path, err)
+	}
+	file.offset = info.Size()
+
+	start, err := tailStart(f, file.offset, lines)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %v", file.path, err)
+	}
+	if _, err := f.Seek(start, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to read %s: %v", file.path, err)
+	}
+	if _, err := io.CopyN(os.Stdout, f, file.offset-start); err != nil {
+		return fmt.Errorf("failed to read %s: %v", file.path, err)
+	}
+	if start < file.offset && !endsWithNewline(f, file.offset) {
+		fmt.Println()
+	}
+	return nil
+}
+
+// tailStart returns the offset of the last lines of a file of the given
+// size. It reads backwards in blocks, so that large job logs are not read
+// in full.
+func tailStart(f *os.File, size int64, lines int) (int64, error) {
+	if lines == 0 {
+		return size, nil
+	}
+
+	const blockSize = 64 * 1024
+	buf := make([]byte, blockSize)
+	end := size
+
+	// A newline at the very end terminates the last line rather than
+	// starting a new one
+	if endsWithNewline(f, size) {
+		end--
+	}
+
+	pos := end
+	for pos > 0 {
+		n := int64(blockSize)
+		if pos < n {
+			n = pos
+		}
+		pos -= n
+		if _, err := f.ReadAt(buf[:n], pos); err != nil && err != io.EOF {
+			return 0, err
+		}
+
+		for i := n - 1; i >= 0; i-- {
+			if buf[i] != '\n' {
+				continue
+			}
+			lines--
+			if lines == 0 {
+				return pos + i + 1, nil
+			}
+		}
+	}
+	return 0, nil
+}
+
+// endsWithNewline reports whether the file of the given size ends with a
+// newline
+func endsWithNewline(f *os.File, size int64) bool {
+	if size == 0 {
+		return false
+	}
+	last := make([]byte, 1)
+	_, err := f.ReadAt(last, size-1)
+	return err == nil && last[0] == '\n'
+}
+
+// readFrom returns what has been written to a file since it was last
+// read. A file that shrank was truncated or replaced and is read again
+// from the start.
+func readFrom(file *logFile) ([]byte, error) {
+	f, err := os.Open(file.path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() < file.offset {
+		file.offset = 0
+	}
+	if info.Size() == file.offset {
+		return nil, nil
+	}
+
+	data := make([]byte, info.Size()-file.offset)
+	n, err := f.ReadAt(data, file.offset)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	// Keep a partial last line for the next read, unless it is all there is
+	data = data[:n]
+	if i := bytes.LastIndexByte(data, '\n'); i >= 0 {
+		data = data[:i+1]
+	}
+	file.offset += int64(len(data))
+	return data, nil
+}
+
+// Flags returns the options that take no value
+func (l *LogsCommand) Flags() []string {
+	return []string{"-f", "--follow", "--err"}
+}
+
+// Description returns the command description
+func (l *LogsCommand) Description() string {
+	return "Show and follow the output files of a job"
+}
+
+// Usage returns the command usage
+func (l *LogsCommand) Usage() string {
+	return `logs <job_id> [-n lines] [-f] [--err]
+
+Print the end of the files a batch job writes its output to. The paths
+come from the controller or from accounting; patterns such as %j, %x,
+%A and %a in them are expanded, and jobs submitted without --output use
+the sbatch default slurm-<job_id>.out in their work directory.
+
+With -f, keep printing what the job writes until it ends or Ctrl+C.
+
+Options:
+  -n, --lines <n>                 Number of lines to print (default 20)
+  -f, --follow                    Follow the output until the job ends
+  --err                           Show only stderr
+
+Examples:
+  logs 1001                       # The end of job 1001's output
+  logs %last -f                   # Follow the last submitted job
+  logs 1005_3 --err -n 100        # The errors of task 3 of array 1005`
+}
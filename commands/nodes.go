@@ -2,26 +2,65 @@ package commands
 
 import (
 	"fmt"
+	"strings"
+
+	"slsh/config"
 	"slsh/slurm"
+	"slsh/utils"
 )
 
 type NodesCommand struct {
 	client *slurm.Client
+	config *config.Config
 }
 
-func NewNodesCommand(client *slurm.Client) *NodesCommand {
-	return &NodesCommand{client: client}
+func NewNodesCommand(client *slurm.Client, cfg *config.Config) *NodesCommand {
+	return &NodesCommand{client: client, config: cfg}
 }
 
 func (n *NodesCommand) Execute(cmd *slurm.Command, shell ShellInterface) error {
-	result, err := n.client.GetNodes()
+	if interval, watch := watchInterval(cmd); watch {
+		return watchView(n.client, n, cmd, interval, n.config.ColorOutput)
+	}
+	return n.render(cmd, nil)
+}
+
+// render prints the nodes once, highlighting changes in watch mode
+func (n *NodesCommand) render(cmd *slurm.Command, changes *changeTracker) error {
+	nodes, err := n.client.ListNodes()
 	if err != nil {
 		return fmt.Errorf("failed to get nodes: %v", err)
 	}
 	
-	if result.Output != "" {
-		fmt.Print(result.Output)
+	if len(nodes) == 0 {
+		fmt.Println("No nodes found")
+		return nil
 	}
+	
+	useColor := n.config.ColorOutput
+	table := utils.NewTable([]string{
+		"NODE", "STATE", "FLAGS", "PARTITIONS", "CPUS(A/I/O/T)", "MEMORY", "FEATURES", "REASON",
+	}, useColor)
+	
+	for _, node := range nodes {
+		state := utils.FormatNodeState(node.State, useColor)
+		if changes.changed(node.Name, node.State+" "+strings.Join(node.Flags, ",")) {
+			state = utils.Highlight(state, useColor)
+		}
+		
+		table.AddRow([]string{
+			node.Name,
+			state,
+			strings.ToLower(strings.Join(node.Flags, ",")),
+			node.Partition,
+			formatCPUCounts(node.CPUsAlloc, node.CPUsIdle, node.CPUsOther, node.CPUs),
+			utils.FormatMemory(int64(node.Memory) * 1024 * 1024),
+			node.Features,
+			node.Reason,
+		})
+	}
+	
+	table.Print()
 	return nil
 }
 
@@ -30,5 +69,10 @@ func (n *NodesCommand) Description() string {
 }
 
 func (n *NodesCommand) Usage() string {
-	return "nodes - Show cluster node information"
-}
\ No newline at end of file
+	return "nodes [--watch [seconds]] - Show cluster node information"
+}
+
+// formatCPUCounts formats CPU counts the way sinfo's %C does
+func formatCPUCounts(alloc, idle, other, total int) string {
+	return fmt.Sprintf("%d/%d/%d/%d", alloc, idle, other, total)
+}
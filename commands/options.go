@@ -0,0 +1,119 @@
+package commands
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"slsh/slurm"
+)
+
+// timeOptionLayouts are the layouts accepted for absolute times in
+// --since and --until
+var timeOptionLayouts = []string{
+	"2006-01",
+	"2006-01-02",
+	"2006-01-02T15:04",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04",
+}
+
+// ageUnits are the units of ages such as "7d" in --since and --until
+var ageUnits = map[byte]time.Duration{
+	'm': time.Minute,
+	'h': time.Hour,
+	'd': 24 * time.Hour,
+	'w': 7 * 24 * time.Hour,
+}
+
+// optionValue returns the value of the first of names given on the
+// command line, and whether any of them was given
+func optionValue(cmd *slurm.Command, names ...string) (string, bool) {
+	for _, name := range names {
+		if value, ok := cmd.Options[name]; ok {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+// hasOption reports whether any of names was given on the command line
+func hasOption(cmd *slurm.Command, names ...string) bool {
+	_, ok := optionValue(cmd, names...)
+	return ok
+}
+
+// splitList splits comma separated values into their non-empty items
+func splitList(values ...string) []string {
+	var items []string
+	for _, value := range values {
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+	}
+	return items
+}
+
+// parseTimeOption parses the value of --since or --until: a month, a date,
+// a date and time, "now", "today", "yesterday", "month" for the start of
+// the current month, or an age such as "12h", "7d" or "2w" counted back
+// from now
+func parseTimeOption(value string, now time.Time) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	switch strings.ToLower(value) {
+	case "now":
+		return now, nil
+	case "today":
+		return midnight, nil
+	case "yesterday":
+		return midnight.AddDate(0, 0, -1), nil
+	case "month":
+		return startOfMonth(now), nil
+	}
+
+	for _, layout := range timeOptionLayouts {
+		if t, err := time.ParseInLocation(layout, value, now.Location()); err == nil {
+			return t, nil
+		}
+	}
+
+	if len(value) > 1 {
+		if unit, ok := ageUnits[value[len(value)-1]]; ok {
+			if n, err := strconv.Atoi(value[:len(value)-1]); err == nil && n >= 0 {
+				return now.Add(-time.Duration(n) * unit), nil
+			}
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time: %s (use a date such as 2024-01-15, today, or an age such as 7d)", value)
+}
+
+// startOfMonth returns midnight on the first day of the month of t
+func startOfMonth(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+}
+
+// timeRange returns the range given by --since and --until. The range
+// starts at defaultSince when --since is missing and ends now when
+// --until is.
+func timeRange(cmd *slurm.Command, defaultSince, now time.Time) (since, until time.Time, err error) {
+	since, until = defaultSince, now
+	if value, ok := optionValue(cmd, "--since", "-S"); ok {
+		if since, err = parseTimeOption(value, now); err != nil {
+			return
+		}
+	}
+	if value, ok := optionValue(cmd, "--until", "-E"); ok {
+		if until, err = parseTimeOption(value, now); err != nil {
+			return
+		}
+	}
+	if !since.Before(until) {
+		err = fmt.Errorf("--since must be before --until")
+	}
+	return
+}
@@ -0,0 +1,40 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+
+	"slsh/slurm"
+	"slsh/utils"
+)
+
+// taskLabel matches the task prefix srun --label puts in front of each line
+var taskLabel = regexp.MustCompile(`^\s*\d+: `)
+
+// streamPrinter returns a line handler that prints command output as it
+// arrives. stderr goes to the terminal's stderr, in red when colors are on.
+func streamPrinter(useColor bool) slurm.LineHandler {
+	return func(line slurm.OutputLine) {
+		text := line.Text
+		label := ""
+		if loc := taskLabel.FindStringIndex(text); loc != nil {
+			label, text = text[:loc[1]], text[loc[1]:]
+		}
+
+		if useColor {
+			if label != "" {
+				label = utils.ColorCyan + label + utils.ColorReset
+			}
+			if line.Stderr {
+				text = utils.ColorRed + text + utils.ColorReset
+			}
+		}
+
+		if line.Stderr {
+			fmt.Fprintln(os.Stderr, label+text)
+		} else {
+			fmt.Println(label + text)
+		}
+	}
+}
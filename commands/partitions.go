@@ -1,26 +1,62 @@
 package commands
+
 import (
 	"fmt"
+
+	"slsh/config"
 	"slsh/slurm"
+	"slsh/utils"
 )
 
 type PartitionsCommand struct {
 	client *slurm.Client
+	config *config.Config
 }
 
-func NewPartitionsCommand(client *slurm.Client) *PartitionsCommand {
-	return &PartitionsCommand{client: client}
+func NewPartitionsCommand(client *slurm.Client, cfg *config.Config) *PartitionsCommand {
+	return &PartitionsCommand{client: client, config: cfg}
 }
 
 func (p *PartitionsCommand) Execute(cmd *slurm.Command, shell ShellInterface) error {
-	result, err := p.client.GetPartitions()
+	partitions, err := p.client.ListPartitions()
 	if err != nil {
 		return fmt.Errorf("failed to get partitions: %v", err)
 	}
 	
-	if result.Output != "" {
-		fmt.Print(result.Output)
+	if len(partitions) == 0 {
+		fmt.Println("No partitions found")
+		return nil
 	}
+	
+	useColor := p.config.ColorOutput
+	table := utils.NewTable([]string{
+		"PARTITION", "STATE", "NODES", "CPUS(A/I/O/T)", "MAX_TIME", "DEFAULT_TIME", "MAX_NODES", "NODELIST",
+	}, useColor)
+	
+	for _, partition := range partitions {
+		name := partition.Name
+		if partition.Default {
+			name += "*"
+		}
+		
+		maxNodes := "unlimited"
+		if partition.MaxNodes > 0 {
+			maxNodes = fmt.Sprintf("%d", partition.MaxNodes)
+		}
+		
+		table.AddRow([]string{
+			name,
+			utils.FormatNodeState(partition.State, useColor),
+			fmt.Sprintf("%d", partition.TotalNodes),
+			formatCPUCounts(partition.CPUsAlloc, partition.CPUsIdle, partition.CPUsOther, partition.CPUs),
+			partition.MaxTime,
+			partition.DefaultTime,
+			maxNodes,
+			partition.NodeList,
+		})
+	}
+	
+	table.Print()
 	return nil
 }
 
@@ -30,4 +66,4 @@ func (p *PartitionsCommand) Description() string {
 
 func (p *PartitionsCommand) Usage() string {
 	return "partitions - Show cluster partition information"
-}
\ No newline at end of file
+}
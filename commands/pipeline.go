@@ -0,0 +1,401 @@
+package commands
+
+import (
+	"fmt"
+	"maps"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"slsh/config"
+	"slsh/slurm"
+	"slsh/utils"
+)
+
+// neverSatisfied is the reason Slurm gives for jobs whose dependencies
+// can no longer be met
+const neverSatisfied = "DependencyNeverSatisfied"
+
+// PipelineCommand implements the 'pipeline' command
+type PipelineCommand struct {
+	client *slurm.Client
+	config *config.Config
+}
+
+// NewPipelineCommand creates a new pipeline command
+func NewPipelineCommand(client *slurm.Client, cfg *config.Config) *PipelineCommand {
+	return &PipelineCommand{
+		client: client,
+		config: cfg,
+	}
+}
+
+// Execute executes the pipeline command
+func (p *PipelineCommand) Execute(cmd *slurm.Command, shell ShellInterface) error {
+	if len(cmd.Args) == 0 {
+		return fmt.Errorf("usage: pipeline run <file> | pipeline status [name] | pipeline list")
+	}
+
+	action, args := cmd.Args[0], cmd.Args[1:]
+	switch action {
+	case "run", "submit":
+		if len(args) != 1 {
+			return fmt.Errorf("usage: pipeline run [--dry-run] <file>")
+		}
+		return p.run(args[0], hasOption(cmd, "--dry-run"), shell)
+	case "status":
+		name := ""
+		if len(args) > 0 {
+			name = args[0]
+		}
+		return p.status(name)
+	case "list", "ls":
+		return p.list()
+	default:
+		return fmt.Errorf("unknown pipeline action: %s (use run, status or list)", action)
+	}
+}
+
+// run submits the steps of a pipeline file in dependency order, each
+// waiting for the jobs of the steps it comes after
+func (p *PipelineCommand) run(file string, dryRun bool, shell ShellInterface) error {
+	pipeline, err := config.LoadPipeline(file)
+	if err != nil {
+		return err
+	}
+	steps, err := pipeline.Order()
+	if err != nil {
+		return err
+	}
+
+	// Relative script paths and working directories are relative to the
+	// pipeline file
+	path, err := filepath.Abs(file)
+	if err != nil {
+		return fmt.Errorf("failed to resolve pipeline path: %v", err)
+	}
+	dir := filepath.Dir(path)
+
+	if dryRun {
+		fmt.Printf("Pipeline %s would submit %s:\n", pipeline.Name, countOf(len(steps), "step"))
+		table := utils.NewTable([]string{"STEP", "RUNS", "AFTER", "RESOURCES"}, p.config.ColorOutput)
+		for _, step := range steps {
+			runs := step.Command
+			if step.Script != "" {
+				runs = step.Script
+			}
+			table.AddRow([]string{
+				step.Name,
+				runs,
+				describeAfter(step.After, step.DependencyType()),
+				describeResources(pipelineStepOptions(pipeline, step, dir)),
+			})
+		}
+		table.Print()
+		return nil
+	}
+
+	run := &config.PipelineRun{Name: pipeline.Name, File: path, Submitted: time.Now()}
+	submit := NewSubmitCommand(p.client, p.config)
+	jobIDs := make(map[string]string)
+
+	for i, step := range steps {
+		opts := pipelineStepOptions(pipeline, step, dir)
+
+		var after []string
+		for _, name := range step.After {
+			after = append(after, jobIDs[name])
+		}
+		if len(after) > 0 {
+			opts.Dependency = step.DependencyType() + ":" + strings.Join(after, ":")
+		}
+
+		fmt.Printf("[%d/%d] %s\n", i+1, len(steps), step.Name)
+		jobID, err := p.submitStep(submit, step, opts, dir, shell)
+		if err != nil {
+			// Keep what was submitted, so that it can be followed or cancelled
+			if len(run.Jobs) > 0 {
+				config.SavePipelineRun(run)
+			}
+			return fmt.Errorf("failed to submit step %s: %v", step.Name, err)
+		}
+		fmt.Println()
+
+		jobIDs[step.Name] = jobID
+		job := config.PipelineJob{Step: step.Name, JobID: jobID, After: step.After}
+		if len(step.After) > 0 {
+			job.Dependency = step.DependencyType()
+		}
+		run.Jobs = append(run.Jobs, job)
+	}
+
+	if err := config.SavePipelineRun(run); err != nil {
+		return err
+	}
+	fmt.Printf("Pipeline %s submitted as %s\n", pipeline.Name, countOf(len(run.Jobs), "job"))
+	fmt.Printf("Follow it with 'pipeline status %s'\n", pipeline.Name)
+	return nil
+}
+
+// submitStep submits the script or command of a step, returning its job ID
+func (p *PipelineCommand) submitStep(submit *SubmitCommand, step config.PipelineStep, opts *slurm.JobOptions, dir string, shell ShellInterface) (string, error) {
+	if err := validateArray(opts.Array); err != nil {
+		return "", err
+	}
+
+	var jobID string
+	var err error
+	if step.Script != "" {
+		script := step.Script
+		if !filepath.IsAbs(script) {
+			script = filepath.Join(dir, script)
+		}
+		jobID, err = submit.submitFile(script, opts, shell)
+	} else {
+		applyDefaults(opts, p.config, nil)
+		fmt.Printf("Submitting: %s\n", step.Command)
+		jobID, err = submit.submitScript(step.Command, opts, shell)
+	}
+
+	if err == nil && jobID == "" {
+		err = fmt.Errorf("sbatch did not report a job ID")
+	}
+	return jobID, err
+}
+
+// pipelineStepOptions returns the job options of a step: the pipeline's
+// defaults overridden by the step's own options. Jobs are named after
+// their step and run in the directory of the pipeline file.
+func pipelineStepOptions(pipeline *config.Pipeline, step config.PipelineStep, dir string) *slurm.JobOptions {
+	opts := pipeline.Defaults
+	opts.Environment = maps.Clone(pipeline.Defaults.Environment)
+	opts.ExtraArgs = slices.Clone(pipeline.Defaults.ExtraArgs)
+	mergeJobOptions(&opts, &step.Options)
+
+	if step.Options.Name == "" {
+		opts.Name = step.Name
+	}
+	if opts.WorkDir == "" {
+		opts.WorkDir = dir
+	} else if !filepath.IsAbs(opts.WorkDir) {
+		opts.WorkDir = filepath.Join(dir, opts.WorkDir)
+	}
+	return &opts
+}
+
+// status shows the steps of a submitted pipeline with the current states
+// of their jobs, and points out steps that can never start
+func (p *PipelineCommand) status(name string) error {
+	run, err := config.LoadPipelineRun(name)
+	if err != nil {
+		return err
+	}
+
+	var ids []string
+	for _, job := range run.Jobs {
+		ids = append(ids, job.JobID)
+	}
+	records, err := p.jobRecords(ids)
+	if err != nil {
+		return fmt.Errorf("failed to get job states: %v", err)
+	}
+
+	fmt.Printf("Pipeline %s, submitted %s from %s\n", run.Name, run.Submitted.Format(statusTimeLayout), run.File)
+	fmt.Println()
+
+	useColor := p.config.ColorOutput
+	depth := make(map[string]int)
+	states := make(map[string]string)
+	var stuck []config.PipelineJob
+
+	table := utils.NewTable([]string{"STEP", "JOB", "STATE", "ELAPSED", "AFTER", "REASON"}, useColor)
+	for _, job := range run.Jobs {
+		for _, after := range job.After {
+			depth[job.Step] = max(depth[job.Step], depth[after]+1)
+		}
+
+		record, known := records[job.JobID]
+		state, elapsed := record.State, slurm.FormatSlurmDuration(record.Elapsed)
+		if !known {
+			state, elapsed = "UNKNOWN", "-"
+		}
+		states[job.Step] = state
+
+		reason := ""
+		if state == slurm.JobStatePending && record.Reason != "None" {
+			reason = record.Reason
+		}
+		if reason == neverSatisfied {
+			stuck = append(stuck, job)
+			reason = utils.FormatWarning(reason, useColor)
+		}
+
+		step := job.Step
+		if depth[job.Step] > 0 {
+			step = strings.Repeat("   ", depth[job.Step]-1) + "└─ " + job.Step
+		}
+
+		table.AddRow([]string{
+			step,
+			job.JobID,
+			utils.FormatJobState(state, useColor),
+			elapsed,
+			describeAfter(job.After, job.Dependency),
+			reason,
+		})
+	}
+	table.Print()
+
+	if len(stuck) == 0 {
+		return nil
+	}
+
+	fmt.Println()
+	var stuckIDs []string
+	for _, job := range stuck {
+		var causes []string
+		for _, after := range job.After {
+			causes = append(causes, after+" "+states[after])
+		}
+		msg := fmt.Sprintf("%s (job %s) will never start: it waits for %s (%s)",
+			job.Step, job.JobID, job.Dependency, strings.Join(causes, ", "))
+		fmt.Println(utils.FormatWarning(msg, useColor))
+		stuckIDs = append(stuckIDs, job.JobID)
+	}
+	fmt.Printf("Cancel the stuck jobs with 'cancel %s' and fix the failed steps\n", strings.Join(stuckIDs, ","))
+	return nil
+}
+
+// jobRecords returns the current state of jobs: squeue knows the jobs
+// still in the queue, sacct the ones that have finished
+func (p *PipelineCommand) jobRecords(ids []string) (map[string]slurm.JobRecord, error) {
+	records := make(map[string]slurm.JobRecord)
+
+	jobs, queueErr := p.client.ListJobs(&slurm.JobFilter{JobIDs: ids})
+	for _, job := range jobs {
+		// The tasks of an array share a record, the first task's
+		id, _, _ := strings.Cut(job.ID, "_")
+		if _, seen := records[id]; seen {
+			continue
+		}
+		elapsed, _ := slurm.ParseSlurmDuration(job.TimeUsed)
+		records[id] = slurm.JobRecord{
+			ID:       id,
+			Name:     job.Name,
+			State:    job.State,
+			Reason:   job.Reason,
+			Elapsed:  elapsed,
+			NodeList: job.NodeList,
+		}
+	}
+
+	var missing []string
+	for _, id := range ids {
+		if _, ok := records[id]; !ok {
+			missing = append(missing, id)
+		}
+	}
+	if len(missing) == 0 {
+		return records, nil
+	}
+
+	finished, err := p.client.ListAccounting(&slurm.JobFilter{JobIDs: missing})
+	if err != nil {
+		if len(records) > 0 {
+			return records, nil
+		}
+		if queueErr != nil {
+			return nil, queueErr
+		}
+		return nil, err
+	}
+	for _, record := range finished {
+		id, _, _ := strings.Cut(record.ID, "_")
+		if _, seen := records[id]; !seen {
+			records[id] = record
+		}
+	}
+	return records, nil
+}
+
+// list prints the recorded pipeline runs
+func (p *PipelineCommand) list() error {
+	runs, err := config.ListPipelineRuns()
+	if err != nil {
+		return err
+	}
+	if len(runs) == 0 {
+		fmt.Println("No pipelines submitted yet")
+		return nil
+	}
+
+	table := utils.NewTable([]string{"PIPELINE", "SUBMITTED", "JOBS", "FILE"}, p.config.ColorOutput)
+	for _, run := range runs {
+		var ids []string
+		for _, job := range run.Jobs {
+			ids = append(ids, job.JobID)
+		}
+		table.AddRow([]string{run.Name, run.Submitted.Format(statusTimeLayout), strings.Join(ids, ","), run.File})
+	}
+	table.Print()
+	return nil
+}
+
+// describeAfter describes the dependencies of a step, naming the
+// dependency type unless it is the default afterok
+func describeAfter(after []string, dependency string) string {
+	text := strings.Join(after, ",")
+	if text != "" && dependency != "" && dependency != "afterok" {
+		text += " (" + dependency + ")"
+	}
+	return text
+}
+
+// Flags returns the options that take no value
+func (p *PipelineCommand) Flags() []string {
+	return []string{"--dry-run"}
+}
+
+// Description returns the command description
+func (p *PipelineCommand) Description() string {
+	return "Submit and follow pipelines of dependent jobs"
+}
+
+// Usage returns the command usage
+func (p *PipelineCommand) Usage() string {
+	return `pipeline run [--dry-run] <file>
+pipeline status [name]
+pipeline list
+
+A pipeline file describes named steps in JSON. Each step runs a script
+or a command, with its own job options, after the steps it lists in
+"after". Steps are submitted in dependency order with --dependency, so
+Slurm starts each one when the steps before it have succeeded (afterok),
+or as set by "dependency": afterany, afternotok or aftercorr.
+
+Scripts and working directories are relative to the pipeline file.
+"defaults" holds job options shared by all steps; the option names are
+those of templates (partition, nodes, cpus, memory, time, account, ...).
+
+  {
+    "name": "train-chain",
+    "defaults": {"account": "physics"},
+    "steps": [
+      {"name": "prep", "script": "prep.sh"},
+      {"name": "train", "command": "python train.py", "after": ["prep"],
+       "options": {"partition": "gpu", "time": "8:00:00"}},
+      {"name": "eval", "command": "python eval.py", "after": ["train"]}
+    ]
+  }
+
+'pipeline status' shows the steps of the last run of a pipeline with the
+states of their jobs, and warns about steps that will never start because
+a step they depend on failed (DependencyNeverSatisfied).
+
+Examples:
+  pipeline run --dry-run chain.json   # Show what would be submitted
+  pipeline run chain.json             # Submit the pipeline
+  pipeline status                     # Follow the last submitted pipeline
+  pipeline status train-chain         # Follow a pipeline by name`
+}
@@ -4,48 +4,98 @@ import (
 	"fmt"
 	"os"
 
+	"slsh/config"
 	"slsh/slurm"
 )
 
 // QueueCommand implements the 'queue' command
 type QueueCommand struct {
 	client *slurm.Client
+	config *config.Config
 }
 
 // NewQueueCommand creates a new queue command
-func NewQueueCommand(client *slurm.Client) *QueueCommand {
+func NewQueueCommand(client *slurm.Client, cfg *config.Config) *QueueCommand {
 	return &QueueCommand{
 		client: client,
+		config: cfg,
 	}
 }
 
 // Execute executes the queue command
 func (q *QueueCommand) Execute(cmd *slurm.Command, shell ShellInterface) error {
-	var user string
-	
-	// Check if user is specified
-	if len(cmd.Args) > 0 {
-		user = cmd.Args[0]
-	} else {
-		// Default to current user
-		user = os.Getenv("USER")
+	if interval, watch := watchInterval(cmd); watch {
+		return watchView(q.client, q, cmd, interval, q.config.ColorOutput)
+	}
+	return q.render(cmd, nil)
+}
+
+// render prints the queue once, highlighting changes in watch mode
+func (q *QueueCommand) render(cmd *slurm.Command, changes *changeTracker) error {
+	filter, err := queueFilter(cmd)
+	if err != nil {
+		return err
 	}
 	
-	result, err := q.client.GetQueue(user)
+	jobs, err := q.client.ListJobs(filter)
 	if err != nil {
 		return fmt.Errorf("failed to get queue: %v", err)
 	}
 	
-	if result.Output != "" {
-		fmt.Print(result.Output)
+	if keys, ok := optionValue(cmd, "-S", "--sort"); ok {
+		if err := sortJobs(jobs, keys, hasOption(cmd, "--reverse")); err != nil {
+			return err
+		}
 	}
-	if result.Error != "" {
-		fmt.Print(result.Error)
+	
+	printJobTableChanges(jobs, q.config.ColorOutput, changes)
+	if len(jobs) > 0 {
+		printJobSummary(jobs, q.config.ColorOutput)
 	}
 	
 	return nil
 }
 
+// queueFilter builds the job filter of a queue command line. Without
+// users or --all, only the current user's jobs are shown.
+func queueFilter(cmd *slurm.Command) (*slurm.JobFilter, error) {
+	filter := &slurm.JobFilter{}
+	
+	users, _ := optionValue(cmd, "-u", "--user")
+	filter.Users = splitList(append(cmd.Args, users)...)
+	
+	if hasOption(cmd, "-a", "--all") {
+		if len(filter.Users) > 0 {
+			return nil, fmt.Errorf("--all cannot be combined with user names")
+		}
+	} else if len(filter.Users) == 0 {
+		if user := os.Getenv("USER"); user != "" {
+			filter.Users = []string{user}
+		}
+	}
+	
+	states, _ := optionValue(cmd, "-t", "--state")
+	for _, state := range splitList(states) {
+		filter.States = append(filter.States, slurm.NormalizeJobState(state))
+	}
+	
+	partitions, _ := optionValue(cmd, "-p", "--partition")
+	filter.Partitions = splitList(partitions)
+	
+	accounts, _ := optionValue(cmd, "-A", "--account")
+	filter.Accounts = splitList(accounts)
+	
+	names, _ := optionValue(cmd, "-n", "--name")
+	filter.Names = splitList(names)
+	
+	return filter, nil
+}
+
+// Flags returns the options that take no value
+func (q *QueueCommand) Flags() []string {
+	return []string{"-a", "--all", "--reverse"}
+}
+
 // Description returns the command description
 func (q *QueueCommand) Description() string {
 	return "Show the job queue"
@@ -53,13 +103,34 @@ func (q *QueueCommand) Description() string {
 
 // Usage returns the command usage
 func (q *QueueCommand) Usage() string {
-	return `queue [user]
+	return `queue [OPTIONS] [user...]
 
 Show the job queue. Without arguments, shows jobs for current user.
-With a username, shows jobs for that user (if you have permission).
+With usernames, shows jobs for those users (if you have permission).
+A summary of the jobs per state is printed below the table.
 
 Examples:
-  queue           # Show your jobs
-  queue alice     # Show alice's jobs
-  queue --all     # Show all jobs (if supported)`
-}
\ No newline at end of file
+  queue                         # Show your jobs
+  queue alice bob               # Show alice's and bob's jobs
+  queue --all                   # Show all jobs
+  queue --all -t pending -p gpu # Pending jobs in the gpu partition
+  queue --name 'train_*'        # Jobs whose name matches a pattern
+  queue --sort state,time       # Sort by state, then by run time
+  queue --watch 5               # Refresh every 5 seconds
+
+Options:
+  -a, --all                     Show jobs of all users
+  -u, --user <users>            Users, comma separated
+  -t, --state <states>          States, e.g. running,pending or R,PD
+  -p, --partition <partitions>  Partitions, comma separated
+  -A, --account <accounts>      Accounts, comma separated
+  -n, --name <patterns>         Job names, * and ? match any characters
+  -S, --sort <columns>          Sort by columns, e.g. state,-cpus
+  --reverse                     Reverse the sort order
+  --watch [seconds]             Redraw every few seconds, highlighting
+                                state changes, until a key is pressed
+
+Columns are jobid, name, user, account, state, partition, nodes, cpus,
+time, limit, nodelist, submit and start. A '-' before a column sorts it
+in descending order; --reverse flips the whole order.`
+}
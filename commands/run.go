@@ -2,10 +2,12 @@ package commands
 
 import (
 	"fmt"
+	"path/filepath"
 	"strings"
 
 	"slsh/config"
 	"slsh/slurm"
+	"slsh/slurm/hostlist"
 	"slsh/utils"
 )
 
@@ -30,44 +32,97 @@ func (r *RunCommand) Execute(cmd *slurm.Command, shell ShellInterface) error {
 	}
 	
 	// Parse job options from command
-	jobOpts := parseJobOptions(cmd.Options)
+	jobOpts := parseJobOptions(cmd, nil)
+	if err := resolveNodeLists(jobOpts); err != nil {
+		return err
+	}
+	if jobOpts.Array != "" {
+		return fmt.Errorf("job arrays run as batch jobs; use submit --array")
+	}
 	
-	// Apply defaults from config
-	r.applyDefaults(jobOpts)
+	// Inside an allocation the job runs as a step of it, on resources
+	// already granted, so defaults would only get in the way
+	if alloc := currentAllocation(shell); alloc != nil {
+		jobOpts.JobID = alloc.JobID
+	} else {
+		applyDefaults(jobOpts, r.config, nil)
+	}
 	
 	// Build the command to execute
 	command := strings.Join(cmd.Args, " ")
 	
+	name := jobOpts.Name
+	if name == "" {
+		name = filepath.Base(cmd.Args[0])
+	}
+	return r.runJob(command, name, jobOpts, shell)
+}
+
+// runJob runs command with srun and reports how it finished. Jobs that
+// have to wait for resources are remembered under name.
+func (r *RunCommand) runJob(command, name string, jobOpts *slurm.JobOptions, shell ShellInterface) error {
 	// Show what we're about to execute
 	fmt.Printf("Running: %s\n", command)
+	if jobOpts.JobID != "" {
+		fmt.Printf("Allocation: %s\n", jobOpts.JobID)
+	}
 	if jobOpts.Partition != "" {
 		fmt.Printf("Partition: %s\n", jobOpts.Partition)
 	}
-	if jobOpts.Nodes > 0 {
+	if jobOpts.NodeList != "" {
+		fmt.Printf("Nodes: %s\n", jobOpts.NodeList)
+	} else if jobOpts.Nodes > 0 {
 		fmt.Printf("Nodes: %d\n", jobOpts.Nodes)
 	}
+	if jobOpts.Exclude != "" {
+		fmt.Printf("Excluding: %s\n", jobOpts.Exclude)
+	}
 	if jobOpts.Time != "" {
 		fmt.Printf("Time limit: %s\n", jobOpts.Time)
 	}
 	fmt.Println()
 	
-	// Execute the job
-	result, err := r.client.RunJob(command, jobOpts)
-	if err != nil {
-		return fmt.Errorf("failed to run job: %v", err)
+	// Interactive jobs get the terminal and run until the user leaves them
+	if jobOpts.PTY {
+		if err := r.client.RunJobTerminal(command, jobOpts); err != nil {
+			return fmt.Errorf("interactive job failed: %v", err)
+		}
+		return nil
 	}
 	
-	// Display output
-	if result.Output != "" {
-		fmt.Print(result.Output)
+	// Execute the job, showing its output while it runs. srun names the
+	// job when run with -v, so that it can be tracked under the name Slurm
+	// gives it; unless the user asked for them, srun's progress lines are
+	// not shown.
+	var jobID string
+	quiet := false
+	if jobOpts.JobID == "" && !hasAnyArg(jobOpts.ExtraArgs, "-v", "--verbose", "-Q", "--quiet") {
+		jobOpts.ExtraArgs = append(jobOpts.ExtraArgs, "-v")
+		quiet = true
 	}
-	if result.Error != "" {
-		fmt.Print(result.Error)
+	printLine := streamPrinter(r.config.ColorOutput)
+	result, err := r.client.RunJobStream(command, jobOpts, func(line slurm.OutputLine) {
+		if !line.Stderr {
+			printLine(line)
+			return
+		}
+		if id, ok := slurm.ParseSubmittedJobID(line.Text); ok && jobID == "" && jobOpts.JobID == "" {
+			jobID = id
+			if shell != nil {
+				shell.TrackJob(jobID, name)
+			}
+		}
+		if !quiet || !slurm.IsSrunVerbose(line.Text) {
+			printLine(line)
+		}
+	})
+	if err != nil {
+		return fmt.Errorf("failed to run job: %v", err)
 	}
 	
 	// Show completion status
 	if result.Success {
-		fmt.Printf(utils.FormatSuccess("Job completed successfully", r.config.ColorOutput))
+		fmt.Print(utils.FormatSuccess("Job completed successfully", r.config.ColorOutput))
 	} else {
 		fmt.Printf(utils.FormatError("Job failed with exit code %d", r.config.ColorOutput), result.ExitCode)
 	}
@@ -76,37 +131,60 @@ func (r *RunCommand) Execute(cmd *slurm.Command, shell ShellInterface) error {
 	return nil
 }
 
-// applyDefaults applies default configuration to job options
-func (r *RunCommand) applyDefaults(opts *slurm.JobOptions) {
-	if opts.Partition == "" && r.config.DefaultPartition != "" {
-		opts.Partition = r.config.DefaultPartition
+// applyDefaults applies default configuration to job options. Options
+// set by the #SBATCH directives of a script are left to the script.
+func applyDefaults(opts *slurm.JobOptions, cfg *config.Config, directives map[string]string) {
+	scriptSets := func(names ...string) bool {
+		for _, name := range names {
+			if _, ok := directives[name]; ok {
+				return true
+			}
+		}
+		return false
+	}
+	
+	if opts.Partition == "" && cfg.DefaultPartition != "" && !scriptSets("partition") {
+		opts.Partition = cfg.DefaultPartition
 	}
 	
-	if opts.Nodes == 0 && r.config.DefaultNodes > 0 {
-		opts.Nodes = r.config.DefaultNodes
+	// An explicit node list determines the node count
+	if opts.Nodes == 0 && opts.NodeList == "" && cfg.DefaultNodes > 0 && !scriptSets("nodes", "nodelist") {
+		opts.Nodes = cfg.DefaultNodes
 	}
 	
-	if opts.CPUs == 0 && r.config.DefaultCPUs > 0 {
-		opts.CPUs = r.config.DefaultCPUs
+	if opts.CPUs == 0 && cfg.DefaultCPUs > 0 && !scriptSets("cpus-per-task") {
+		opts.CPUs = cfg.DefaultCPUs
 	}
 	
-	if opts.Memory == "" && r.config.DefaultMemory != "" {
-		opts.Memory = r.config.DefaultMemory
+	if opts.Memory == "" && cfg.DefaultMemory != "" && !scriptSets("mem", "mem-per-cpu", "mem-per-gpu") {
+		opts.Memory = cfg.DefaultMemory
 	}
 	
-	if opts.Time == "" && r.config.DefaultTime != "" {
-		opts.Time = r.config.DefaultTime
+	if opts.Time == "" && cfg.DefaultTime != "" && !scriptSets("time") {
+		opts.Time = cfg.DefaultTime
 	}
 	
-	if opts.QoS == "" && r.config.DefaultQoS != "" {
-		opts.QoS = r.config.DefaultQoS
+	if opts.QoS == "" && cfg.DefaultQoS != "" && !scriptSets("qos") {
+		opts.QoS = cfg.DefaultQoS
 	}
 	
-	if opts.Account == "" && r.config.DefaultAccount != "" {
-		opts.Account = r.config.DefaultAccount
+	if opts.Account == "" && cfg.DefaultAccount != "" && !scriptSets("account") {
+		opts.Account = cfg.DefaultAccount
 	}
 }
 
+// Flags returns the options that take no value
+func (r *RunCommand) Flags() []string {
+	flags := []string{"-l", "--label", "--pty", "-v", "--verbose"}
+	for _, flag := range slurm.JobFlags() {
+		// srun's --wait takes the seconds to wait for other tasks
+		if flag != "--wait" {
+			flags = append(flags, flag)
+		}
+	}
+	return flags
+}
+
 // Description returns the command description
 func (r *RunCommand) Description() string {
 	return "Execute a command using srun with configured defaults"
@@ -124,6 +202,9 @@ Examples:
   run -N 2 hostname               # Run on 2 nodes
   run -p gpu nvidia-smi           # Run on GPU partition
   run -t 30:00 ./my_simulation    # Run with 30 minute time limit
+  run -w node[01-04] hostname     # Run on specific nodes
+  run -l -N 2 hostname            # Prefix each line with its task number
+  run --pty bash                  # Interactive shell on a compute node
 
 Options:
   -J, --job-name <name>           Job name
@@ -136,17 +217,31 @@ Options:
   -A, --account <account>         Account to charge
   -o, --output <file>             Output file
   -e, --error <file>              Error file
+  -w, --nodelist <hosts>          Run on these nodes, e.g. gpu[01-04]
+  -x, --exclude <hosts>           Never run on these nodes
+  -l, --label                     Prefix output lines with the task number
+  --pty                           Run interactively on a pseudo-terminal
+  -v, --verbose                   Show srun's progress messages
 
-The command will use your configured defaults for any options not specified.`
+Output is shown as the job produces it; stderr is shown in red.
+Each job joins the session's jobs, so that %last refers to it.
+The command will use your configured defaults for any options not specified.
+Inside an allocation (see alloc), the command runs as a job step on the
+allocated resources and the defaults are not applied.`
 }
 
-// parseJobOptions parses command options into JobOptions struct
-func parseJobOptions(options map[string]string) *slurm.JobOptions {
+// parseJobOptions parses command options into JobOptions struct, in the
+// order they were given. Options in skip belong to the command itself.
+func parseJobOptions(cmd *slurm.Command, skip map[string]bool) *slurm.JobOptions {
 	jobOpts := &slurm.JobOptions{
 		Environment: make(map[string]string),
 	}
 
-	for opt, value := range options {
+	for _, opt := range optionOrder(cmd) {
+		if skip[opt] {
+			continue
+		}
+		value := cmd.Options[opt]
 		switch opt {
 		case "-J", "--job-name":
 			jobOpts.Name = value
@@ -174,6 +269,18 @@ func parseJobOptions(options map[string]string) *slurm.JobOptions {
 			jobOpts.Error = value
 		case "-D", "--chdir":
 			jobOpts.WorkDir = value
+		case "-w", "--nodelist":
+			jobOpts.NodeList = value
+		case "-x", "--exclude":
+			jobOpts.Exclude = value
+		case "--array":
+			jobOpts.Array = value
+		case "-d", "--dependency":
+			jobOpts.Dependency = value
+		case "-l", "--label":
+			jobOpts.Label = true
+		case "--pty":
+			jobOpts.PTY = true
 		default:
 			// Store unknown options as extra args
 			if value != "" {
@@ -187,6 +294,36 @@ func parseJobOptions(options map[string]string) *slurm.JobOptions {
 	return jobOpts
 }
 
+// resolveNodeLists validates the -w and -x hostlists of opts and rewrites
+// them in compressed form
+func resolveNodeLists(opts *slurm.JobOptions) error {
+	var err error
+	
+	if opts.NodeList != "" {
+		if opts.NodeList, err = hostlist.Normalize(opts.NodeList); err != nil {
+			return fmt.Errorf("invalid node list: %v", err)
+		}
+	}
+	
+	if opts.Exclude != "" {
+		if opts.Exclude, err = hostlist.Normalize(opts.Exclude); err != nil {
+			return fmt.Errorf("invalid exclude list: %v", err)
+		}
+	}
+	
+	if opts.NodeList != "" && opts.Exclude != "" {
+		overlap, err := hostlist.Intersect(opts.NodeList, opts.Exclude)
+		if err != nil {
+			return err
+		}
+		if len(overlap) > 0 {
+			return fmt.Errorf("nodes both requested and excluded: %s", hostlist.Compress(overlap))
+		}
+	}
+	
+	return nil
+}
+
 // parseInt safely parses a string to int
 func parseInt(s string) int {
 	var result int
@@ -198,4 +335,16 @@ func parseInt(s string) int {
 		}
 	}
 	return result
-}
\ No newline at end of file
+}
+
+// hasAnyArg reports whether args contains any of names
+func hasAnyArg(args []string, names ...string) bool {
+	for _, arg := range args {
+		for _, name := range names {
+			if arg == name {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -2,55 +2,180 @@ package commands
 
 import (
 	"fmt"
+	"strings"
+	"time"
 
+	"slsh/config"
 	"slsh/slurm"
+	"slsh/utils"
 )
 
+// statusTimeLayout is how status prints timestamps
+const statusTimeLayout = "2006-01-02 15:04:05"
+
 // StatusCommand implements the 'status' command
 type StatusCommand struct {
 	client *slurm.Client
+	config *config.Config
 }
 
 // NewStatusCommand creates a new status command
-func NewStatusCommand(client *slurm.Client) *StatusCommand {
+func NewStatusCommand(client *slurm.Client, cfg *config.Config) *StatusCommand {
 	return &StatusCommand{
 		client: client,
+		config: cfg,
 	}
 }
 
 // Execute executes the status command
 func (s *StatusCommand) Execute(cmd *slurm.Command, shell ShellInterface) error {
 	if len(cmd.Args) == 0 {
-		return fmt.Errorf("usage: status <job_id>")
+		return fmt.Errorf("usage: status <job_id>...")
 	}
 	
-	jobID := cmd.Args[0]
-	result, err := s.client.GetJobStatus(jobID)
-	if err != nil {
-		return fmt.Errorf("failed to get job status: %v", err)
+	for i, jobID := range cmd.Args {
+		detail, err := s.client.JobDetail(jobID)
+		if err != nil {
+			return fmt.Errorf("failed to get job status: %v", err)
+		}
+		
+		if i > 0 {
+			fmt.Println()
+		}
+		printJobDetail(detail, s.config.ColorOutput)
 	}
 	
-	if result.Output != "" {
-		fmt.Print(result.Output)
+	return nil
+}
+
+// printJobDetail prints the merged view of a job followed by its steps
+func printJobDetail(d *slurm.JobDetail, useColor bool) {
+	title := "Job " + d.ID
+	if d.Name != "" {
+		title += " (" + d.Name + ")"
 	}
-	if result.Error != "" {
-		fmt.Print(result.Error)
+	if useColor {
+		title = utils.ColorBold + title + utils.ColorReset
 	}
+	fmt.Println(title)
 	
-	return nil
+	field := func(label, value string) {
+		if value != "" {
+			fmt.Printf("  %-11s %s\n", label+":", value)
+		}
+	}
+	
+	finished := slurm.IsTerminalJobState(d.State)
+	
+	field("State", utils.FormatJobState(d.State, useColor))
+	if !strings.EqualFold(d.Reason, "None") {
+		field("Reason", d.Reason)
+	}
+	if finished {
+		field("Exit code", formatExitCode(d.ExitCode, d.Signal))
+	}
+	
+	user := d.User
+	if d.Account != "" {
+		user += " (account " + d.Account + ")"
+	}
+	field("User", user)
+	field("Partition", d.Partition)
+	
+	field("Submitted", formatStatusTime(d.SubmitTime))
+	if !d.StartTime.IsZero() && !d.SubmitTime.IsZero() && !d.StartTime.After(time.Now()) {
+		field("Started", fmt.Sprintf("%s (waited %s)", formatStatusTime(d.StartTime),
+			slurm.FormatSlurmDuration(d.StartTime.Sub(d.SubmitTime))))
+	} else if !d.StartTime.IsZero() {
+		field("Starts", formatStatusTime(d.StartTime)+" (expected)")
+	}
+	if finished {
+		field("Ended", formatStatusTime(d.EndTime))
+	} else if !d.EndTime.IsZero() {
+		field("Ends", formatStatusTime(d.EndTime)+" (at the time limit)")
+	}
+	field("Elapsed", formatElapsed(d.Elapsed, d.TimeLimit))
+	
+	field("Resources", d.TRES)
+	field("Nodes", d.NodeList)
+	field("Work dir", d.WorkDir)
+	field("Command", d.Command)
+	field("Stdout", d.StdOut)
+	if d.StdErr != d.StdOut {
+		field("Stderr", d.StdErr)
+	}
+	field("Sources", strings.Join(d.Sources, ", "))
+	
+	if len(d.Steps) == 0 {
+		return
+	}
+	
+	fmt.Println()
+	table := utils.NewTable([]string{"STEP", "NAME", "STATE", "EXIT", "ELAPSED", "NODES", "RESOURCES"}, useColor)
+	for _, step := range d.Steps {
+		exit := "-"
+		if slurm.IsTerminalJobState(step.State) {
+			exit = fmt.Sprintf("%d:%d", step.ExitCode, step.Signal)
+		}
+		table.AddRow([]string{
+			step.ID,
+			step.Name,
+			utils.FormatJobState(step.State, useColor),
+			exit,
+			slurm.FormatSlurmDuration(step.Elapsed),
+			step.NodeList,
+			step.TRES,
+		})
+	}
+	table.Print()
+}
+
+// formatExitCode describes an exit code and the signal that ended the job
+func formatExitCode(code, signal int) string {
+	if signal != 0 {
+		return fmt.Sprintf("%d (signal %d)", code, signal)
+	}
+	return fmt.Sprintf("%d", code)
+}
+
+// formatElapsed compares the run time of a job to its time limit
+func formatElapsed(elapsed time.Duration, limit string) string {
+	text := slurm.FormatSlurmDuration(elapsed)
+	if limit == "" {
+		return text
+	}
+	
+	total, err := slurm.ParseSlurmDuration(limit)
+	if err != nil || total <= 0 {
+		return text + " (no limit)"
+	}
+	return fmt.Sprintf("%s of %s (%d%%)", text, limit, int(elapsed*100/total))
+}
+
+// formatStatusTime formats a timestamp, leaving unknown times empty
+func formatStatusTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(statusTimeLayout)
 }
 
 // Description returns the command description
 func (s *StatusCommand) Description() string {
-	return "Show status of a specific job"
+	return "Show detailed status of jobs"
 }
 
 // Usage returns the command usage
 func (s *StatusCommand) Usage() string {
-	return `status <job_id>
+	return `status <job_id>...
 
-Show detailed status information for a specific job.
+Show detailed status information for jobs. The view combines scontrol,
+squeue and sacct, so it also works for jobs that finished long ago: state
+and reason, exit code and signal, submit, start and end times, elapsed
+time against the limit, allocated resources, nodes, working directory,
+output files and the steps of the job.
 
 Examples:
-  status 12345    # Show status of job 12345`
-}
\ No newline at end of file
+  status 12345          # Show status of job 12345
+  status 12345 12346    # Show several jobs`
+}
@@ -2,10 +2,21 @@ package commands
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	
 	"slsh/config"
 	"slsh/slurm"
 )
 
+// interpreters are commands whose first argument names the job better
+// than the command itself
+var interpreters = map[string]bool{
+	"bash": true, "sh": true, "python": true, "python3": true, "Rscript": true,
+	"julia": true, "perl": true, "ruby": true, "node": true, "matlab": true,
+}
+
 type SubmitCommand struct {
 	client *slurm.Client
 	config *config.Config
@@ -17,27 +28,288 @@ func NewSubmitCommand(client *slurm.Client, cfg *config.Config) *SubmitCommand {
 
 func (s *SubmitCommand) Execute(cmd *slurm.Command, shell ShellInterface) error {
 	if len(cmd.Args) == 0 {
-		return fmt.Errorf("usage: submit <script>")
+		return fmt.Errorf("usage: submit [options] <script> | submit [options] -- <command>")
+	}
+	
+	jobOpts := parseJobOptions(cmd, nil)
+	if err := resolveNodeLists(jobOpts); err != nil {
+		return err
+	}
+	if err := validateArray(jobOpts.Array); err != nil {
+		return err
+	}
+	
+	if cmd.EndOfOptions {
+		return s.submitCommand(cmd.Args, jobOpts, shell)
+	}
+	
+	_, err := s.submitFile(cmd.Args[0], jobOpts, shell)
+	return err
+}
+
+// submitFile submits a batch script file, returning the ID of the job
+func (s *SubmitCommand) submitFile(script string, jobOpts *slurm.JobOptions, shell ShellInterface) (string, error) {
+	// Defaults fill in what neither the command line nor the script sets
+	var directives map[string]string
+	if data, err := os.ReadFile(script); err == nil {
+		directives = slurm.ParseScriptDirectives(string(data))
+	}
+	applyDefaults(jobOpts, s.config, directives)
+	
+	printResourceRequest(jobOpts, directives)
+	
+	result, err := s.client.SubmitJob(script, jobOpts)
+	if err != nil {
+		return "", fmt.Errorf("failed to submit job: %v", err)
+	}
+	
+	if result.Output != "" {
+		fmt.Print(result.Output)
+	}
+	
+	// Remember the job for %last and report when it starts and finishes
+	jobID, _, ok := slurm.ParseSubmittedJob(result.Output)
+	if ok && shell != nil {
+		name := jobOpts.Name
+		if name == "" {
+			name = directives["job-name"]
+		}
+		if name == "" {
+			name = filepath.Base(script)
+		}
+		shell.TrackJob(jobID, name)
+	}
+	return jobID, nil
+}
+
+// submitCommand wraps a command in a generated batch script and submits it
+func (s *SubmitCommand) submitCommand(args []string, jobOpts *slurm.JobOptions, shell ShellInterface) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: submit [options] -- <command>")
+	}
+	
+	applyDefaults(jobOpts, s.config, nil)
+	if jobOpts.Name == "" {
+		jobOpts.Name = commandJobName(args)
+	}
+	
+	fmt.Printf("Submitting: %s\n", joinCommand(args))
+	_, err := s.submitScript(joinCommand(args), jobOpts, shell)
+	return err
+}
+
+// submitScript submits a script body through a file in the output
+// directory. Bodies without a shebang are wrapped in a generated batch
+// script; complete scripts are written as they are. The file is renamed
+// after the job once sbatch reports its ID, so it sits next to the job's
+// output. It returns the ID of the job.
+func (s *SubmitCommand) submitScript(body string, jobOpts *slurm.JobOptions, shell ShellInterface) (string, error) {
+	dir := expandHome(s.config.DefaultOutputDir)
+	if dir == "" {
+		dir = "."
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create output directory: %v", err)
+	}
+	
+	template := s.config.JobNameTemplate
+	if template == "" {
+		template = "slurm-%j"
+	}
+	if jobOpts.Output == "" {
+		jobOpts.Output = filepath.Join(dir, template+".out")
+	}
+	
+	// sbatch would otherwise run the job in the directory of the script
+	if jobOpts.WorkDir == "" {
+		if cwd, err := os.Getwd(); err == nil {
+			jobOpts.WorkDir = cwd
+		}
+	}
+	
+	file, err := os.CreateTemp(dir, jobOpts.Name+"-*.sh")
+	if err != nil {
+		return "", fmt.Errorf("failed to write batch script: %v", err)
+	}
+	script := file.Name()
+	content := body
+	if !strings.HasPrefix(body, "#!") {
+		content = s.client.BatchScript(body, jobOpts)
+	}
+	_, err = file.WriteString(content)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(script)
+		return "", fmt.Errorf("failed to write batch script: %v", err)
 	}
 	
-	script := cmd.Args[0]
-	jobOpts := parseJobOptions(cmd.Options)
+	printResourceRequest(jobOpts, slurm.ParseScriptDirectives(content))
 	
 	result, err := s.client.SubmitJob(script, jobOpts)
 	if err != nil {
-		return fmt.Errorf("failed to submit job: %v", err)
+		os.Remove(script)
+		return "", fmt.Errorf("failed to submit job: %v", err)
 	}
 	
 	if result.Output != "" {
 		fmt.Print(result.Output)
 	}
+	
+	jobID, _, ok := slurm.ParseSubmittedJob(result.Output)
+	if !ok {
+		fmt.Printf("Script: %s\n", script)
+		return "", nil
+	}
+	
+	// sbatch keeps its own copy, so the script can be renamed after the job
+	named := slurm.ExpandFilenamePattern(template, slurm.FilenameFields{
+		JobID: jobID,
+		Name:  jobOpts.Name,
+		User:  os.Getenv("USER"),
+	})
+	if !strings.Contains(named, "%") {
+		target := filepath.Join(dir, named+".sh")
+		if _, err := os.Stat(target); os.IsNotExist(err) && os.Rename(script, target) == nil {
+			script = target
+		}
+	}
+	fmt.Printf("Script: %s\n", script)
+	
+	if shell != nil {
+		shell.TrackJob(jobID, jobOpts.Name)
+	}
+	return jobID, nil
+}
+
+// printResourceRequest prints the resources a job asks for. Options the
+// command line leaves unset are taken from the script's directives.
+func printResourceRequest(opts *slurm.JobOptions, directives map[string]string) {
+	value := func(option, directive string) string {
+		if option != "" && option != "0" {
+			return option
+		}
+		return directives[directive]
+	}
+	field := func(label, value string) {
+		if value != "" && value != "0" {
+			fmt.Printf("  %-11s %s\n", label+":", value)
+		}
+	}
+	
+	field("Job name", value(opts.Name, "job-name"))
+	field("Partition", value(opts.Partition, "partition"))
+	if opts.NodeList != "" {
+		field("Nodes", opts.NodeList)
+	} else {
+		field("Nodes", value(fmt.Sprint(opts.Nodes), "nodes"))
+	}
+	field("CPUs/task", value(fmt.Sprint(opts.CPUs), "cpus-per-task"))
+	field("Memory", value(opts.Memory, "mem"))
+	field("Time limit", value(opts.Time, "time"))
+	field("Array", describeArray(value(opts.Array, "array")))
+	field("Depends on", value(opts.Dependency, "dependency"))
+	field("QoS", value(opts.QoS, "qos"))
+	field("Account", value(opts.Account, "account"))
+	field("Output", value(opts.Output, "output"))
+	field("Error", value(opts.Error, "error"))
+}
+
+// validateArray checks an --array specification, so that mistakes are
+// reported before anything is submitted
+func validateArray(spec string) error {
+	if spec == "" {
+		return nil
+	}
+	if _, _, err := slurm.ParseArrayIndices(spec); err != nil {
+		return fmt.Errorf("invalid --array: %v", err)
+	}
 	return nil
 }
 
+// describeArray adds the number of tasks to an --array specification
+func describeArray(spec string) string {
+	indices, throttle, err := slurm.ParseArrayIndices(spec)
+	if err != nil {
+		return spec
+	}
+	description := fmt.Sprintf("%s (%s", spec, countOf(len(indices), "task"))
+	if throttle > 0 {
+		description += fmt.Sprintf(", %d at a time", throttle)
+	}
+	return description + ")"
+}
+
+// commandJobName names a job after its command, or after the script an
+// interpreter runs, as in "train" for "python train.py"
+func commandJobName(args []string) string {
+	name := filepath.Base(args[0])
+	if interpreters[name] {
+		for _, arg := range args[1:] {
+			if !strings.HasPrefix(arg, "-") {
+				name = filepath.Base(arg)
+				break
+			}
+		}
+	}
+	return strings.TrimSuffix(name, filepath.Ext(name))
+}
+
+// joinCommand joins command arguments into a shell command line, quoting
+// arguments that contain spaces or quotes. Operators such as > and &&
+// are kept, so they work in the generated script.
+func joinCommand(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		if arg == "" || strings.ContainsAny(arg, " \t\n'\"") {
+			arg = "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+		}
+		quoted[i] = arg
+	}
+	return strings.Join(quoted, " ")
+}
+
+// expandHome replaces a leading ~ with the home directory
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, path[1:])
+}
+
+// Flags returns the options that take no value
+func (s *SubmitCommand) Flags() []string {
+	return slurm.JobFlags()
+}
+
 func (s *SubmitCommand) Description() string {
-	return "Submit a batch job script"
+	return "Submit a batch job script or command"
 }
 
 func (s *SubmitCommand) Usage() string {
-	return "submit <script> - Submit a job script using sbatch"
-}
\ No newline at end of file
+	return `submit [options] <script>
+submit [options] -- <command> [arguments...]
+
+Submit a batch script with sbatch, or wrap a command in a generated batch
+script. Configured defaults fill in the resources that neither the command
+line nor the script's #SBATCH lines request.
+
+Generated scripts write their output to default_output_dir, named after
+job_name_template (e.g. job_%j.out). The script is saved next to the output
+under the same name.
+
+Examples:
+  submit job.sh                          # Submit a script
+  submit -p gpu job.sh                   # Override the partition
+  submit -- python train.py --lr 0.1     # Submit a command
+  submit -J sweep -t 4:00:00 -- ./run.sh # Name the job and set a time limit
+  submit --array 1-100%10 sweep.sh       # 100 tasks, at most 10 running
+
+Array tasks find their index in $SLURM_ARRAY_TASK_ID. Use 'array <jobid>'
+to follow the tasks of an array and resubmit the ones that failed.`
+}
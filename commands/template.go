@@ -0,0 +1,407 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"slsh/config"
+	"slsh/slurm"
+	"slsh/utils"
+)
+
+// paramNamePattern matches the names of template parameters
+var paramNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// templateActions are the actions that take a template name
+var templateActions = map[string]bool{
+	"save":   true,
+	"show":   true,
+	"delete": true,
+	"rm":     true,
+	"use":    true,
+}
+
+// templateOptions are the options of the template command itself; all
+// others are job options
+var templateOptions = map[string]bool{
+	"--set":         true,
+	"--description": true,
+	"--run":         true,
+}
+
+// TemplateCommand implements the 'template' command
+type TemplateCommand struct {
+	client *slurm.Client
+	config *config.Config
+}
+
+// NewTemplateCommand creates a new template command
+func NewTemplateCommand(client *slurm.Client, cfg *config.Config) *TemplateCommand {
+	return &TemplateCommand{
+		client: client,
+		config: cfg,
+	}
+}
+
+// Execute executes the template command
+func (t *TemplateCommand) Execute(cmd *slurm.Command, shell ShellInterface) error {
+	if len(cmd.Args) == 0 || cmd.Args[0] == "list" || cmd.Args[0] == "ls" {
+		return t.list()
+	}
+
+	action, args := cmd.Args[0], cmd.Args[1:]
+	if !templateActions[action] {
+		return fmt.Errorf("unknown template action: %s (use save, list, show, delete or use)", action)
+	}
+	if len(args) == 0 {
+		return fmt.Errorf("usage: template %s <name>", action)
+	}
+
+	switch action {
+	case "show":
+		return t.show(args[0])
+	case "save":
+		return t.save(cmd, args[0], args[1:])
+	case "delete", "rm":
+		if err := config.DeleteTemplate(args[0]); err != nil {
+			return err
+		}
+		fmt.Printf("Template %s deleted\n", args[0])
+		return nil
+	default:
+		return t.use(cmd, args[0], args[1:], shell)
+	}
+}
+
+// save stores the job options of the command line under name, with the
+// script read from a file or the command given after --
+func (t *TemplateCommand) save(cmd *slurm.Command, name string, args []string) error {
+	opts := parseJobOptions(cmd, templateOptions)
+	if err := resolveNodeLists(opts); err != nil {
+		return err
+	}
+
+	tmpl := &config.Template{
+		Name:        name,
+		Description: cmd.Options["--description"],
+		Options:     *opts,
+		Created:     time.Now(),
+	}
+
+	switch {
+	case cmd.EndOfOptions:
+		tmpl.Script = joinCommand(args)
+	case len(args) == 1:
+		data, err := os.ReadFile(args[0])
+		if err != nil {
+			return fmt.Errorf("failed to read script: %v", err)
+		}
+		tmpl.Script = string(data)
+	case len(args) > 1:
+		return fmt.Errorf("usage: template save <name> [options] [<script> | -- <command>]")
+	}
+
+	defaults, err := parseParams(cmd.Options["--set"])
+	if err != nil {
+		return err
+	}
+	params := make(map[string]bool)
+	for _, param := range tmpl.Parameters() {
+		params[param] = true
+	}
+	for param := range defaults {
+		if !params[param] {
+			return fmt.Errorf("template %s has no parameter %s", name, param)
+		}
+	}
+	if len(defaults) > 0 {
+		tmpl.Defaults = defaults
+	}
+
+	_, err = config.LoadTemplate(name)
+	existed := err == nil
+
+	if err := config.SaveTemplate(tmpl); err != nil {
+		return err
+	}
+
+	if existed {
+		fmt.Printf("Template %s updated\n", name)
+	} else {
+		fmt.Printf("Template %s saved\n", name)
+	}
+	if params := tmpl.Parameters(); len(params) > 0 {
+		fmt.Printf("Parameters: %s\n", strings.Join(params, ", "))
+	}
+	return nil
+}
+
+// use fills in a template and submits it, or runs it with srun when
+// --run is given. Job options on the command line override the
+// template's, and templates without a script take the command after --.
+func (t *TemplateCommand) use(cmd *slurm.Command, name string, args []string, shell ShellInterface) error {
+	tmpl, err := config.LoadTemplate(name)
+	if err != nil {
+		return err
+	}
+
+	values, err := parseParams(cmd.Options["--set"])
+	if err != nil {
+		return err
+	}
+	opts, body, err := tmpl.Render(values)
+	if err != nil {
+		return err
+	}
+
+	override := parseJobOptions(cmd, templateOptions)
+	if err := resolveNodeLists(override); err != nil {
+		return err
+	}
+	mergeJobOptions(opts, override)
+
+	switch {
+	case cmd.EndOfOptions && body != "":
+		return fmt.Errorf("template %s has a script; a command cannot be added", name)
+	case cmd.EndOfOptions:
+		body = joinCommand(args)
+	case len(args) > 0:
+		return fmt.Errorf("unexpected argument %q (parameters are set with --set name=value)", args[0])
+	}
+	if strings.TrimSpace(body) == "" {
+		return fmt.Errorf("template %s has no script; give the command after --", name)
+	}
+
+	if opts.Name == "" {
+		opts.Name = name
+	}
+
+	if hasOption(cmd, "--run") {
+		applyDefaults(opts, t.config, nil)
+		return NewRunCommand(t.client, t.config).runJob(scriptCommand(body), opts.Name, opts, shell)
+	}
+
+	if err := validateArray(opts.Array); err != nil {
+		return err
+	}
+	applyDefaults(opts, t.config, slurm.ParseScriptDirectives(body))
+	fmt.Printf("Submitting template %s\n", name)
+	_, err = NewSubmitCommand(t.client, t.config).submitScript(body, opts, shell)
+	return err
+}
+
+// list prints the stored templates
+func (t *TemplateCommand) list() error {
+	templates, err := config.ListTemplates()
+	if err != nil {
+		return err
+	}
+	if len(templates) == 0 {
+		fmt.Println("No templates saved (see 'help template')")
+		return nil
+	}
+
+	table := utils.NewTable([]string{"Template", "Resources", "Parameters", "Description"}, t.config.ColorOutput)
+	for _, tmpl := range templates {
+		table.AddRow([]string{
+			tmpl.Name,
+			describeResources(&tmpl.Options),
+			strings.Join(tmpl.Parameters(), ", "),
+			tmpl.Description,
+		})
+	}
+	table.Print()
+	return nil
+}
+
+// show prints a template's options, parameters and script
+func (t *TemplateCommand) show(name string) error {
+	tmpl, err := config.LoadTemplate(name)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Template: %s\n", tmpl.Name)
+	if tmpl.Description != "" {
+		fmt.Printf("  %-11s %s\n", "Description:", tmpl.Description)
+	}
+	printResourceRequest(&tmpl.Options, nil)
+	if len(tmpl.Options.ExtraArgs) > 0 {
+		fmt.Printf("  %-11s %s\n", "Extra:", strings.Join(tmpl.Options.ExtraArgs, " "))
+	}
+
+	if params := tmpl.Parameters(); len(params) > 0 {
+		fmt.Println()
+		fmt.Println("Parameters:")
+		for _, param := range params {
+			if value, ok := tmpl.Defaults[param]; ok {
+				fmt.Printf("  %-11s default %s\n", param, value)
+			} else {
+				fmt.Printf("  %-11s required\n", param)
+			}
+		}
+	}
+
+	if tmpl.Script != "" {
+		fmt.Println()
+		fmt.Println("Script:")
+		for _, line := range strings.Split(strings.TrimRight(tmpl.Script, "\n"), "\n") {
+			fmt.Printf("  %s\n", line)
+		}
+	}
+	return nil
+}
+
+// parseParams parses "name=value" pairs separated by commas. Commas in a
+// value are kept when the text after them is not a new pair.
+func parseParams(value string) (map[string]string, error) {
+	params := make(map[string]string)
+	if value == "" {
+		return params, nil
+	}
+
+	last := ""
+	for _, item := range strings.Split(value, ",") {
+		name, val, ok := strings.Cut(item, "=")
+		if !ok || !paramNamePattern.MatchString(name) {
+			if last == "" {
+				return nil, fmt.Errorf("invalid parameter %q (use name=value)", item)
+			}
+			params[last] += "," + item
+			continue
+		}
+		params[name] = val
+		last = name
+	}
+	return params, nil
+}
+
+// mergeJobOptions overrides the options of base with those set in
+// override
+func mergeJobOptions(base, override *slurm.JobOptions) {
+	strs := []struct{ dst, src *string }{
+		{&base.Name, &override.Name},
+		{&base.Partition, &override.Partition},
+		{&base.Memory, &override.Memory},
+		{&base.Time, &override.Time},
+		{&base.QoS, &override.QoS},
+		{&base.Account, &override.Account},
+		{&base.Output, &override.Output},
+		{&base.Error, &override.Error},
+		{&base.WorkDir, &override.WorkDir},
+		{&base.NodeList, &override.NodeList},
+		{&base.Exclude, &override.Exclude},
+		{&base.Array, &override.Array},
+		{&base.Dependency, &override.Dependency},
+	}
+	for _, s := range strs {
+		if *s.src != "" {
+			*s.dst = *s.src
+		}
+	}
+
+	if override.Nodes > 0 {
+		base.Nodes = override.Nodes
+	}
+	if override.NodeList != "" && override.Nodes == 0 {
+		base.Nodes = 0
+	}
+	if override.CPUs > 0 {
+		base.CPUs = override.CPUs
+	}
+	base.Label = base.Label || override.Label
+	base.PTY = base.PTY || override.PTY
+
+	if base.Environment == nil {
+		base.Environment = make(map[string]string)
+	}
+	for key, value := range override.Environment {
+		base.Environment[key] = value
+	}
+	base.ExtraArgs = append(base.ExtraArgs, override.ExtraArgs...)
+}
+
+// describeResources summarizes job options in one line
+func describeResources(opts *slurm.JobOptions) string {
+	var parts []string
+	if opts.Partition != "" {
+		parts = append(parts, opts.Partition)
+	}
+	if opts.NodeList != "" {
+		parts = append(parts, opts.NodeList)
+	} else if opts.Nodes > 1 {
+		parts = append(parts, fmt.Sprintf("%d nodes", opts.Nodes))
+	}
+	if opts.CPUs > 0 {
+		parts = append(parts, fmt.Sprintf("%d CPUs/task", opts.CPUs))
+	}
+	if opts.Memory != "" {
+		parts = append(parts, opts.Memory)
+	}
+	if opts.Time != "" {
+		parts = append(parts, opts.Time)
+	}
+	parts = append(parts, opts.ExtraArgs...)
+	return strings.Join(parts, " ")
+}
+
+// scriptCommand turns a script body into a command for srun. A body of
+// one command is run as it is; longer scripts are run by bash.
+func scriptCommand(body string) string {
+	var commands []string
+	for _, line := range strings.Split(body, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "#") {
+			commands = append(commands, line)
+		}
+	}
+	if len(commands) == 1 {
+		return commands[0]
+	}
+	return joinCommand([]string{"bash", "-c", body})
+}
+
+// Flags returns the options that take no value
+func (t *TemplateCommand) Flags() []string {
+	return append([]string{"--run", "-l", "--label", "--pty", "-v", "--verbose"}, slurm.JobFlags()...)
+}
+
+// Description returns the command description
+func (t *TemplateCommand) Description() string {
+	return "Save and reuse named job templates"
+}
+
+// Usage returns the command usage
+func (t *TemplateCommand) Usage() string {
+	return `template [list]
+template save <name> [options] [<script> | -- <command>]
+template show <name>
+template delete <name>
+template use <name> [--set name=value,...] [--run] [options] [-- <command>]
+
+Templates keep job options and a script body under the slsh config
+directory. The script and option values may contain {{param}}
+placeholders, which are filled in with --set when the template is used.
+Values given with --set when saving become the defaults.
+
+Templates are submitted with sbatch, or run with srun when --run is
+given. Options given to 'use' override the template's, and templates
+saved without a script take the command after --.
+
+Options:
+  --set <name=value,...>          Parameter values (defaults when saving)
+  --description <text>            Describe the template when saving
+  --run                           Run with srun instead of submitting
+
+Examples:
+  template save a100-debug -p gpu --gres gpu:a100:1 -t 30:00
+  template use a100-debug -- python check.py
+  template save gpu-train -p gpu -t 4:00:00 -- python train.py --epochs {{epochs}}
+  template use gpu-train --set epochs=10
+  template save mpi4 -N 4 --set steps=100 sim.sh
+  template use mpi4 --set steps=500 -t 8:00:00
+  template use gpu-train --set epochs=1 --run`
+}
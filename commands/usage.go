@@ -0,0 +1,454 @@
+package commands
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"slsh/config"
+	"slsh/slurm"
+	"slsh/utils"
+)
+
+// usageGroupings are the ways usage can be grouped, with their column
+// titles
+var usageGroupings = map[string]string{
+	"user":      "USER",
+	"account":   "ACCOUNT",
+	"partition": "PARTITION",
+	"day":       "DAY",
+}
+
+// usageMetrics are the figures usage can chart and sort by
+var usageMetrics = map[string]string{
+	"cpu":     "CPU-hours",
+	"gpu":     "GPU-hours",
+	"billing": "billing-hours",
+}
+
+// usageBarWidth is the width of the bars of usage charts
+const usageBarWidth = 30
+
+// budgetWarning is the share of a budget from which accounts are flagged
+const budgetWarning = 0.8
+
+// UsageCommand implements the 'usage' command
+type UsageCommand struct {
+	client *slurm.Client
+	config *config.Config
+}
+
+// NewUsageCommand creates a new usage command
+func NewUsageCommand(client *slurm.Client, cfg *config.Config) *UsageCommand {
+	return &UsageCommand{
+		client: client,
+		config: cfg,
+	}
+}
+
+// usageTotals is the usage of a group of jobs
+type usageTotals struct {
+	key      string
+	jobs     int
+	cpuHours float64
+	gpuHours float64
+	billing  float64
+}
+
+// metric returns one of usageMetrics
+func (t *usageTotals) metric(name string) float64 {
+	switch name {
+	case "gpu":
+		return t.gpuHours
+	case "billing":
+		return t.billing
+	}
+	return t.cpuHours
+}
+
+// Execute executes the usage command
+func (u *UsageCommand) Execute(cmd *slurm.Command, shell ShellInterface) error {
+	if len(cmd.Args) > 0 {
+		return fmt.Errorf("usage: usage [--by user|account|partition|day] [--since time] [--until time] [--budget] [--chart]")
+	}
+
+	by, _ := optionValue(cmd, "-g", "--by")
+	if by == "" {
+		by = "account"
+	}
+	if _, ok := usageGroupings[by]; !ok {
+		return fmt.Errorf("cannot group by %s; use user, account, partition or day", by)
+	}
+	metric, _ := optionValue(cmd, "-m", "--metric")
+	if metric == "" {
+		metric = "cpu"
+	}
+	if _, ok := usageMetrics[metric]; !ok {
+		return fmt.Errorf("unknown metric: %s; use cpu, gpu or billing", metric)
+	}
+
+	now := time.Now()
+	since, until, err := timeRange(cmd, startOfMonth(now), now)
+	if err != nil {
+		return err
+	}
+
+	budget := hasOption(cmd, "--budget")
+	filter, err := u.filter(cmd, budget)
+	if err != nil {
+		return err
+	}
+	filter.Since, filter.Until = since, until
+
+	records, err := u.client.ListAccounting(filter)
+	if err != nil {
+		return fmt.Errorf("failed to get accounting data: %v", err)
+	}
+
+	fmt.Printf("Usage of %s from %s to %s\n\n", describeUsageScope(filter), since.Format("2006-01-02 15:04"), until.Format("2006-01-02 15:04"))
+	if budget {
+		totals, _ := aggregateUsage(records, "account", since, until, now)
+		u.printBudgets(totals, filter.Accounts, hasOption(cmd, "--chart"))
+		return nil
+	}
+
+	totals, overall := aggregateUsage(records, by, since, until, now)
+	if len(totals) == 0 {
+		fmt.Println("No jobs ran in this period")
+		return nil
+	}
+	if by != "day" {
+		slices.SortStableFunc(totals, func(a, b usageTotals) int {
+			return cmp.Compare(b.metric(metric), a.metric(metric))
+		})
+	}
+
+	if hasOption(cmd, "--chart") {
+		printUsageChart(totals, metric, u.config.ColorOutput)
+	} else {
+		table := utils.NewTable([]string{usageGroupings[by], "JOBS", "CPU-HOURS", "GPU-HOURS", "BILLING"}, u.config.ColorOutput)
+		for _, t := range totals {
+			table.AddRow([]string{t.key, fmt.Sprint(t.jobs), formatHours(t.cpuHours), formatHours(t.gpuHours), formatHours(t.billing)})
+		}
+		table.Print()
+	}
+
+	fmt.Printf("\n%s: %s CPU-hours, %s GPU-hours, %s billing-hours\n",
+		countOf(overall.jobs, "job"), formatHours(overall.cpuHours), formatHours(overall.gpuHours), formatHours(overall.billing))
+	return nil
+}
+
+// filter builds the accounting filter of a usage command line. Without
+// users, accounts or --all, only the current user's jobs count. Budgets
+// cover whole accounts, those with a configured budget by default.
+func (u *UsageCommand) filter(cmd *slurm.Command, budget bool) (*slurm.JobFilter, error) {
+	filter := &slurm.JobFilter{}
+
+	users, _ := optionValue(cmd, "-u", "--user")
+	filter.Users = splitList(users)
+	accounts, _ := optionValue(cmd, "-A", "--account")
+	filter.Accounts = splitList(accounts)
+	partitions, _ := optionValue(cmd, "-p", "--partition")
+	filter.Partitions = splitList(partitions)
+
+	if budget && len(filter.Accounts) == 0 {
+		filter.Accounts = slices.Sorted(maps.Keys(u.config.Budgets))
+		if len(filter.Accounts) == 0 {
+			return nil, fmt.Errorf("no budgets configured; set one with 'config set budget.<account> <billing-hours>'")
+		}
+	}
+
+	all := hasOption(cmd, "-a", "--all")
+	if all && len(filter.Users) > 0 {
+		return nil, fmt.Errorf("--all cannot be combined with user names")
+	}
+	if !all && len(filter.Users) == 0 && len(filter.Accounts) == 0 {
+		if user := os.Getenv("USER"); user != "" {
+			filter.Users = []string{user}
+		}
+	}
+	return filter, nil
+}
+
+// describeUsageScope describes whose jobs a filter covers
+func describeUsageScope(filter *slurm.JobFilter) string {
+	var parts []string
+	if len(filter.Users) > 0 {
+		parts = append(parts, strings.Join(filter.Users, ", "))
+	}
+	if len(filter.Accounts) > 0 {
+		label := "account "
+		if len(filter.Accounts) > 1 {
+			label = "accounts "
+		}
+		parts = append(parts, label+strings.Join(filter.Accounts, ", "))
+	}
+	if len(parts) == 0 {
+		parts = append(parts, "all users")
+	}
+	if len(filter.Partitions) > 0 {
+		parts = append(parts, "in "+strings.Join(filter.Partitions, ", "))
+	}
+	return strings.Join(parts, " ")
+}
+
+// aggregateUsage adds up the resources jobs held between since and until,
+// by group, and in total. Running jobs count until now, and jobs that ran
+// across the edges of the period only for the part inside it. By day,
+// jobs count towards every day they ran on.
+func aggregateUsage(records []slurm.JobRecord, by string, since, until, now time.Time) ([]usageTotals, usageTotals) {
+	var totals []usageTotals
+	index := make(map[string]int)
+	var overall usageTotals
+
+	add := func(key string, hours, cpus, gpus, billing float64) {
+		i, ok := index[key]
+		if !ok {
+			i = len(totals)
+			index[key] = i
+			totals = append(totals, usageTotals{key: key})
+		}
+		totals[i].jobs++
+		totals[i].cpuHours += hours * cpus
+		totals[i].gpuHours += hours * gpus
+		totals[i].billing += hours * billing
+	}
+
+	for i := range records {
+		record := &records[i]
+		start, end := record.StartTime, record.EndTime
+		if start.IsZero() {
+			continue
+		}
+		if end.IsZero() || end.After(now) {
+			end = now
+		}
+		start, end = maxTime(start, since), minTime(end, until)
+		if !end.After(start) {
+			continue
+		}
+
+		cpus := float64(record.CPUs)
+		if cpus == 0 {
+			cpus = record.TRESCount("cpu")
+		}
+		gpus := record.TRESCount("gres/gpu")
+		// Without billing weights, Slurm bills each allocated CPU
+		billing := record.TRESCount("billing")
+		if billing == 0 {
+			billing = cpus
+		}
+
+		hours := end.Sub(start).Hours()
+		overall.jobs++
+		overall.cpuHours += hours * cpus
+		overall.gpuHours += hours * gpus
+		overall.billing += hours * billing
+
+		switch by {
+		case "day":
+			for from := start; from.Before(end); {
+				next := time.Date(from.Year(), from.Month(), from.Day()+1, 0, 0, 0, 0, from.Location())
+				to := minTime(next, end)
+				add(from.Format("2006-01-02"), to.Sub(from).Hours(), cpus, gpus, billing)
+				from = to
+			}
+		case "user":
+			add(record.User, hours, cpus, gpus, billing)
+		case "partition":
+			add(record.Partition, hours, cpus, gpus, billing)
+		default:
+			add(record.Account, hours, cpus, gpus, billing)
+		}
+	}
+
+	if by == "day" {
+		slices.SortFunc(totals, func(a, b usageTotals) int { return strings.Compare(a.key, b.key) })
+	}
+	return totals, overall
+}
+
+// printBudgets compares the billing-hours accounts used with their
+// budgets, as a table or as a chart
+func (u *UsageCommand) printBudgets(totals []usageTotals, accounts []string, chart bool) {
+	useColor := u.config.ColorOutput
+	used := make(map[string]usageTotals)
+	for _, t := range totals {
+		used[t.key] = t
+	}
+	for account := range used {
+		if !slices.Contains(accounts, account) {
+			accounts = append(accounts, account)
+		}
+	}
+	slices.Sort(accounts)
+
+	mark := func(text string, ratio float64) string {
+		switch {
+		case !useColor:
+			return text
+		case ratio >= 1:
+			return utils.ColorRed + text + utils.ColorReset
+		case ratio >= budgetWarning:
+			return utils.ColorYellow + text + utils.ColorReset
+		}
+		return text
+	}
+
+	var warnings []string
+	table := utils.NewTable([]string{"ACCOUNT", "JOBS", "CPU-HOURS", "GPU-HOURS", "USED", "BUDGET", "USE", "LEFT"}, useColor)
+	width := 0
+	for _, account := range accounts {
+		width = max(width, len(account))
+	}
+
+	for _, account := range accounts {
+		t := used[account]
+		limit, hasBudget := u.config.Budgets[account]
+		ratio := 0.0
+		if hasBudget {
+			ratio = t.billing / limit
+			switch {
+			case ratio >= 1:
+				warnings = append(warnings, fmt.Sprintf("%s is over its budget by %s billing-hours", account, formatHours(t.billing-limit)))
+			case ratio >= budgetWarning:
+				warnings = append(warnings, fmt.Sprintf("%s has used %s of its budget", account, formatRatio(ratio)))
+			}
+		}
+
+		if chart {
+			line := fmt.Sprintf("%-*s  ", width, account)
+			if hasBudget {
+				line += mark(usageBar(ratio, usageBarWidth), ratio)
+				line += fmt.Sprintf("  %s of %s billing-hours", mark(formatRatio(ratio), ratio), formatHours(limit))
+			} else {
+				line += fmt.Sprintf("%-*s  %s billing-hours, no budget", usageBarWidth, "", formatHours(t.billing))
+			}
+			fmt.Println(line)
+			continue
+		}
+
+		budget, use, left := "-", "-", "-"
+		if hasBudget {
+			budget = formatHours(limit)
+			use = mark(formatRatio(ratio), ratio)
+			left = formatHours(max(limit-t.billing, 0))
+		}
+		table.AddRow([]string{account, fmt.Sprint(t.jobs), formatHours(t.cpuHours), formatHours(t.gpuHours), formatHours(t.billing), budget, use, left})
+	}
+	if !chart {
+		table.Print()
+	}
+
+	if len(warnings) > 0 {
+		fmt.Println()
+		for _, warning := range warnings {
+			fmt.Println(utils.FormatWarning(warning, useColor))
+		}
+	}
+}
+
+// printUsageChart draws a bar per group, scaled to the largest
+func printUsageChart(totals []usageTotals, metric string, useColor bool) {
+	width, largest := 0, 0.0
+	for _, t := range totals {
+		width = max(width, len(t.key))
+		largest = max(largest, t.metric(metric))
+	}
+
+	for _, t := range totals {
+		ratio := 0.0
+		if largest > 0 {
+			ratio = t.metric(metric) / largest
+		}
+		bar := usageBar(ratio, usageBarWidth)
+		if useColor {
+			bar = utils.ColorCyan + bar + utils.ColorReset
+		}
+		fmt.Printf("%-*s  %s  %s %s\n", width, t.key, bar, formatHours(t.metric(metric)), usageMetrics[metric])
+	}
+}
+
+// usageBar draws a bar filled to ratio, which is capped at one
+func usageBar(ratio float64, width int) string {
+	filled := int(min(max(ratio, 0), 1)*float64(width) + 0.5)
+	return strings.Repeat("█", filled) + strings.Repeat("░", width-filled)
+}
+
+// formatHours formats a number of hours
+func formatHours(hours float64) string {
+	return fmt.Sprintf("%.1f", hours)
+}
+
+// minTime returns the earlier of two times
+func minTime(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
+
+// maxTime returns the later of two times
+func maxTime(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+// Flags returns the options that take no value
+func (u *UsageCommand) Flags() []string {
+	return []string{"-a", "--all", "--budget", "--chart"}
+}
+
+// Description returns the command description
+func (u *UsageCommand) Description() string {
+	return "Report CPU-hours, GPU-hours and billing by user, account, partition or day"
+}
+
+// Usage returns the command usage
+func (u *UsageCommand) Usage() string {
+	return `usage [OPTIONS]
+
+Add up the resources jobs held, from accounting (sacct), in CPU-hours,
+GPU-hours and billing-hours, grouped by account unless --by says
+otherwise. The period is the current month unless --since and --until
+say otherwise; jobs that ran across its edges count only for the part
+inside it, and running jobs count until now.
+
+Without users, accounts or --all only your own jobs count; with
+accounts, the jobs of everyone in them do (as far as Slurm shows them
+to you).
+
+--budget compares the billing-hours of accounts with their budgets,
+set with 'config set budget.<account> <billing-hours>', and warns about
+accounts that used 80% of theirs or more.
+
+Times are months (2024-01), dates (2024-01-15), dates and times
+(2024-01-15T08:00), today, yesterday, month for the start of this month,
+or ages such as 12h, 7d or 2w.
+
+Options:
+  -g, --by <group>              user, account, partition or day
+  --since <time>                Start of the period (default month)
+  --until <time>                End of the period (default now)
+  -u, --user <users>            Users, comma separated
+  -A, --account <accounts>      Accounts, comma separated
+  -p, --partition <partitions>  Partitions, comma separated
+  -a, --all                     Jobs of all users
+  --budget                      Show accounts against their budgets
+  --chart                       Draw a text bar chart instead of a table
+  -m, --metric <metric>         cpu, gpu or billing: what --chart draws
+                                and groups are sorted by (default cpu)
+
+Examples:
+  usage                         # Your usage this month, by account
+  usage -A physics --by user    # Who used the physics account
+  usage --since 2024-01 --until 2024-02 --by day --chart
+  usage --by partition -m gpu   # GPU-hours per partition
+  usage --budget --chart        # Accounts against their budgets`
+}
@@ -0,0 +1,287 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"slsh/config"
+	"slsh/slurm"
+	"slsh/utils"
+)
+
+// defaultWatchInterval is the refresh interval when none is given
+const defaultWatchInterval = 2 * time.Second
+
+// minWatchInterval keeps watch mode from flooding slurmctld
+const minWatchInterval = 500 * time.Millisecond
+
+// clearScreen moves the cursor home and clears the terminal
+const clearScreen = "\033[H\033[2J"
+
+// watchable is implemented by commands that can redraw themselves in
+// watch mode, highlighting what changed since the previous refresh
+type watchable interface {
+	render(cmd *slurm.Command, changes *changeTracker) error
+}
+
+// changeTracker remembers the state of each item between refreshes. A nil
+// tracker reports no changes.
+type changeTracker struct {
+	previous map[string]string
+	current  map[string]string
+	started  bool
+}
+
+// newChangeTracker creates a tracker for a watch session
+func newChangeTracker() *changeTracker {
+	return &changeTracker{current: make(map[string]string)}
+}
+
+// changed records the state of key and reports whether it differs from
+// the previous refresh. Nothing counts as changed on the first refresh.
+func (t *changeTracker) changed(key, state string) bool {
+	if t == nil {
+		return false
+	}
+	t.current[key] = state
+	if !t.started {
+		return false
+	}
+	previous, seen := t.previous[key]
+	return !seen || previous != state
+}
+
+// next ends a refresh
+func (t *changeTracker) next() {
+	t.previous, t.current = t.current, make(map[string]string)
+	t.started = true
+}
+
+// WatchCommand implements the 'watch' command
+type WatchCommand struct {
+	registry *Registry
+	client   *slurm.Client
+	config   *config.Config
+}
+
+// NewWatchCommand creates a new watch command
+func NewWatchCommand(registry *Registry, client *slurm.Client, cfg *config.Config) *WatchCommand {
+	return &WatchCommand{
+		registry: registry,
+		client:   client,
+		config:   cfg,
+	}
+}
+
+// Execute executes the watch command
+func (w *WatchCommand) Execute(cmd *slurm.Command, shell ShellInterface) error {
+	args := cmd.Args
+	interval := defaultWatchInterval
+	if value, ok := optionValue(cmd, "--interval"); ok {
+		var err error
+		if interval, err = parseWatchInterval(value); err != nil {
+			return err
+		}
+	} else if len(args) > 0 {
+		if parsed, err := parseWatchInterval(args[0]); err == nil {
+			interval, args = parsed, args[1:]
+		}
+	}
+
+	if len(args) == 0 {
+		return fmt.Errorf("usage: watch [interval] <command> [arguments...]")
+	}
+	if args[0] == "watch" {
+		return fmt.Errorf("cannot watch the watch command")
+	}
+
+	inner := &slurm.Command{
+		Name:         args[0],
+		Args:         append([]string(nil), args[1:]...),
+		Options:      make(map[string]string),
+		EndOfOptions: cmd.EndOfOptions,
+	}
+	for _, opt := range optionOrder(cmd) {
+		if opt != "--interval" && opt != "--watch" {
+			inner.SetOption(opt, cmd.Options[opt])
+		}
+	}
+
+	title := strings.TrimSpace(inner.Name + " " + strings.Join(buildArgs(inner), " "))
+	render := func() error { return w.registry.Execute(inner, shell) }
+	if handler, ok := w.registry.GetCommand(inner.Name); ok {
+		if view, ok := handler.(watchable); ok {
+			changes := newChangeTracker()
+			render = func() error {
+				defer changes.next()
+				return view.render(inner, changes)
+			}
+		}
+	}
+
+	return runWatch(w.client, title, interval, render, w.config.ColorOutput)
+}
+
+// watchInterval reports whether cmd asks for watch mode, and at which
+// interval. A value that is not an interval belongs to the command and is
+// moved back to its arguments.
+func watchInterval(cmd *slurm.Command) (time.Duration, bool) {
+	value, ok := cmd.Options["--watch"]
+	if !ok {
+		return 0, false
+	}
+	if value == "" {
+		return defaultWatchInterval, true
+	}
+
+	interval, err := parseWatchInterval(value)
+	if err != nil {
+		cmd.Args = append(cmd.Args, value)
+		return defaultWatchInterval, true
+	}
+	return interval, true
+}
+
+// parseWatchInterval parses an interval in seconds, such as "5" or "0.5",
+// or a Go duration such as "1m"
+func parseWatchInterval(value string) (time.Duration, error) {
+	interval, err := time.ParseDuration(value)
+	if err != nil {
+		seconds, ferr := strconv.ParseFloat(value, 64)
+		if ferr != nil {
+			return 0, fmt.Errorf("invalid interval: %s", value)
+		}
+		interval = time.Duration(seconds * float64(time.Second))
+	}
+	if interval < minWatchInterval {
+		return 0, fmt.Errorf("interval must be at least %s", minWatchInterval)
+	}
+	return interval, nil
+}
+
+// watchView runs a watchable command in watch mode
+func watchView(client *slurm.Client, view watchable, cmd *slurm.Command, interval time.Duration, useColor bool) error {
+	changes := newChangeTracker()
+	title := strings.TrimSpace(cmd.Name + " " + strings.Join(buildArgs(cmd), " "))
+	return runWatch(client, title, interval, func() error {
+		defer changes.next()
+		return view.render(cmd, changes)
+	}, useColor)
+}
+
+// runWatch redraws the output of render every interval until a key is
+// pressed or the client is interrupted. Errors are shown in place of the
+// output, so that a slow controller does not end the watch.
+func runWatch(client *slurm.Client, title string, interval time.Duration, render func() error, useColor bool) error {
+	ctx, cancel := client.Interruptible()
+	defer cancel()
+
+	keyHint := "Ctrl+C to exit"
+	if restored, ok := stopOnKey(ctx, cancel); ok {
+		keyHint = "press any key to exit"
+		// The terminal must be back in line mode before the prompt returns
+		defer func() {
+			cancel()
+			<-restored
+		}()
+	}
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			fmt.Println()
+			return nil
+		case <-timer.C:
+		}
+
+		fmt.Print(clearScreen)
+		header := fmt.Sprintf("Every %s: %s", interval, title)
+		fmt.Printf("%s    %s\n", header, time.Now().Format("15:04:05"))
+		fmt.Printf("Changes are highlighted; %s\n\n", keyHint)
+
+		if err := render(); err != nil && ctx.Err() == nil {
+			fmt.Println(utils.FormatError(err.Error(), useColor))
+		}
+
+		timer.Reset(interval)
+	}
+}
+
+// stopOnKey cancels the watch when a key is pressed on the terminal and
+// reports whether stdin is a terminal it could watch. The returned
+// channel is closed once the terminal mode has been restored.
+func stopOnKey(ctx context.Context, cancel context.CancelFunc) (<-chan struct{}, bool) {
+	fd := int(os.Stdin.Fd())
+	if !utils.IsTerminal(fd) {
+		return nil, false
+	}
+
+	state, err := utils.MakeKeyPoll(fd)
+	if err != nil {
+		return nil, false
+	}
+
+	restored := make(chan struct{})
+	go func() {
+		defer close(restored)
+		defer utils.RestoreTerminal(fd, state)
+
+		buf := make([]byte, 16)
+		for ctx.Err() == nil {
+			// Reads time out every tenth of a second, so the loop ends
+			// soon after the watch does without swallowing later input
+			if n, _ := os.Stdin.Read(buf); n > 0 {
+				cancel()
+				return
+			}
+		}
+	}()
+	return restored, true
+}
+
+// Flags returns the options that take no value. The watched command is
+// only known once the line is parsed, so its options are those of any
+// command.
+func (w *WatchCommand) Flags() []string {
+	var flags []string
+	for name, handler := range w.registry.GetCommands() {
+		if name == "watch" {
+			continue
+		}
+		for flag := range HandlerFlags(handler) {
+			if !slices.Contains(flags, flag) {
+				flags = append(flags, flag)
+			}
+		}
+	}
+	return flags
+}
+
+// Description returns the command description
+func (w *WatchCommand) Description() string {
+	return "Re-run a command periodically, highlighting changes"
+}
+
+// Usage returns the command usage
+func (w *WatchCommand) Usage() string {
+	return `watch [interval] <command> [arguments...]
+
+Redraw the output of a command in place every few seconds until a key
+is pressed or Ctrl+C. For queue, jobs and nodes, jobs and nodes whose
+state changed since the previous refresh are highlighted.
+
+The interval is in seconds (default 2) or a duration such as 1m.
+
+Examples:
+  watch queue --all -p gpu        # Watch the gpu partition
+  watch 10 nodes                  # Refresh the node list every 10 seconds
+  watch --interval 1m squeue      # Watch any Slurm command`
+}
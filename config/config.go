@@ -34,10 +34,41 @@ type Config struct {
 	
 	// Advanced settings
 	CommandTimeout   int  `json:"command_timeout_seconds"`
+	SubmitTimeout    int  `json:"submit_timeout_seconds"`
+	QueryRetries     int  `json:"query_retries"`
 	ConfirmDangerous bool `json:"confirm_dangerous_operations"`
 	SaveJobHistory   bool `json:"save_job_history"`
+	
+	// Job notifications
+	NotifyJobs     bool   `json:"notify_jobs"`
+	NotifyAllJobs  bool   `json:"notify_all_jobs"`
+	NotifyInterval int    `json:"notify_interval_seconds"`
+	NotifyBell     bool   `json:"notify_bell"`
+	NotifyHook     string `json:"notify_hook,omitempty"`
+	
+	// Budgets are the billing-hours each account may use in a reporting
+	// period, as shown by 'usage --budget'
+	Budgets map[string]float64 `json:"budgets,omitempty"`
+	
+	// Backend settings
+	Backend     string `json:"backend"`
+	FixtureFile string `json:"fixture_file,omitempty"`
+	
+	// slurmrestd settings, used by the rest backend. The token is only
+	// taken from SLURM_JWT, so that it is never written to this file.
+	RestURL     string `json:"rest_url,omitempty"`
+	RestVersion string `json:"rest_api_version,omitempty"`
+	RestUser    string `json:"rest_user,omitempty"`
 }
 
+// Backends that can execute Slurm commands
+const (
+	BackendCLI    = "cli"
+	BackendFake   = "fake"
+	BackendRecord = "record"
+	BackendREST   = "rest"
+)
+
 // Default returns a configuration with sensible defaults
 func Default() *Config {
 	homeDir, _ := os.UserHomeDir()
@@ -74,8 +105,17 @@ func Default() *Config {
 		
 		// Advanced settings
 		CommandTimeout:   30,
+		SubmitTimeout:    15,
+		QueryRetries:     3,
 		ConfirmDangerous: true,
 		SaveJobHistory:   true,
+		
+		// Job notifications
+		NotifyJobs:     true,
+		NotifyInterval: 30,
+		
+		// Backend settings
+		Backend: BackendCLI,
 	}
 }
 
@@ -126,6 +166,12 @@ func (c *Config) Save() error {
 	return nil
 }
 
+// GetRestToken returns the slurmrestd token from the SLURM_JWT
+// environment variable set by 'scontrol token'
+func (c *Config) GetRestToken() string {
+	return os.Getenv("SLURM_JWT")
+}
+
 // GetConfigPath returns the path to the configuration file
 func GetConfigPath() string {
 	homeDir, err := os.UserHomeDir()
@@ -146,6 +192,32 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("command_timeout_seconds must be at least 1")
 	}
 	
+	if c.SubmitTimeout < 1 {
+		return fmt.Errorf("submit_timeout_seconds must be at least 1")
+	}
+	
+	if c.QueryRetries < 0 {
+		return fmt.Errorf("query_retries cannot be negative")
+	}
+	
+	if c.NotifyJobs && c.NotifyInterval < 5 {
+		return fmt.Errorf("notify_interval_seconds must be at least 5")
+	}
+	
+	switch c.Backend {
+	case "", BackendCLI, BackendFake:
+	case BackendRecord:
+		if c.FixtureFile == "" {
+			return fmt.Errorf("backend %q requires fixture_file", c.Backend)
+		}
+	case BackendREST:
+		if c.RestURL == "" {
+			return fmt.Errorf("backend %q requires rest_url", c.Backend)
+		}
+	default:
+		return fmt.Errorf("unknown backend: %s", c.Backend)
+	}
+	
 	// Validate time format if set
 	if c.DefaultTime != "" && !isValidTimeFormat(c.DefaultTime) {
 		return fmt.Errorf("invalid default_time format: %s", c.DefaultTime)
@@ -185,6 +257,19 @@ func (c *Config) GetAlias(name string) (string, bool) {
 	return alias, exists
 }
 
+// SetBudget sets the budget of an account in billing-hours; a budget of
+// zero removes it
+func (c *Config) SetBudget(account string, hours float64) {
+	if hours <= 0 {
+		delete(c.Budgets, account)
+		return
+	}
+	if c.Budgets == nil {
+		c.Budgets = make(map[string]float64)
+	}
+	c.Budgets[account] = hours
+}
+
 // UpdateDefaults updates default job settings
 func (c *Config) UpdateDefaults(partition string, nodes int, cpus int, memory string, time string) {
 	if partition != "" {
@@ -235,6 +320,14 @@ func (c *Config) Print() {
 		fmt.Println()
 	}
 	
+	if len(c.Budgets) > 0 {
+		fmt.Println("Budgets (billing-hours):")
+		for account, hours := range c.Budgets {
+			fmt.Printf("  %-10s = %g\n", account, hours)
+		}
+		fmt.Println()
+	}
+	
 	fmt.Println("Output Settings:")
 	fmt.Printf("  Default Output Dir: %s\n", c.DefaultOutputDir)
 	fmt.Printf("  Job Name Template: %s\n", c.JobNameTemplate)
@@ -242,6 +335,23 @@ func (c *Config) Print() {
 	
 	fmt.Println("Advanced Settings:")
 	fmt.Printf("  Command Timeout: %d seconds\n", c.CommandTimeout)
+	fmt.Printf("  Submit Timeout: %d seconds\n", c.SubmitTimeout)
+	fmt.Printf("  Query Retries: %d\n", c.QueryRetries)
 	fmt.Printf("  Confirm Dangerous Operations: %t\n", c.ConfirmDangerous)
 	fmt.Printf("  Save Job History: %t\n", c.SaveJobHistory)
+	fmt.Printf("  Job Notifications: %t\n", c.NotifyJobs)
+	if c.NotifyJobs {
+		fmt.Printf("    All Jobs: %t, Interval: %d seconds, Bell: %t\n", c.NotifyAllJobs, c.NotifyInterval, c.NotifyBell)
+		if c.NotifyHook != "" {
+			fmt.Printf("    Hook: %s\n", c.NotifyHook)
+		}
+	}
+	fmt.Printf("  Backend: %s\n", c.Backend)
+	if c.FixtureFile != "" {
+		fmt.Printf("  Fixture File: %s\n", c.FixtureFile)
+	}
+	if c.Backend == BackendREST {
+		fmt.Printf("  REST URL: %s\n", c.RestURL)
+		fmt.Printf("  REST API Version: %s\n", c.RestVersion)
+	}
 }
\ No newline at end of file
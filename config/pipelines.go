@@ -0,0 +1,264 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"slsh/slurm"
+)
+
+// dependencyTypes are the sbatch dependency types a step can wait with
+var dependencyTypes = map[string]bool{
+	"afterok":    true,
+	"afterany":   true,
+	"afternotok": true,
+	"aftercorr":  true,
+}
+
+// Pipeline is a set of batch jobs that depend on each other, described in
+// a JSON file
+type Pipeline struct {
+	Name     string           `json:"name"`
+	Defaults slurm.JobOptions `json:"defaults"`
+	Steps    []PipelineStep   `json:"steps"`
+}
+
+// PipelineStep is one job of a pipeline. It runs a script file or a
+// command once the steps it comes after have finished.
+type PipelineStep struct {
+	Name       string           `json:"name"`
+	Script     string           `json:"script,omitempty"`
+	Command    string           `json:"command,omitempty"`
+	After      []string         `json:"after,omitempty"`
+	Dependency string           `json:"dependency,omitempty"`
+	Options    slurm.JobOptions `json:"options"`
+}
+
+// DependencyType returns how the step waits for the steps it comes after,
+// afterok unless the file says otherwise
+func (s *PipelineStep) DependencyType() string {
+	if s.Dependency == "" {
+		return "afterok"
+	}
+	return s.Dependency
+}
+
+// LoadPipeline reads and checks a pipeline file. Pipelines without a name
+// are named after the file.
+func LoadPipeline(path string) (*Pipeline, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pipeline: %v", err)
+	}
+
+	// Unknown fields are most likely misspelled ones
+	var p Pipeline
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&p); err != nil {
+		return nil, fmt.Errorf("failed to parse pipeline %s: %v", path, err)
+	}
+
+	if p.Name == "" {
+		p.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	if err := p.validate(); err != nil {
+		return nil, fmt.Errorf("invalid pipeline %s: %v", path, err)
+	}
+	return &p, nil
+}
+
+// validate checks the names, contents and dependencies of the steps
+func (p *Pipeline) validate() error {
+	if !namePattern.MatchString(p.Name) {
+		return fmt.Errorf("invalid name %q", p.Name)
+	}
+	if len(p.Steps) == 0 {
+		return fmt.Errorf("no steps")
+	}
+
+	names := make(map[string]bool)
+	for _, step := range p.Steps {
+		if !namePattern.MatchString(step.Name) {
+			return fmt.Errorf("invalid step name %q", step.Name)
+		}
+		if names[step.Name] {
+			return fmt.Errorf("step %s is defined twice", step.Name)
+		}
+		names[step.Name] = true
+
+		if (step.Script == "") == (step.Command == "") {
+			return fmt.Errorf("step %s needs either a script or a command", step.Name)
+		}
+		if step.Dependency != "" && !dependencyTypes[step.Dependency] {
+			return fmt.Errorf("step %s: unknown dependency type %q", step.Name, step.Dependency)
+		}
+	}
+
+	for _, step := range p.Steps {
+		for _, after := range step.After {
+			if !names[after] {
+				return fmt.Errorf("step %s comes after unknown step %s", step.Name, after)
+			}
+		}
+	}
+
+	_, err := p.Order()
+	return err
+}
+
+// Order returns the steps so that every step comes after the steps it
+// depends on. Steps that could run in either order keep the order of the
+// file.
+func (p *Pipeline) Order() ([]PipelineStep, error) {
+	done := make(map[string]bool)
+	var order []PipelineStep
+
+	for len(order) < len(p.Steps) {
+		progress := false
+		for _, step := range p.Steps {
+			if done[step.Name] || !allDone(step.After, done) {
+				continue
+			}
+			order = append(order, step)
+			done[step.Name] = true
+			progress = true
+		}
+
+		if !progress {
+			var waiting []string
+			for _, step := range p.Steps {
+				if !done[step.Name] {
+					waiting = append(waiting, step.Name)
+				}
+			}
+			return nil, fmt.Errorf("dependency cycle among steps %s", strings.Join(waiting, ", "))
+		}
+	}
+	return order, nil
+}
+
+// allDone reports whether all names are in done
+func allDone(names []string, done map[string]bool) bool {
+	for _, name := range names {
+		if !done[name] {
+			return false
+		}
+	}
+	return true
+}
+
+// PipelineRun records the jobs a pipeline was submitted as, so that its
+// progress can be followed later
+type PipelineRun struct {
+	Name      string        `json:"name"`
+	File      string        `json:"file"`
+	Submitted time.Time     `json:"submitted"`
+	Jobs      []PipelineJob `json:"jobs"`
+}
+
+// PipelineJob is the job a pipeline step was submitted as
+type PipelineJob struct {
+	Step       string   `json:"step"`
+	JobID      string   `json:"job_id"`
+	After      []string `json:"after,omitempty"`
+	Dependency string   `json:"dependency,omitempty"`
+}
+
+// GetPipelineDir returns the directory pipeline runs are recorded in
+func GetPipelineDir() string {
+	return filepath.Join(filepath.Dir(GetConfigPath()), "pipelines")
+}
+
+// SavePipelineRun records a pipeline run, replacing the previous run of
+// the pipeline
+func SavePipelineRun(run *PipelineRun) error {
+	if !namePattern.MatchString(run.Name) {
+		return fmt.Errorf("invalid pipeline name: %q", run.Name)
+	}
+
+	dir := GetPipelineDir()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create pipeline directory: %v", err)
+	}
+
+	data, err := json.MarshalIndent(run, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal pipeline run: %v", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, run.Name+".json"), data, 0644); err != nil {
+		return fmt.Errorf("failed to write pipeline run: %v", err)
+	}
+	return nil
+}
+
+// LoadPipelineRun reads the last run of the named pipeline, or the most
+// recently submitted pipeline when name is empty
+func LoadPipelineRun(name string) (*PipelineRun, error) {
+	if name != "" {
+		if !namePattern.MatchString(name) {
+			return nil, fmt.Errorf("invalid pipeline name: %q", name)
+		}
+		return readPipelineRun(filepath.Join(GetPipelineDir(), name+".json"))
+	}
+
+	runs, err := ListPipelineRuns()
+	if err != nil {
+		return nil, err
+	}
+	if len(runs) == 0 {
+		return nil, fmt.Errorf("no pipelines submitted yet")
+	}
+	return runs[0], nil
+}
+
+// ListPipelineRuns returns the recorded pipeline runs, latest first
+func ListPipelineRuns() ([]*PipelineRun, error) {
+	entries, err := os.ReadDir(GetPipelineDir())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pipeline directory: %v", err)
+	}
+
+	var runs []*PipelineRun
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		if run, err := readPipelineRun(filepath.Join(GetPipelineDir(), entry.Name())); err == nil {
+			runs = append(runs, run)
+		}
+	}
+
+	sort.Slice(runs, func(i, j int) bool {
+		return runs[i].Submitted.After(runs[j].Submitted)
+	})
+	return runs, nil
+}
+
+// readPipelineRun reads a pipeline run file
+func readPipelineRun(path string) (*PipelineRun, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		name := strings.TrimSuffix(filepath.Base(path), ".json")
+		return nil, fmt.Errorf("pipeline %s has not been submitted", name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pipeline run: %v", err)
+	}
+
+	var run PipelineRun
+	if err := json.Unmarshal(data, &run); err != nil {
+		return nil, fmt.Errorf("failed to parse pipeline run %s: %v", path, err)
+	}
+	return &run, nil
+}
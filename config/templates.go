@@ -0,0 +1,231 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"slsh/slurm"
+)
+
+// namePattern matches valid names of templates and pipelines, which are
+// also the names of their files
+var namePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// placeholderPattern matches {{param}} placeholders in template scripts
+// and option values
+var placeholderPattern = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_]*)\s*\}\}`)
+
+// Template is a named job shape: job options and an optional script body,
+// both of which may contain {{param}} placeholders
+type Template struct {
+	Name        string            `json:"name"`
+	Description string            `json:"description,omitempty"`
+	Options     slurm.JobOptions  `json:"options"`
+	Script      string            `json:"script,omitempty"`
+	Defaults    map[string]string `json:"defaults,omitempty"`
+	Created     time.Time         `json:"created"`
+}
+
+// GetTemplateDir returns the directory templates are stored in, next to
+// the configuration file
+func GetTemplateDir() string {
+	return filepath.Join(filepath.Dir(GetConfigPath()), "templates")
+}
+
+// templatePath returns the file of a template, rejecting names that are
+// not valid file names
+func templatePath(name string) (string, error) {
+	if !namePattern.MatchString(name) {
+		return "", fmt.Errorf("invalid template name: %q", name)
+	}
+	return filepath.Join(GetTemplateDir(), name+".json"), nil
+}
+
+// LoadTemplate reads the template with the given name
+func LoadTemplate(name string) (*Template, error) {
+	path, err := templatePath(name)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("template not found: %s", name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read template: %v", err)
+	}
+
+	var t Template
+	if err := json.Unmarshal(data, &t); err != nil {
+		return nil, fmt.Errorf("failed to parse template %s: %v", name, err)
+	}
+	t.Name = name
+	return &t, nil
+}
+
+// SaveTemplate writes a template, replacing any template of the same name
+func SaveTemplate(t *Template) error {
+	path, err := templatePath(t.Name)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create template directory: %v", err)
+	}
+
+	data, err := json.MarshalIndent(t, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal template: %v", err)
+	}
+
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write template: %v", err)
+	}
+	return nil
+}
+
+// DeleteTemplate removes the template with the given name
+func DeleteTemplate(name string) error {
+	path, err := templatePath(name)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(path); os.IsNotExist(err) {
+		return fmt.Errorf("template not found: %s", name)
+	} else if err != nil {
+		return fmt.Errorf("failed to delete template: %v", err)
+	}
+	return nil
+}
+
+// ListTemplates returns all stored templates sorted by name. Files that
+// cannot be read are skipped.
+func ListTemplates() ([]*Template, error) {
+	entries, err := os.ReadDir(GetTemplateDir())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read template directory: %v", err)
+	}
+
+	var templates []*Template
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), ".json")
+		if !ok || entry.IsDir() {
+			continue
+		}
+		if t, err := LoadTemplate(name); err == nil {
+			templates = append(templates, t)
+		}
+	}
+
+	sort.Slice(templates, func(i, j int) bool {
+		return templates[i].Name < templates[j].Name
+	})
+	return templates, nil
+}
+
+// optionFields returns the string fields of job options, which in a template
+// may contain placeholders like the script
+func optionFields(opts *slurm.JobOptions) []*string {
+	fields := []*string{
+		&opts.Name, &opts.Partition, &opts.Memory, &opts.Time, &opts.QoS,
+		&opts.Account, &opts.Output, &opts.Error, &opts.WorkDir,
+		&opts.NodeList, &opts.Exclude, &opts.Array, &opts.Dependency,
+	}
+	for i := range opts.ExtraArgs {
+		fields = append(fields, &opts.ExtraArgs[i])
+	}
+	return fields
+}
+
+// Parameters returns the names of the template's placeholders in the
+// order they first appear
+func (t *Template) Parameters() []string {
+	texts := []string{t.Script}
+	for _, field := range optionFields(&t.Options) {
+		texts = append(texts, *field)
+	}
+	for _, key := range slices.Sorted(maps.Keys(t.Options.Environment)) {
+		texts = append(texts, t.Options.Environment[key])
+	}
+
+	var names []string
+	seen := make(map[string]bool)
+	for _, text := range texts {
+		for _, m := range placeholderPattern.FindAllStringSubmatch(text, -1) {
+			if !seen[m[1]] {
+				seen[m[1]] = true
+				names = append(names, m[1])
+			}
+		}
+	}
+	return names
+}
+
+// Render fills in the placeholders of the template with values, falling
+// back to the template's defaults. It returns the job options and script
+// body with every placeholder replaced.
+func (t *Template) Render(values map[string]string) (*slurm.JobOptions, string, error) {
+	params := make(map[string]bool)
+	for _, name := range t.Parameters() {
+		params[name] = true
+	}
+
+	var unknown []string
+	for name := range values {
+		if !params[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return nil, "", fmt.Errorf("template %s has no parameter %s", t.Name, strings.Join(unknown, ", "))
+	}
+
+	var missing []string
+	for _, name := range t.Parameters() {
+		_, set := values[name]
+		_, hasDefault := t.Defaults[name]
+		if !set && !hasDefault {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, "", fmt.Errorf("template %s needs values for %s (use --set name=value)", t.Name, strings.Join(missing, ", "))
+	}
+
+	fill := func(text string) string {
+		return placeholderPattern.ReplaceAllStringFunc(text, func(placeholder string) string {
+			name := placeholderPattern.FindStringSubmatch(placeholder)[1]
+			if value, ok := values[name]; ok {
+				return value
+			}
+			return t.Defaults[name]
+		})
+	}
+
+	opts := t.Options
+	opts.ExtraArgs = append([]string(nil), t.Options.ExtraArgs...)
+	opts.Environment = make(map[string]string, len(t.Options.Environment))
+	for key, value := range t.Options.Environment {
+		opts.Environment[key] = fill(value)
+	}
+	for _, field := range optionFields(&opts) {
+		*field = fill(*field)
+	}
+
+	return &opts, fill(t.Script), nil
+}
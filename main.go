@@ -3,26 +3,13 @@ package main
 import (
 	"fmt"
 	"os"
-	"os/signal"
-	"syscall"
 
 	"slsh/shell"
 )
 
 func main() {
-	// Handle graceful shutdown
-	c := make(chan os.Signal, 1)
-	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
-
-	// Create and configure shell
+	// Create and configure shell. The shell handles Ctrl+C and SIGTERM itself.
 	sh := shell.New()
-	
-	// Handle Ctrl+C gracefully
-	go func() {
-		<-c
-		fmt.Println("\nGoodbye!")
-		os.Exit(0)
-	}()
 
 	// Start the shell
 	if err := sh.Run(); err != nil {
@@ -5,7 +5,9 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -15,10 +17,18 @@ type HistoryEntry struct {
 	Timestamp time.Time `json:"timestamp"`
 	Success   bool      `json:"success"`
 	Duration  time.Duration `json:"duration"`
+	
+	// JobIDs are the jobs the command submitted
+	JobIDs []string `json:"job_ids,omitempty"`
 }
 
+// historyJobsPrefix marks the field of a history line that lists the jobs
+// the command submitted. Lines without it are from older versions.
+const historyJobsPrefix = "jobs:"
+
 // History manages command history
 type History struct {
+	mu       sync.Mutex
 	entries  []HistoryEntry
 	maxSize  int
 	filePath string
@@ -52,6 +62,9 @@ func (h *History) Add(command string, success bool, duration time.Duration) {
 		return
 	}
 	
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	
 	// Skip if same as last command
 	if len(h.entries) > 0 && h.entries[len(h.entries)-1].Command == entry.Command {
 		return
@@ -65,6 +78,54 @@ func (h *History) Add(command string, success bool, duration time.Duration) {
 	}
 }
 
+// AddWithJobs adds a command that submitted the given jobs to history.
+// Unlike Add it keeps a command that repeats the last one, so that each
+// job is found with the run of the command that submitted it.
+func (h *History) AddWithJobs(command string, success bool, duration time.Duration, jobIDs ...string) {
+	if len(jobIDs) == 0 {
+		h.Add(command, success, duration)
+		return
+	}
+	
+	entry := HistoryEntry{
+		Command:   strings.TrimSpace(command),
+		Timestamp: time.Now(),
+		Success:   success,
+		Duration:  duration,
+	}
+	for _, jobID := range jobIDs {
+		if !slices.Contains(entry.JobIDs, jobID) {
+			entry.JobIDs = append(entry.JobIDs, jobID)
+		}
+	}
+	
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	
+	h.entries = append(h.entries, entry)
+	if len(h.entries) > h.maxSize {
+		h.entries = h.entries[len(h.entries)-h.maxSize:]
+	}
+}
+
+// FindJob returns the 1-based index and the command line of the latest
+// entry that submitted a job. Array tasks such as "1005_3" are found by
+// the array job that was submitted.
+func (h *History) FindJob(jobID string) (int, string, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	
+	arrayJobID, _, _ := strings.Cut(jobID, "_")
+	for i := len(h.entries) - 1; i >= 0; i-- {
+		for _, id := range h.entries[i].JobIDs {
+			if id == jobID || id == arrayJobID {
+				return i + 1, h.entries[i].Command, true
+			}
+		}
+	}
+	return 0, "", false
+}
+
 // GetAll returns all history entries
 func (h *History) GetAll() []HistoryEntry {
 	return h.entries
@@ -114,6 +175,9 @@ func (h *History) Clear() {
 
 // Save saves history to file
 func (h *History) Save() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	
 	file, err := os.Create(h.filePath)
 	if err != nil {
 		return fmt.Errorf("failed to create history file: %v", err)
@@ -124,12 +188,16 @@ func (h *History) Save() error {
 	defer writer.Flush()
 
 	for _, entry := range h.entries {
-		// Format: timestamp|success|duration|command
+		// Format: timestamp|success|duration|[jobs:id,...|]command
+		command := entry.Command
+		if len(entry.JobIDs) > 0 {
+			command = historyJobsPrefix + strings.Join(entry.JobIDs, ",") + "|" + command
+		}
 		line := fmt.Sprintf("%d|%t|%d|%s\n", 
 			entry.Timestamp.Unix(), 
 			entry.Success, 
 			entry.Duration.Nanoseconds(),
-			entry.Command)
+			command)
 		
 		if _, err := writer.WriteString(line); err != nil {
 			return fmt.Errorf("failed to write history entry: %v", err)
@@ -209,8 +277,14 @@ func parseHistoryLine(line string) (HistoryEntry, error) {
 	}
 	entry.Duration = time.Duration(duration)
 
-	// Command is the rest
+	// Command is the rest, after the jobs it submitted if any
 	entry.Command = parts[3]
+	if jobs, ok := strings.CutPrefix(entry.Command, historyJobsPrefix); ok {
+		if ids, command, found := strings.Cut(jobs, "|"); found {
+			entry.JobIDs = strings.Split(ids, ",")
+			entry.Command = command
+		}
+	}
 
 	return entry, nil
 }
@@ -248,6 +322,11 @@ func (h *History) PrintHistory(showTimestamp bool, showDuration bool) {
 			parts = append(parts, timeStr)
 		}
 		parts = append(parts, index, prefix, entry.Command)
+		if len(entry.JobIDs) == 1 {
+			parts = append(parts, "[job "+entry.JobIDs[0]+"]")
+		} else if len(entry.JobIDs) > 1 {
+			parts = append(parts, "[jobs "+strings.Join(entry.JobIDs, ", ")+"]")
+		}
 		if showDuration {
 			parts = append(parts, durationStr)
 		}
@@ -0,0 +1,142 @@
+package shell
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"slsh/slurm"
+)
+
+// jobRefPattern matches job references: %last, %-N or %name
+var jobRefPattern = regexp.MustCompile(`^%(last|-\d+|[A-Za-z_][A-Za-z0-9_.*?\[\]-]*)$`)
+
+// jobIDOptions are the options whose values are job IDs
+var jobIDOptions = []string{"-j", "--jobs", "--jobid"}
+
+// SessionJob is a job submitted from the shell
+type SessionJob struct {
+	ID        string
+	Name      string
+	Submitted time.Time
+}
+
+// SessionJobs remembers the jobs submitted in this session, so commands
+// can refer to them as %last, %-2 or %name
+type SessionJobs struct {
+	mu   sync.Mutex
+	jobs []SessionJob
+}
+
+// NewSessionJobs creates an empty list of session jobs
+func NewSessionJobs() *SessionJobs {
+	return &SessionJobs{}
+}
+
+// Add records a submitted job. Jobs reported twice, as srun does when it
+// waits for resources, are recorded once.
+func (s *SessionJobs) Add(jobID, name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, job := range s.jobs {
+		if job.ID == jobID {
+			return
+		}
+	}
+	s.jobs = append(s.jobs, SessionJob{ID: jobID, Name: name, Submitted: time.Now()})
+}
+
+// List returns the session jobs, oldest first
+func (s *SessionJobs) List() []SessionJob {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]SessionJob(nil), s.jobs...)
+}
+
+// Resolve returns the job ID a reference stands for. %last and %-1 are
+// the latest job, %-2 the one before; %name is the latest job with that
+// name, which may be a glob pattern. ok is false for words that are not
+// references.
+func (s *SessionJobs) Resolve(ref string) (jobID string, ok bool, err error) {
+	m := jobRefPattern.FindStringSubmatch(ref)
+	if m == nil {
+		return "", false, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := m[1]
+	if key == "last" {
+		key = "-1"
+	}
+
+	if strings.HasPrefix(key, "-") {
+		back, _ := strconv.Atoi(key[1:])
+		switch {
+		case back == 0:
+			return "", true, fmt.Errorf("invalid job reference %s", ref)
+		case len(s.jobs) == 0:
+			return "", true, fmt.Errorf("%s: no jobs submitted in this session", ref)
+		case back > len(s.jobs):
+			return "", true, fmt.Errorf("%s: only %d jobs submitted in this session", ref, len(s.jobs))
+		}
+		return s.jobs[len(s.jobs)-back].ID, true, nil
+	}
+
+	for i := len(s.jobs) - 1; i >= 0; i-- {
+		if matched, _ := path.Match(key, s.jobs[i].Name); matched {
+			return s.jobs[i].ID, true, nil
+		}
+	}
+	return "", false, nil
+}
+
+// ResolveCommand replaces job references in the arguments of cmd and in
+// the values of job ID options. Lists such as "%-2,%last" are resolved
+// item by item. References to names no session job has are left as they
+// are, since words such as "%s" may mean something else to the command.
+func (s *SessionJobs) ResolveCommand(cmd *slurm.Command) error {
+	for i, arg := range cmd.Args {
+		resolved, err := s.resolveList(arg)
+		if err != nil {
+			return err
+		}
+		cmd.Args[i] = resolved
+	}
+
+	for _, name := range jobIDOptions {
+		if value, ok := cmd.Options[name]; ok {
+			resolved, err := s.resolveList(value)
+			if err != nil {
+				return err
+			}
+			cmd.Options[name] = resolved
+		}
+	}
+	return nil
+}
+
+// resolveList resolves the references in a comma separated word
+func (s *SessionJobs) resolveList(word string) (string, error) {
+	if !strings.Contains(word, "%") {
+		return word, nil
+	}
+
+	items := strings.Split(word, ",")
+	for i, item := range items {
+		jobID, ok, err := s.Resolve(item)
+		if err != nil {
+			return "", err
+		}
+		if ok {
+			items[i] = jobID
+		}
+	}
+	return strings.Join(items, ","), nil
+}
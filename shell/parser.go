@@ -8,8 +8,27 @@ import (
 	"slsh/slurm"
 )
 
-// ParseCommand parses a command line into a Command struct
-func ParseCommand(line string) (*slurm.Command, error) {
+// FlagLookup returns the options of a command that never take a value,
+// so that the word after them is an argument. Commands without such
+// options, including those passed on to Slurm, get nil.
+type FlagLookup func(name string) map[string]bool
+
+// timeLimitCommands are the commands whose -t option is a time limit
+var timeLimitCommands = map[string]bool{
+	"run":      true,
+	"submit":   true,
+	"srun":     true,
+	"sbatch":   true,
+	"salloc":   true,
+	"template": true,
+	"array":    true,
+	"alloc":    true,
+}
+
+// ParseCommand parses a command line into a Command struct. flags tells
+// which options of the command take no value; with nil, every option
+// followed by a word takes it as its value.
+func ParseCommand(line string, flags FlagLookup) (*slurm.Command, error) {
 	line = strings.TrimSpace(line)
 	if line == "" {
 		return nil, fmt.Errorf("empty command")
@@ -30,19 +49,32 @@ func ParseCommand(line string) (*slurm.Command, error) {
 		Options: make(map[string]string),
 	}
 
+	var flagOptions map[string]bool
+	if flags != nil {
+		flagOptions = flags(cmd.Name)
+	}
+
 	// Parse tokens into args and options
 	for i := 1; i < len(tokens); i++ {
 		token := tokens[i]
 
+		// "--" ends the options; the rest of the line is a command with
+		// options of its own
+		if token == "--" {
+			cmd.EndOfOptions = true
+			cmd.Args = append(cmd.Args, tokens[i+1:]...)
+			break
+		}
+
 		if strings.HasPrefix(token, "-") {
 			// This is an option
-			if i+1 < len(tokens) && !strings.HasPrefix(tokens[i+1], "-") {
+			if i+1 < len(tokens) && !strings.HasPrefix(tokens[i+1], "-") && !flagOptions[token] {
 				// Option has a value
-				cmd.Options[token] = tokens[i+1]
+				cmd.SetOption(token, tokens[i+1])
 				i++ // Skip the next token as it's the value
 			} else {
 				// Option is a flag (no value)
-				cmd.Options[token] = ""
+				cmd.SetOption(token, "")
 			}
 		} else {
 			// This is an argument
@@ -139,6 +171,14 @@ func ParseJobOptions(options map[string]string) *slurm.JobOptions {
 			jobOpts.Error = value
 		case "-D", "--chdir":
 			jobOpts.WorkDir = value
+		case "-w", "--nodelist":
+			jobOpts.NodeList = value
+		case "-x", "--exclude":
+			jobOpts.Exclude = value
+		case "-l", "--label":
+			jobOpts.Label = true
+		case "--pty":
+			jobOpts.PTY = true
 		default:
 			// Store unknown options as extra args
 			if value != "" {
@@ -178,8 +218,8 @@ func ValidateCommand(cmd *slurm.Command) error {
 		}
 	}
 
-	// Validate time format if specified
-	if timeLimit, exists := cmd.Options["-t"]; exists && timeLimit != "" {
+	// Validate time format if specified. Query commands use -t for states.
+	if timeLimit, exists := cmd.Options["-t"]; exists && timeLimit != "" && timeLimitCommands[cmd.Name] {
 		if !isValidTimeFormat(timeLimit) {
 			return fmt.Errorf("invalid time format: %s (use format: HH:MM:SS or minutes)", timeLimit)
 		}
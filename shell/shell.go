@@ -3,8 +3,11 @@ package shell
 import (
 	"bufio"
 	"fmt"
+	"io"
 	"os"
+	"os/signal"
 	"strings"
+	"syscall"
 	"time"
 
 	"slsh/commands"
@@ -20,23 +23,84 @@ type Shell struct {
 	client   *slurm.Client
 	commands *commands.Registry
 	prompt   *utils.Prompt
+	tracker  *JobTracker
+	session  *SessionJobs
+	input    *bufio.Scanner
 	running  bool
+	
+	// allocation is the salloc allocation job steps run in, if any
+	allocation *slurm.Allocation
 }
 
 // New creates a new shell instance
 func New() *Shell {
-	cfg := config.Load()
-	
+	return NewWithConfig(config.Load())
+}
+
+// NewWithConfig creates a new shell instance using the given configuration
+func NewWithConfig(cfg *config.Config) *Shell {
+	client := newClient(cfg)
 	return &Shell{
 		config:   cfg,
 		history:  NewHistory(cfg.HistorySize),
-		client:   slurm.NewClient(),
+		client:   client,
 		commands: commands.NewRegistry(),
 		prompt:   utils.NewPrompt(cfg.Prompt),
+		tracker:  NewJobTracker(client, cfg),
+		session:  NewSessionJobs(),
+		input:    bufio.NewScanner(os.Stdin),
 		running:  false,
 	}
 }
 
+// newClient creates the Slurm client for the configured backend and
+// applies the configured timeouts and retries
+func newClient(cfg *config.Config) *slurm.Client {
+	client := newBackendClient(cfg)
+	
+	// Queries honor the configured timeout and may be retried; srun has
+	// no timeout and sbatch a short one
+	client.SetTimeout(time.Duration(cfg.CommandTimeout) * time.Second)
+	client.SetPolicy(slurm.OpRead, slurm.Policy{
+		Timeout: time.Duration(cfg.CommandTimeout) * time.Second,
+		Retries: cfg.QueryRetries,
+	})
+	client.SetPolicy(slurm.OpSubmit, slurm.Policy{
+		Timeout: time.Duration(cfg.SubmitTimeout) * time.Second,
+	})
+	
+	return client
+}
+
+// newBackendClient creates the client for the configured backend
+func newBackendClient(cfg *config.Config) *slurm.Client {
+	switch cfg.Backend {
+	case config.BackendFake:
+		runner, err := slurm.NewFakeRunnerFromFile(cfg.FixtureFile)
+		if err != nil {
+			fmt.Printf("Warning: Failed to load fixtures, using built-in fake cluster: %v\n", err)
+			runner = slurm.NewFakeRunner(slurm.DefaultFixtures())
+		}
+		return slurm.NewClientWithRunner(runner)
+	case config.BackendRecord:
+		runner, err := slurm.NewRecordingRunner(slurm.NewExecRunner(), cfg.FixtureFile)
+		if err != nil {
+			fmt.Printf("Warning: Record mode disabled: %v\n", err)
+			return slurm.NewClient()
+		}
+		return slurm.NewClientWithRunner(runner)
+	case config.BackendREST:
+		if cfg.RestURL == "" {
+			fmt.Println("Warning: rest backend needs rest_url, falling back to the Slurm CLI")
+			return slurm.NewClient()
+		}
+		rest := slurm.NewRESTClient(cfg.RestURL, cfg.RestVersion, cfg.RestUser, cfg.GetRestToken())
+		return slurm.NewClientWithREST(rest)
+	default:
+		return slurm.NewClient()
+	}
+}
+
 // Run starts the main shell loop
 func (s *Shell) Run() error {
 	// Load history
@@ -48,14 +112,28 @@ func (s *Shell) Run() error {
 	s.showWelcome()
 
 	// Register built-in commands
-	s.registerBuiltinCommands()
+	s.ensureCommands()
+
+	// Ctrl+C interrupts the running command instead of the shell
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+	go s.handleSignals(signals)
+
+	// Watch jobs started in this session
+	if s.config.NotifyJobs {
+		s.tracker.Start(time.Duration(s.config.NotifyInterval) * time.Second)
+		defer s.tracker.Stop()
+	}
 
 	// Main REPL loop
 	s.running = true
-	scanner := bufio.NewScanner(os.Stdin)
+	scanner := s.input
 	
 	for s.running {
-		// Show prompt
+		// Report job state changes, then show prompt
+		s.tracker.Print()
+		s.checkAllocation()
 		s.prompt.Show()
 		
 		// Read input
@@ -72,12 +150,60 @@ func (s *Shell) Run() error {
 		s.executeCommand(line)
 	}
 	
-	// Save history before exit
+	// Give back resources nobody is going to use, then save history
+	s.releaseAllocation()
+	s.saveHistory()
+	
+	return scanner.Err()
+}
+
+// doubleInterruptWindow is how soon a second Ctrl+C must follow the first
+// to exit the shell
+const doubleInterruptWindow = 2 * time.Second
+
+// handleSignals cancels the running command on Ctrl+C. A second Ctrl+C
+// within doubleInterruptWindow, or SIGTERM, saves history and exits.
+func (s *Shell) handleSignals(signals <-chan os.Signal) {
+	var last time.Time
+	
+	for sig := range signals {
+		if sig != os.Interrupt {
+			s.exit()
+		}
+		
+		// Interactive commands get Ctrl+C from the terminal themselves
+		if s.client.InInteractive() {
+			continue
+		}
+		
+		if time.Since(last) < doubleInterruptWindow {
+			s.exit()
+		}
+		last = time.Now()
+		
+		if s.client.Interrupt() {
+			fmt.Println("\nInterrupting... (press Ctrl+C again to exit)")
+		} else {
+			fmt.Println("\n(press Ctrl+C again to exit)")
+			s.prompt.Show()
+		}
+	}
+}
+
+// exit saves history and terminates the process
+func (s *Shell) exit() {
+	s.client.Interrupt()
+	fmt.Println("\nGoodbye!")
+	s.releaseAllocation()
+	s.saveHistory()
+	os.Exit(0)
+}
+
+// saveHistory writes history to disk, warning on failure
+func (s *Shell) saveHistory() {
 	if err := s.history.Save(); err != nil {
 		fmt.Printf("Warning: Failed to save history: %v\n", err)
 	}
-	
-	return scanner.Err()
 }
 
 // executeCommand executes a single command
@@ -86,7 +212,7 @@ func (s *Shell) executeCommand(line string) {
 	success := true
 	
 	// Parse command
-	cmd, err := ParseCommand(line)
+	cmd, err := s.ParseCommand(line)
 	if err != nil {
 		fmt.Printf("Error parsing command: %v\n", err)
 		success = false
@@ -105,7 +231,7 @@ func (s *Shell) executeCommand(line string) {
 	// Check for aliases
 	if alias, exists := s.config.Aliases[cmd.Name]; exists {
 		// Replace command with alias
-		aliasCmd, err := ParseCommand(alias + " " + strings.Join(cmd.Args, " "))
+		aliasCmd, err := s.ParseCommand(alias + " " + strings.Join(cmd.Args, " "))
 		if err != nil {
 			fmt.Printf("Error parsing alias: %v\n", err)
 			success = false
@@ -115,16 +241,35 @@ func (s *Shell) executeCommand(line string) {
 		cmd = aliasCmd
 	}
 	
+	// Replace references to session jobs such as %last
+	if err := s.session.ResolveCommand(cmd); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		s.history.Add(line, false, time.Since(startTime))
+		return
+	}
+	
 	// Execute command
+	submitted := len(s.session.List())
 	err = s.commands.Execute(cmd, s)
 	if err != nil {
 		fmt.Printf("Error: %v\n", err)
 		success = false
 	}
 	
-	// Add to history
+	// Add to history, with the jobs the command submitted
 	duration := time.Since(startTime)
-	s.history.Add(line, success, duration)
+	var jobIDs []string
+	for _, job := range s.session.List()[submitted:] {
+		jobIDs = append(jobIDs, job.ID)
+	}
+	s.history.AddWithJobs(line, success, duration, jobIDs...)
+}
+
+// ensureCommands registers the built-in commands once
+func (s *Shell) ensureCommands() {
+	if _, exists := s.commands.GetCommand("help"); !exists {
+		s.registerBuiltinCommands()
+	}
 }
 
 // registerBuiltinCommands registers all built-in commands
@@ -132,16 +277,26 @@ func (s *Shell) registerBuiltinCommands() {
 	// Job execution commands
 	s.commands.Register("run", commands.NewRunCommand(s.client, s.config))
 	s.commands.Register("submit", commands.NewSubmitCommand(s.client, s.config))
+	s.commands.Register("template", commands.NewTemplateCommand(s.client, s.config))
+	s.commands.Register("pipeline", commands.NewPipelineCommand(s.client, s.config))
+	s.commands.Register("logs", commands.NewLogsCommand(s.client, s.config))
+	s.commands.Register("alloc", commands.NewAllocCommand(s.client, s.config))
+	s.commands.Register("release", commands.NewReleaseCommand(s.client, s.config))
 	
 	// Job management commands
-	s.commands.Register("status", commands.NewStatusCommand(s.client))
-	s.commands.Register("cancel", commands.NewCancelCommand(s.client))
-	s.commands.Register("queue", commands.NewQueueCommand(s.client))
-	s.commands.Register("jobs", commands.NewJobsCommand(s.client))
+	s.commands.Register("status", commands.NewStatusCommand(s.client, s.config))
+	s.commands.Register("cancel", commands.NewCancelCommand(s.client, s.config))
+	s.commands.Register("array", commands.NewArrayCommand(s.client, s.config))
+	s.commands.Register("queue", commands.NewQueueCommand(s.client, s.config))
+	s.commands.Register("jobs", commands.NewJobsCommand(s.client, s.config))
+	s.commands.Register("watch", commands.NewWatchCommand(s.commands, s.client, s.config))
+	s.commands.Register("eff", commands.NewEffCommand(s.client, s.config))
+	s.commands.Register("jhist", commands.NewJhistCommand(s.client, s.config, s.history))
+	s.commands.Register("usage", commands.NewUsageCommand(s.client, s.config))
 	
 	// Node information commands
-	s.commands.Register("nodes", commands.NewNodesCommand(s.client))
-	s.commands.Register("partitions", commands.NewPartitionsCommand(s.client))
+	s.commands.Register("nodes", commands.NewNodesCommand(s.client, s.config))
+	s.commands.Register("partitions", commands.NewPartitionsCommand(s.client, s.config))
 	
 	// Shell management commands
 	s.commands.Register("history", commands.NewHistoryCommand(s.history))
@@ -152,9 +307,9 @@ func (s *Shell) registerBuiltinCommands() {
 	s.commands.Register("quit", commands.NewExitCommand(s))
 	
 	// Shortcuts
-	s.commands.Register("q", commands.NewQueueCommand(s.client))
-	s.commands.Register("j", commands.NewJobsCommand(s.client))
-	s.commands.Register("n", commands.NewNodesCommand(s.client))
+	s.commands.Register("q", commands.NewQueueCommand(s.client, s.config))
+	s.commands.Register("j", commands.NewJobsCommand(s.client, s.config))
+	s.commands.Register("n", commands.NewNodesCommand(s.client, s.config))
 	s.commands.Register("h", commands.NewHelpCommand(s.commands))
 }
 
@@ -191,6 +346,86 @@ func (s *Shell) GetHistory() *History {
 	return s.history
 }
 
+// TrackJob records a job submitted from the shell, so it can be referred
+// to later, and reports its state changes before later prompts
+func (s *Shell) TrackJob(jobID, name string) {
+	s.session.Add(jobID, name)
+	if s.config.NotifyJobs {
+		s.tracker.Track(jobID, name)
+	}
+}
+
+// GetSessionJobs returns the jobs submitted in this session
+func (s *Shell) GetSessionJobs() *SessionJobs {
+	return s.session
+}
+
+// GetTracker returns the job tracker of the shell
+func (s *Shell) GetTracker() *JobTracker {
+	return s.tracker
+}
+
+// SetInput replaces standard input as the source of command lines and
+// confirmation answers
+func (s *Shell) SetInput(r io.Reader) {
+	s.input = bufio.NewScanner(r)
+}
+
+// Confirm asks a yes/no question on the shell's input. Anything but "y"
+// or "yes", including end of input, is a no.
+func (s *Shell) Confirm(question string) bool {
+	fmt.Printf("%s [y/N] ", question)
+	if !s.input.Scan() {
+		fmt.Println()
+		return false
+	}
+	
+	answer := strings.ToLower(strings.TrimSpace(s.input.Text()))
+	return answer == "y" || answer == "yes"
+}
+
+// Allocation returns the allocation the shell is inside of, or nil
+func (s *Shell) Allocation() *slurm.Allocation {
+	return s.allocation
+}
+
+// SetAllocation enters an allocation, or leaves it when alloc is nil. The
+// prompt shows the allocation while the shell is inside it.
+func (s *Shell) SetAllocation(alloc *slurm.Allocation) {
+	s.allocation = alloc
+	if alloc == nil {
+		s.prompt.SetAllocation("", time.Time{})
+	} else {
+		s.prompt.SetAllocation(alloc.JobID, alloc.End)
+	}
+}
+
+// checkAllocation leaves an allocation that has reached its time limit,
+// since Slurm has ended it
+func (s *Shell) checkAllocation() {
+	if s.allocation == nil {
+		return
+	}
+	if remaining, limited := s.allocation.Remaining(); limited && remaining == 0 {
+		fmt.Printf("Allocation %s has reached its time limit\n", s.allocation.JobID)
+		s.SetAllocation(nil)
+	}
+}
+
+// releaseAllocation cancels the allocation the shell is inside of, so
+// that its resources are not held until the time limit
+func (s *Shell) releaseAllocation() {
+	if s.allocation == nil {
+		return
+	}
+	if _, err := s.client.CancelJob(s.allocation.JobID); err != nil {
+		fmt.Printf("Warning: Failed to release allocation %s: %v\n", s.allocation.JobID, err)
+	} else {
+		fmt.Printf("Released allocation %s\n", s.allocation.JobID)
+	}
+	s.SetAllocation(nil)
+}
+
 // GetClient returns the Slurm client
 func (s *Shell) GetClient() *slurm.Client {
 	return s.client
@@ -201,9 +436,18 @@ func (s *Shell) UpdatePrompt(newPrompt string) {
 	s.prompt.SetPrompt(newPrompt)
 }
 
+// ParseCommand parses a command line, knowing which options of the
+// shell's commands take no value
+func (s *Shell) ParseCommand(line string) (*slurm.Command, error) {
+	s.ensureCommands()
+	return ParseCommand(line, s.commands.Flags)
+}
+
 // ExecuteDirectCommand executes a command directly (for testing or API use)
 func (s *Shell) ExecuteDirectCommand(command string) error {
-	cmd, err := ParseCommand(command)
+	s.ensureCommands()
+	
+	cmd, err := s.ParseCommand(command)
 	if err != nil {
 		return fmt.Errorf("failed to parse command: %v", err)
 	}
@@ -212,6 +456,10 @@ func (s *Shell) ExecuteDirectCommand(command string) error {
 		return fmt.Errorf("invalid command: %v", err)
 	}
 	
+	if err := s.session.ResolveCommand(cmd); err != nil {
+		return err
+	}
+	
 	return s.commands.Execute(cmd, s)
 }
 
@@ -0,0 +1,358 @@
+package shell
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"slsh/config"
+	"slsh/slurm"
+	"slsh/utils"
+)
+
+// hookTimeout limits how long a notification hook may run
+const hookTimeout = 30 * time.Second
+
+// Notification reports that a tracked job changed state
+type Notification struct {
+	JobID    string
+	Name     string
+	State    string
+	Reason   string
+	NodeList string
+	ExitCode int
+	Elapsed  time.Duration
+	Finished bool
+}
+
+// String formats the notification, as in
+// "job 12345 (train) COMPLETED in 1h02m, exit 0"
+func (n Notification) String() string {
+	job := "job " + n.JobID
+	if n.Name != "" {
+		job += " (" + n.Name + ")"
+	}
+
+	switch {
+	case !n.Finished:
+		switch {
+		case n.State == slurm.JobStateRunning && n.NodeList != "":
+			return fmt.Sprintf("%s RUNNING on %s", job, n.NodeList)
+		case n.Reason != "" && n.Reason != "None":
+			return fmt.Sprintf("%s %s (%s)", job, n.State, n.Reason)
+		}
+		return fmt.Sprintf("%s %s", job, n.State)
+	case n.State == "":
+		return job + " left the queue; its final state is unknown"
+	case n.State == slurm.JobStateCompleted || n.State == slurm.JobStateFailed:
+		return fmt.Sprintf("%s %s in %s, exit %d", job, n.State, formatElapsed(n.Elapsed), n.ExitCode)
+	case n.State == slurm.JobStateCancelled || n.State == "PREEMPTED":
+		return fmt.Sprintf("%s %s after %s", job, n.State, formatElapsed(n.Elapsed))
+	}
+	return fmt.Sprintf("%s FAILED: %s after %s", job, n.State, formatElapsed(n.Elapsed))
+}
+
+// formatElapsed formats a run time compactly, as in "45s", "5m03s" or "1h02m"
+func formatElapsed(d time.Duration) string {
+	d = d.Round(time.Second)
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm%02ds", int(d.Minutes()), int(d.Seconds())%60)
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh%02dm", int(d.Hours()), int(d.Minutes())%60)
+	}
+	return fmt.Sprintf("%dd%02dh", int(d.Hours())/24, int(d.Hours())%24)
+}
+
+// trackedJob is a job watched by the tracker
+type trackedJob struct {
+	name  string
+	state string
+}
+
+// JobTracker polls the jobs started in this session, and optionally all
+// of the user's jobs, and collects a notification for every state change
+type JobTracker struct {
+	client *slurm.Client
+	config *config.Config
+	user   string
+
+	mu            sync.Mutex
+	jobs          map[string]*trackedJob
+	notifications []Notification
+	stop          chan struct{}
+}
+
+// NewJobTracker creates a tracker. It polls through its own clone of
+// client, so that Ctrl+C never cancels a background poll.
+func NewJobTracker(client *slurm.Client, cfg *config.Config) *JobTracker {
+	return &JobTracker{
+		client: client.Clone(),
+		config: cfg,
+		user:   os.Getenv("USER"),
+		jobs:   make(map[string]*trackedJob),
+	}
+}
+
+// Track starts watching a job
+func (t *JobTracker) Track(jobID, name string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if _, exists := t.jobs[jobID]; !exists {
+		t.jobs[jobID] = &trackedJob{name: name}
+	}
+}
+
+// Tracked returns the IDs of the jobs being watched
+func (t *JobTracker) Tracked() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	ids := make([]string, 0, len(t.jobs))
+	for id := range t.jobs {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Start polls in the background every interval until Stop is called
+func (t *JobTracker) Start(interval time.Duration) {
+	t.mu.Lock()
+	if t.stop != nil {
+		t.mu.Unlock()
+		return
+	}
+	stop := make(chan struct{})
+	t.stop = stop
+	t.mu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				// Errors are retried on the next tick
+				t.Poll()
+			}
+		}
+	}()
+}
+
+// Stop ends background polling
+func (t *JobTracker) Stop() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.stop != nil {
+		close(t.stop)
+		t.stop = nil
+	}
+}
+
+// Poll checks the tracked jobs once. Jobs still in the queue are
+// compared with their last known state; jobs that left the queue are
+// looked up in accounting and then forgotten.
+func (t *JobTracker) Poll() error {
+	all := t.config.NotifyAllJobs && t.user != ""
+	ids := t.Tracked()
+	if len(ids) == 0 && !all {
+		return nil
+	}
+
+	// Listing by user avoids squeue failing on IDs that already finished
+	filter := &slurm.JobFilter{JobIDs: ids}
+	if t.user != "" {
+		filter = &slurm.JobFilter{Users: []string{t.user}}
+	}
+	jobs, err := t.client.ListJobs(filter)
+	if err != nil {
+		return err
+	}
+
+	var found []Notification
+	active := make(map[string]bool)
+
+	t.mu.Lock()
+	for _, job := range jobs {
+		id := baseJobID(job.ID)
+		tracked, exists := t.jobs[id]
+		if !exists {
+			if !all {
+				continue
+			}
+			// Jobs started outside the shell are watched from now on
+			tracked = &trackedJob{name: job.Name, state: job.State}
+			t.jobs[id] = tracked
+		}
+		if active[id] {
+			continue
+		}
+		active[id] = true
+
+		if tracked.name == "" {
+			tracked.name = job.Name
+		}
+		changed := job.State != tracked.state
+		if tracked.state == "" {
+			// New jobs start out pending; report them once they moved on
+			changed = job.State != slurm.JobStatePending
+		}
+		if changed {
+			found = append(found, Notification{
+				JobID:    id,
+				Name:     tracked.name,
+				State:    job.State,
+				Reason:   job.Reason,
+				NodeList: job.NodeList,
+			})
+		}
+		tracked.state = job.State
+	}
+
+	var finished []string
+	for id := range t.jobs {
+		if !active[id] {
+			finished = append(finished, id)
+		}
+	}
+	t.mu.Unlock()
+
+	if len(finished) > 0 {
+		sort.Strings(finished)
+		found = append(found, t.finish(finished)...)
+	}
+
+	if len(found) > 0 {
+		t.mu.Lock()
+		t.notifications = append(t.notifications, found...)
+		t.mu.Unlock()
+
+		for _, n := range found {
+			t.runHook(n)
+		}
+	}
+	return nil
+}
+
+// finish looks up the final state of jobs that left the queue and stops
+// tracking them
+func (t *JobTracker) finish(ids []string) []Notification {
+	records, err := t.client.ListAccounting(&slurm.JobFilter{JobIDs: ids})
+	if err != nil {
+		records = nil
+	}
+
+	byID := make(map[string]slurm.JobRecord)
+	for _, record := range records {
+		id := baseJobID(record.ID)
+		if _, seen := byID[id]; !seen {
+			byID[id] = record
+		}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var found []Notification
+	for _, id := range ids {
+		tracked := t.jobs[id]
+		record, ok := byID[id]
+		if ok && !slurm.IsTerminalJobState(record.State) {
+			// Accounting lags behind the queue; check again next time
+			continue
+		}
+		delete(t.jobs, id)
+
+		n := Notification{JobID: id, Name: tracked.name, Finished: true}
+		if ok {
+			n.State = record.State
+			n.ExitCode = record.ExitCode
+			n.Elapsed = record.Elapsed
+			if n.Name == "" {
+				n.Name = record.Name
+			}
+		}
+		found = append(found, n)
+	}
+	return found
+}
+
+// Drain returns the notifications collected since the last call
+func (t *JobTracker) Drain() []Notification {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	notifications := t.notifications
+	t.notifications = nil
+	return notifications
+}
+
+// Print shows the collected notifications, ringing the bell if configured
+func (t *JobTracker) Print() {
+	notifications := t.Drain()
+	if len(notifications) == 0 {
+		return
+	}
+
+	if t.config.NotifyBell {
+		fmt.Print("\a")
+	}
+	for _, n := range notifications {
+		msg := n.String()
+		switch {
+		case !n.Finished:
+			fmt.Println(utils.FormatInfo(msg, t.config.ColorOutput))
+		case n.State == slurm.JobStateCompleted && n.ExitCode == 0:
+			fmt.Println(utils.FormatSuccess(msg, t.config.ColorOutput))
+		default:
+			fmt.Println(utils.FormatError(msg, t.config.ColorOutput))
+		}
+	}
+}
+
+// runHook runs the configured notification hook in the background. The
+// job is described in SLSH_JOB_* environment variables.
+func (t *JobTracker) runHook(n Notification) {
+	if t.config.NotifyHook == "" {
+		return
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), hookTimeout)
+		defer cancel()
+
+		cmd := exec.CommandContext(ctx, "sh", "-c", t.config.NotifyHook)
+		cmd.Env = append(os.Environ(),
+			"SLSH_JOB_ID="+n.JobID,
+			"SLSH_JOB_NAME="+n.Name,
+			"SLSH_JOB_STATE="+n.State,
+			"SLSH_JOB_EXIT_CODE="+strconv.Itoa(n.ExitCode),
+			"SLSH_JOB_FINISHED="+strconv.FormatBool(n.Finished),
+			"SLSH_MESSAGE="+n.String(),
+		)
+		cmd.Run()
+	}()
+}
+
+// baseJobID strips the array task from a job ID, so that "123_4" and
+// "123_[5-9]" are tracked as job 123
+func baseJobID(id string) string {
+	if i := strings.IndexByte(id, '_'); i >= 0 {
+		return id[:i]
+	}
+	return id
+}
@@ -0,0 +1,234 @@
+package slurm
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// recordFormat is the sacct format used by ListAccounting. The job name
+// is printed last so that separators inside names are kept intact.
+var recordFormat = strings.Join([]string{
+	"JobID", "State", "ExitCode", "Elapsed", "Submit", "Start", "End",
+	"Partition", "Account", "User", "AllocCPUS", "NodeList", "Timelimit",
+	"AllocTRES", "WorkDir", "Reason", "JobName",
+}, ",")
+
+// recordFieldCount is the number of fields in recordFormat
+const recordFieldCount = 17
+
+// ListAccounting returns the accounting records of the jobs matching
+// filter. Without job IDs, sacct only reports jobs since midnight.
+func (c *Client) ListAccounting(filter *JobFilter) ([]JobRecord, error) {
+	if c.useJSON() {
+		records, err := c.listAccountingJSON(filter)
+		if err != errJSONUnavailable {
+			return records, err
+		}
+	}
+
+	args := append([]string{"-X", "--noheader", "--parsable2", "--format=" + recordFormat}, accountingFilterArgs(filter)...)
+	result, err := c.Execute("sacct", args...)
+	if err != nil {
+		return nil, commandError(result, err)
+	}
+
+	records, err := ParseJobRecords(result.Output)
+	if err != nil {
+		return nil, err
+	}
+	return filterRecords(records, filter), nil
+}
+
+// accountingFilterArgs converts a JobFilter to sacct options
+func accountingFilterArgs(filter *JobFilter) []string {
+	var args []string
+	if filter == nil {
+		return args
+	}
+	if len(filter.JobIDs) > 0 {
+		args = append(args, "-j", strings.Join(filter.JobIDs, ","))
+	}
+	if len(filter.Users) > 0 {
+		args = append(args, "-u", strings.Join(filter.Users, ","))
+	} else if len(filter.JobIDs) == 0 {
+		// Without it sacct only reports the caller's own jobs
+		args = append(args, "--allusers")
+	}
+	if len(filter.States) > 0 {
+		args = append(args, "-s", strings.Join(filter.States, ","))
+	}
+	if len(filter.Partitions) > 0 {
+		args = append(args, "-r", strings.Join(filter.Partitions, ","))
+	}
+	if len(filter.Accounts) > 0 {
+		args = append(args, "-A", strings.Join(filter.Accounts, ","))
+	}
+	if len(filter.Names) > 0 && !hasGlob(filter.Names) {
+		args = append(args, "--name="+strings.Join(filter.Names, ","))
+	}
+	if !filter.Since.IsZero() {
+		args = append(args, "-S", filter.Since.Format(slurmTimeLayout))
+	}
+	if !filter.Until.IsZero() {
+		args = append(args, "-E", filter.Until.Format(slurmTimeLayout))
+	}
+	return args
+}
+
+// ParseJobRecords parses sacct --parsable2 output produced with recordFormat
+func ParseJobRecords(output string) ([]JobRecord, error) {
+	var records []JobRecord
+
+	for _, line := range strings.Split(output, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		fields := strings.SplitN(line, "|", recordFieldCount)
+		if len(fields) != recordFieldCount {
+			return nil, fmt.Errorf("unexpected sacct line: %q", line)
+		}
+
+		record := JobRecord{
+			ID:        fields[0],
+			State:     accountingState(fields[1]),
+			Partition: fields[7],
+			Account:   fields[8],
+			User:      fields[9],
+			CPUs:      atoi(fields[10]),
+			NodeList:  fields[11],
+			TimeLimit: fields[12],
+			TRES:      fields[13],
+			WorkDir:   fields[14],
+			Reason:    fields[15],
+			Name:      fields[16],
+		}
+		if record.NodeList == "None assigned" {
+			record.NodeList = ""
+		}
+
+		code, signal, _ := strings.Cut(fields[2], ":")
+		record.ExitCode = atoi(code)
+		record.Signal = atoi(signal)
+
+		record.Elapsed, _ = ParseSlurmDuration(fields[3])
+		record.SubmitTime = parseSlurmTime(fields[4])
+		record.StartTime = parseSlurmTime(fields[5])
+		record.EndTime = parseSlurmTime(fields[6])
+
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
+// JobSteps returns the accounting record of a job together with the
+// records of its steps, such as "1001.batch" and "1001.0"
+func (c *Client) JobSteps(jobID string) (*JobRecord, []JobRecord, error) {
+	var records []JobRecord
+	err := errJSONUnavailable
+	if c.useJSON() {
+		records, err = c.jobStepsJSON(jobID)
+	}
+	if err == errJSONUnavailable {
+		result, execErr := c.Execute("sacct", "-j", jobID, "--noheader", "--parsable2", "--format="+recordFormat)
+		if execErr != nil {
+			return nil, nil, commandError(result, execErr)
+		}
+		records, err = ParseJobRecords(result.Output)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var job *JobRecord
+	var steps []JobRecord
+	for i, record := range records {
+		parent, _, isStep := strings.Cut(record.ID, ".")
+		switch {
+		case !isStep && record.ID == jobID:
+			job = &records[i]
+		case isStep && parent == jobID:
+			steps = append(steps, record)
+		}
+	}
+	return job, steps, nil
+}
+
+// TRESCount returns the amount of a trackable resource allocated to the
+// job, such as "cpu", "billing" or "gres/gpu", from its AllocTRES. GPUs
+// listed only by type, as in "gres/gpu:a100=2", are added up.
+func (r *JobRecord) TRESCount(name string) float64 {
+	var total, typed float64
+	for _, item := range strings.Split(r.TRES, ",") {
+		key, value, ok := strings.Cut(item, "=")
+		if !ok {
+			continue
+		}
+		count, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			continue
+		}
+		switch {
+		case key == name:
+			total += count
+		case strings.HasPrefix(key, name+":"):
+			typed += count
+		}
+	}
+	if total > 0 {
+		return total
+	}
+	return typed
+}
+
+// accountingState strips the details sacct appends to some states, as in
+// "CANCELLED by 1000"
+func accountingState(state string) string {
+	if i := strings.IndexByte(state, ' '); i >= 0 {
+		state = state[:i]
+	}
+	return strings.TrimSuffix(state, "+")
+}
+
+// filterRecords applies a JobFilter to accounting records on the client side
+func filterRecords(records []JobRecord, filter *JobFilter) []JobRecord {
+	if filter == nil {
+		return records
+	}
+
+	var result []JobRecord
+	for _, record := range records {
+		if len(filter.Users) > 0 && !containsString(filter.Users, record.User) {
+			continue
+		}
+		if len(filter.JobIDs) > 0 && !matchesJobID(filter.JobIDs, record.ID) {
+			continue
+		}
+		if len(filter.States) > 0 && !matchesState(filter.States, record.State) {
+			continue
+		}
+		if len(filter.Partitions) > 0 && !matchesPartition(filter.Partitions, record.Partition) {
+			continue
+		}
+		if len(filter.Accounts) > 0 && !containsString(filter.Accounts, record.Account) {
+			continue
+		}
+		if len(filter.Names) > 0 && !matchesGlob(filter.Names, record.Name) {
+			continue
+		}
+		result = append(result, record)
+	}
+	return result
+}
+
+// IsTerminalJobState reports whether a job in state has finished
+func IsTerminalJobState(state string) bool {
+	switch NormalizeJobState(state) {
+	case JobStatePending, JobStateRunning, "CONFIGURING", "COMPLETING",
+		"SUSPENDED", "REQUEUED", "RESIZING", "SIGNALING", "STAGE_OUT", "STOPPED", "":
+		return false
+	}
+	return true
+}
@@ -0,0 +1,72 @@
+package slurm
+
+import (
+	"fmt"
+	"time"
+)
+
+// Allocation is a set of resources granted by salloc. Job steps started
+// with --jobid run in it without waiting in the queue again.
+type Allocation struct {
+	JobID     string    `json:"job_id"`
+	Partition string    `json:"partition,omitempty"`
+	NodeList  string    `json:"node_list,omitempty"`
+	End       time.Time `json:"end,omitempty"`
+}
+
+// Remaining returns the walltime left in the allocation, and false when
+// it has no time limit
+func (a *Allocation) Remaining() (time.Duration, bool) {
+	if a.End.IsZero() {
+		return 0, false
+	}
+	return max(time.Until(a.End), 0), true
+}
+
+// Allocate obtains an allocation with salloc --no-shell, which returns
+// once the resources are granted and leaves them allocated until the job
+// is cancelled or reaches its time limit. salloc's messages, such as the
+// ID of a pending allocation, are passed to handler as they arrive.
+func (c *Client) Allocate(options *JobOptions, handler LineHandler) (*Allocation, error) {
+	var args []string
+	if options != nil {
+		args = c.buildJobArgs(options)
+	}
+	args = append(args, "--no-shell")
+
+	result, err := c.ExecuteStream(handler, "salloc", args...)
+	if err != nil {
+		return nil, commandError(result, err)
+	}
+
+	jobID, ok := ParseSubmittedJobID(result.Error + result.Output)
+	if !ok {
+		return nil, fmt.Errorf("salloc did not report a job allocation")
+	}
+
+	alloc := &Allocation{JobID: jobID}
+	granted := time.Now()
+	if options != nil {
+		alloc.Partition = options.Partition
+		if limit, err := ParseSlurmDuration(options.Time); err == nil && limit > 0 {
+			alloc.End = granted.Add(limit)
+		}
+	}
+
+	// The queue knows the nodes and the actual limit, which may be the
+	// partition's default. The end is counted from the time used so far
+	// rather than the start time, so that clock skew does not matter.
+	jobs, err := c.ListJobs(&JobFilter{JobIDs: []string{jobID}})
+	if err == nil && len(jobs) > 0 {
+		job := jobs[0]
+		alloc.NodeList = job.NodeList
+		if job.Partition != "" {
+			alloc.Partition = job.Partition
+		}
+		if limit, err := ParseSlurmDuration(job.TimeLimit); err == nil && limit > 0 {
+			used, _ := ParseSlurmDuration(job.TimeUsed)
+			alloc.End = granted.Add(limit - used)
+		}
+	}
+	return alloc, nil
+}
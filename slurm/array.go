@@ -0,0 +1,141 @@
+package slurm
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// maxArrayTasks bounds the size of array specifications, well above the
+// largest MaxArraySize a cluster can configure
+const maxArrayTasks = 4000001
+
+// ArrayTask is one task of a job array, with its accounting record
+type ArrayTask struct {
+	Index int
+	JobRecord
+}
+
+// ArrayTasks returns the tasks of a job array sorted by index. sacct lists
+// the pending tasks as one record such as "1005_[5-9%2]"; they are
+// expanded into a task each.
+func (c *Client) ArrayTasks(jobID string) ([]ArrayTask, error) {
+	records, err := c.ListAccounting(&JobFilter{JobIDs: []string{jobID}})
+	if err != nil {
+		return nil, err
+	}
+
+	byIndex := make(map[int]ArrayTask)
+	for _, record := range records {
+		base, index, ok := strings.Cut(record.ID, "_")
+		if !ok || base != jobID {
+			continue
+		}
+
+		if strings.HasPrefix(index, "[") {
+			indices, _, err := ParseArrayIndices(strings.Trim(index, "[]"))
+			if err != nil {
+				return nil, fmt.Errorf("unexpected array task %s: %v", record.ID, err)
+			}
+			for _, i := range indices {
+				task := ArrayTask{Index: i, JobRecord: record}
+				task.ID = fmt.Sprintf("%s_%d", base, i)
+				byIndex[i] = task
+			}
+			continue
+		}
+
+		i, err := strconv.Atoi(index)
+		if err != nil {
+			return nil, fmt.Errorf("unexpected array task %s", record.ID)
+		}
+		// A requeued task is listed again; the last record is current
+		byIndex[i] = ArrayTask{Index: i, JobRecord: record}
+	}
+
+	tasks := make([]ArrayTask, 0, len(byIndex))
+	for _, task := range byIndex {
+		tasks = append(tasks, task)
+	}
+	sort.Slice(tasks, func(i, j int) bool {
+		return tasks[i].Index < tasks[j].Index
+	})
+	return tasks, nil
+}
+
+// ParseArrayIndices parses an --array specification such as "1-100%10",
+// "0-30:10" or "1,4,7-9". It returns the indices in ascending order and
+// the limit on simultaneously running tasks, which is 0 when not given.
+func ParseArrayIndices(spec string) ([]int, int, error) {
+	spec, limit, limited := strings.Cut(strings.TrimSpace(spec), "%")
+	throttle := 0
+	if limited {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 0 {
+			return nil, 0, fmt.Errorf("invalid task limit %q", limit)
+		}
+		throttle = n
+	}
+	if spec == "" {
+		return nil, 0, fmt.Errorf("no array indices")
+	}
+
+	seen := make(map[int]bool)
+	for _, item := range strings.Split(spec, ",") {
+		rangeSpec, stepSpec, stepped := strings.Cut(item, ":")
+		first, last, isRange := strings.Cut(rangeSpec, "-")
+		if !isRange {
+			last = first
+		}
+
+		start, err1 := strconv.Atoi(first)
+		end, err2 := strconv.Atoi(last)
+		if err1 != nil || err2 != nil || start < 0 || end < start {
+			return nil, 0, fmt.Errorf("invalid array range %q", item)
+		}
+		step := 1
+		if stepped {
+			var err error
+			if step, err = strconv.Atoi(stepSpec); err != nil || step < 1 || !isRange {
+				return nil, 0, fmt.Errorf("invalid array step %q", item)
+			}
+		}
+		if (end-start)/step+len(seen) >= maxArrayTasks {
+			return nil, 0, fmt.Errorf("array %q is too large", spec)
+		}
+
+		for i := start; i <= end; i += step {
+			seen[i] = true
+		}
+	}
+
+	indices := make([]int, 0, len(seen))
+	for i := range seen {
+		indices = append(indices, i)
+	}
+	sort.Ints(indices)
+	return indices, throttle, nil
+}
+
+// FormatArrayIndices formats indices as an --array specification,
+// joining consecutive indices into ranges as in "1-3,7"
+func FormatArrayIndices(indices []int) string {
+	sorted := append([]int(nil), indices...)
+	sort.Ints(sorted)
+
+	var parts []string
+	for i := 0; i < len(sorted); {
+		j := i
+		for j+1 < len(sorted) && sorted[j+1] <= sorted[j]+1 {
+			j++
+		}
+		if sorted[i] == sorted[j] {
+			parts = append(parts, strconv.Itoa(sorted[i]))
+		} else {
+			parts = append(parts, fmt.Sprintf("%d-%d", sorted[i], sorted[j]))
+		}
+		i = j + 1
+	}
+	return strings.Join(parts, ",")
+}
@@ -1,63 +1,93 @@
 package slurm
 
 import (
-	"bytes"
 	"context"
 	"fmt"
+	"maps"
 	"os"
 	"os/exec"
+	"slices"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
 // Client handles Slurm command execution
 type Client struct {
-	timeout time.Duration
+	runner Runner
+	rest   *RESTClient
+	
+	// Timeout and retry policies, guarded by mu
+	policies map[OpClass]Policy
+	backoff  time.Duration
+	
+	// Slurm version, detected once per session
+	versionOnce sync.Once
+	version     SlurmVersion
+	versionErr  error
+	jsonEnabled atomic.Bool
+	
+	// Commands in flight, cancelled by Interrupt
+	mu          sync.Mutex
+	inflight    map[int]context.CancelFunc
+	nextID      int
+	interactive atomic.Int32
 }
 
 // NewClient creates a new Slurm client
 func NewClient() *Client {
+	return NewClientWithRunner(NewExecRunner())
+}
+
+// NewClientWithRunner creates a Slurm client that executes commands through runner
+func NewClientWithRunner(runner Runner) *Client {
 	return &Client{
-		timeout: 30 * time.Second,
+		runner:   runner,
+		policies: defaultPolicies(),
+		backoff:  defaultRetryBackoff,
 	}
 }
 
-// Execute executes a Slurm command with the given arguments
-func (c *Client) Execute(command string, args ...string) (*CommandResult, error) {
-	start := time.Now()
-	
-	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
-	defer cancel()
-	
-	cmd := exec.CommandContext(ctx, command, args...)
-	
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
-	
-	err := cmd.Run()
-	
-	result := &CommandResult{
-		Success:  err == nil,
-		Output:   stdout.String(),
-		Error:    stderr.String(),
-		Duration: time.Since(start),
-	}
-	
-	if err != nil {
-		if exitErr, ok := err.(*exec.ExitError); ok {
-			result.ExitCode = exitErr.ExitCode()
-		} else {
-			result.ExitCode = -1
-		}
-		return result, fmt.Errorf("command failed: %v", err)
+// NewClientWithREST creates a Slurm client that answers job, node and
+// partition queries, submissions and cancellations through slurmrestd.
+// Other commands, such as srun, still run locally.
+func NewClientWithREST(rest *RESTClient) *Client {
+	client := NewClient()
+	client.rest = rest
+	return client
+}
+
+// Clone returns a client with the same backend and policies, for work in
+// the background. Interrupting either client does not affect the other.
+func (c *Client) Clone() *Client {
+	clone := NewClientWithRunner(c.runner)
+	clone.rest = c.rest
+	
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for class, policy := range c.policies {
+		clone.policies[class] = policy
 	}
+	clone.backoff = c.backoff
 	
-	return result, nil
+	return clone
+}
+
+// Execute executes a Slurm command with the given arguments. The timeout
+// and retries depend on the operation class of the command.
+func (c *Client) Execute(command string, args ...string) (*CommandResult, error) {
+	return c.ExecuteStream(nil, command, args...)
 }
 
 // RunJob submits and runs a job using srun
 func (c *Client) RunJob(command string, options *JobOptions) (*CommandResult, error) {
+	return c.RunJobStream(command, options, nil)
+}
+
+// RunJobStream runs a job using srun, passing its output to handler line
+// by line while the job runs
+func (c *Client) RunJobStream(command string, options *JobOptions, handler LineHandler) (*CommandResult, error) {
 	args := []string{}
 	
 	// Add job options
@@ -70,11 +100,21 @@ func (c *Client) RunJob(command string, options *JobOptions) (*CommandResult, er
 		args = append(args, command)
 	}
 	
-	return c.Execute("srun", args...)
+	return c.ExecuteStream(handler, "srun", args...)
 }
 
 // SubmitJob submits a job using sbatch
 func (c *Client) SubmitJob(scriptPath string, options *JobOptions) (*CommandResult, error) {
+	if c.rest != nil {
+		var result *CommandResult
+		err := c.withPolicy(OpSubmit, func(ctx context.Context) (bool, error) {
+			var err error
+			result, err = c.rest.SubmitJob(ctx, scriptPath, options)
+			return false, err
+		})
+		return result, err
+	}
+	
 	args := []string{}
 	
 	// Add job options
@@ -90,9 +130,36 @@ func (c *Client) SubmitJob(scriptPath string, options *JobOptions) (*CommandResu
 
 // CancelJob cancels a job using scancel
 func (c *Client) CancelJob(jobID string) (*CommandResult, error) {
+	if c.rest != nil {
+		var result *CommandResult
+		err := c.withPolicy(OpMutate, func(ctx context.Context) (bool, error) {
+			var err error
+			result, err = c.rest.CancelJob(ctx, jobID)
+			return false, err
+		})
+		return result, err
+	}
+	
 	return c.Execute("scancel", jobID)
 }
 
+// CancelJobs cancels several jobs with a single scancel. Job IDs may
+// select array tasks, as in "1234_[4-9]".
+func (c *Client) CancelJobs(jobIDs []string) (*CommandResult, error) {
+	if c.rest != nil {
+		var result *CommandResult
+		for _, jobID := range jobIDs {
+			var err error
+			if result, err = c.CancelJob(jobID); err != nil {
+				return result, fmt.Errorf("job %s: %v", jobID, err)
+			}
+		}
+		return result, nil
+	}
+	
+	return c.Execute("scancel", jobIDs...)
+}
+
 // GetJobStatus gets status of a specific job
 func (c *Client) GetJobStatus(jobID string) (*CommandResult, error) {
 	return c.Execute("squeue", "-j", jobID, "--format=%i,%T,%P,%u,%M,%N,%r")
@@ -209,9 +276,37 @@ func (c *Client) buildJobArgs(options *JobOptions) []string {
 		args = append(args, "--chdir="+options.WorkDir)
 	}
 	
-	// Add environment variables
-	for key, value := range options.Environment {
-		args = append(args, "--export="+key+"="+value)
+	if options.NodeList != "" {
+		args = append(args, "--nodelist="+options.NodeList)
+	}
+	
+	if options.Exclude != "" {
+		args = append(args, "--exclude="+options.Exclude)
+	}
+	
+	if options.Array != "" {
+		args = append(args, "--array="+options.Array)
+	}
+	
+	if options.Dependency != "" {
+		args = append(args, "--dependency="+options.Dependency)
+	}
+	
+	if options.JobID != "" {
+		args = append(args, "--jobid="+options.JobID)
+	}
+	
+	if options.Label {
+		args = append(args, "--label")
+	}
+	
+	if options.PTY {
+		args = append(args, "--pty")
+	}
+	
+	// Add environment variables in a stable order
+	for _, key := range slices.Sorted(maps.Keys(options.Environment)) {
+		args = append(args, "--export="+key+"="+options.Environment[key])
 	}
 	
 	// Add extra arguments
@@ -220,8 +315,12 @@ func (c *Client) buildJobArgs(options *JobOptions) []string {
 	return args
 }
 
-// ExecuteInteractive runs a command interactively (with stdin/stdout)
+// ExecuteInteractive runs a command interactively (with stdin/stdout).
+// The command shares the terminal, so it receives Ctrl+C directly.
 func (c *Client) ExecuteInteractive(command string, args ...string) error {
+	c.interactive.Add(1)
+	defer c.interactive.Add(-1)
+	
 	cmd := exec.Command(command, args...)
 	cmd.Stdin = os.Stdin
 	cmd.Stdout = os.Stdout
@@ -230,7 +329,20 @@ func (c *Client) ExecuteInteractive(command string, args ...string) error {
 	return cmd.Run()
 }
 
-// SetTimeout sets the command execution timeout
+// GetRunner returns the runner used to execute commands
+func (c *Client) GetRunner() Runner {
+	return c.runner
+}
+
+// SetTimeout sets the timeout of queries and of commands that change
+// cluster state. sbatch and srun keep their own policies.
 func (c *Client) SetTimeout(timeout time.Duration) {
-	c.timeout = timeout
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	
+	for _, class := range []OpClass{OpRead, OpMutate} {
+		policy := c.policies[class]
+		policy.Timeout = timeout
+		c.policies[class] = policy
+	}
 }
\ No newline at end of file
@@ -0,0 +1,240 @@
+package slurm
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"slsh/slurm/hostlist"
+)
+
+// JobDetail returns everything known about a job. The controller only
+// remembers jobs for a few minutes after they end, so scontrol and squeue
+// describe queued and running jobs while sacct covers finished ones. Any
+// of the three may fail; the job is only reported missing when none of
+// them knows it.
+func (c *Client) JobDetail(jobID string) (*JobDetail, error) {
+	detail := &JobDetail{JobRecord: JobRecord{ID: jobID}}
+	var firstErr error
+	note := func(err error) {
+		if firstErr == nil && !isUnknownJob(err) {
+			firstErr = err
+		}
+	}
+
+	record, steps, err := c.JobSteps(jobID)
+	if err != nil {
+		note(err)
+	} else if record != nil {
+		detail.JobRecord = *record
+		detail.Steps = steps
+		detail.Sources = append(detail.Sources, "sacct")
+	}
+
+	jobs, err := c.ListJobs(&JobFilter{JobIDs: []string{jobID}})
+	if err != nil {
+		note(err)
+	} else if len(jobs) > 0 {
+		detail.mergeJob(jobs[0])
+		detail.Sources = append(detail.Sources, "squeue")
+	}
+
+	fields, err := c.ShowJob(jobID)
+	if err != nil {
+		note(err)
+	} else if fields != nil {
+		detail.mergeControl(fields)
+		detail.Sources = append(detail.Sources, "scontrol")
+	}
+
+	if len(detail.Sources) == 0 {
+		if firstErr != nil {
+			return nil, firstErr
+		}
+		return nil, fmt.Errorf("job %s not found", jobID)
+	}
+	return detail, nil
+}
+
+// ShowJob returns the fields printed by 'scontrol show job', or nil when
+// the controller no longer knows the job
+func (c *Client) ShowJob(jobID string) (map[string]string, error) {
+	result, err := c.Execute("scontrol", "show", "job", jobID)
+	if err != nil {
+		err = commandError(result, err)
+		if isUnknownJob(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return ParseControlRecord(result.Output), nil
+}
+
+// ParseControlRecord parses the Key=Value output of 'scontrol show'.
+// Values may contain spaces, as in "JobName=eval, final", so words
+// without '=' are appended to the preceding value. Only the first record
+// is returned when the output lists several, as it does for job arrays.
+func ParseControlRecord(output string) map[string]string {
+	fields := make(map[string]string)
+	var last string
+
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		if strings.TrimSpace(line) == "" {
+			if len(fields) > 0 {
+				break
+			}
+			continue
+		}
+
+		for _, word := range strings.Fields(line) {
+			key, value, ok := strings.Cut(word, "=")
+			if !ok || key == "" {
+				if last != "" {
+					fields[last] += " " + word
+				}
+				continue
+			}
+			fields[key] = value
+			last = key
+		}
+	}
+
+	if len(fields) == 0 {
+		return nil
+	}
+	return fields
+}
+
+// isUnknownJob reports whether err says the job does not exist
+func isUnknownJob(err error) bool {
+	return err != nil && strings.Contains(strings.ToLower(err.Error()), "invalid job id")
+}
+
+// mergeJob overrides the accounting view with the live view of squeue
+func (d *JobDetail) mergeJob(job Job) {
+	override(&d.Name, job.Name)
+	override(&d.User, job.User)
+	override(&d.Account, job.Account)
+	override(&d.Partition, job.Partition)
+	override(&d.State, job.State)
+	override(&d.Reason, job.Reason)
+	override(&d.TimeLimit, job.TimeLimit)
+	override(&d.NodeList, job.NodeList)
+	override(&d.WorkDir, job.WorkDir)
+	override(&d.Command, job.Command)
+
+	if job.CPUs > 0 {
+		d.CPUs = job.CPUs
+	}
+	if elapsed, err := ParseSlurmDuration(job.TimeUsed); err == nil {
+		d.Elapsed = elapsed
+	}
+	if !job.SubmitTime.IsZero() {
+		d.SubmitTime = job.SubmitTime
+	}
+	if !job.StartTime.IsZero() {
+		d.StartTime = job.StartTime
+	}
+	if !job.EndTime.IsZero() {
+		d.EndTime = job.EndTime
+	}
+}
+
+// mergeControl overrides the other views with the fields of scontrol,
+// which is the only source of the output paths on older releases
+func (d *JobDetail) mergeControl(fields map[string]string) {
+	user, _, _ := strings.Cut(fields["UserId"], "(")
+
+	override(&d.Name, fields["JobName"])
+	override(&d.User, user)
+	override(&d.Account, nullable(fields["Account"]))
+	override(&d.Partition, fields["Partition"])
+	override(&d.State, fields["JobState"])
+	override(&d.Reason, fields["Reason"])
+	override(&d.TimeLimit, fields["TimeLimit"])
+	override(&d.NodeList, nullable(fields["NodeList"]))
+	override(&d.WorkDir, fields["WorkDir"])
+	override(&d.StdOut, fields["StdOut"])
+	override(&d.StdErr, fields["StdErr"])
+	override(&d.Command, nullable(fields["Command"]))
+	override(&d.ArrayJobID, nullable(fields["ArrayJobId"]))
+	override(&d.ArrayTaskID, nullable(fields["ArrayTaskId"]))
+
+	// Pending jobs only have requested resources, and releases before
+	// 21.08 print TRES= rather than AllocTRES=
+	override(&d.TRES, nullable(fields["ReqTRES"]))
+	override(&d.TRES, nullable(fields["TRES"]))
+	override(&d.TRES, nullable(fields["AllocTRES"]))
+
+	if code, signal, ok := strings.Cut(fields["ExitCode"], ":"); ok {
+		d.ExitCode = atoi(code)
+		d.Signal = atoi(signal)
+	}
+	if cpus := atoi(fields["NumCPUs"]); cpus > 0 {
+		d.CPUs = cpus
+	}
+	if elapsed, err := ParseSlurmDuration(fields["RunTime"]); err == nil {
+		d.Elapsed = elapsed
+	}
+	if t := parseSlurmTime(fields["SubmitTime"]); !t.IsZero() {
+		d.SubmitTime = t
+	}
+	if t := parseSlurmTime(fields["StartTime"]); !t.IsZero() {
+		d.StartTime = t
+	}
+	if t := parseSlurmTime(fields["EndTime"]); !t.IsZero() {
+		d.EndTime = t
+	}
+}
+
+// OutputPaths returns the files a batch job writes its stdout and stderr
+// to. sacct reports the paths as given to sbatch, so patterns such as %j
+// and %x are expanded here, and relative paths are relative to the work
+// directory. Without paths, the job uses the sbatch default, which sends
+// both streams to the same file.
+func (d *JobDetail) OutputPaths() (stdout, stderr string) {
+	fields := FilenameFields{
+		JobID:       d.ID,
+		ArrayJobID:  d.ArrayJobID,
+		ArrayTaskID: d.ArrayTaskID,
+		Name:        d.Name,
+		User:        d.User,
+	}
+	// The job ID of an array task is its own, which only the controller
+	// knows while the task is queued or running
+	if arrayID, taskID, ok := strings.Cut(d.ID, "_"); ok {
+		fields.JobID = ""
+		override(&fields.ArrayJobID, arrayID)
+		override(&fields.ArrayTaskID, taskID)
+	}
+	if nodes, err := hostlist.Expand(d.NodeList); err == nil && len(nodes) > 0 {
+		fields.Node = nodes[0]
+	}
+
+	resolve := func(pattern string) string {
+		path := ExpandFilenamePattern(pattern, fields)
+		if !filepath.IsAbs(path) && d.WorkDir != "" {
+			path = filepath.Join(d.WorkDir, path)
+		}
+		return path
+	}
+
+	stdout, stderr = d.StdOut, d.StdErr
+	if stdout == "" {
+		stdout = "slurm-%j.out"
+		if fields.ArrayTaskID != "" {
+			stdout = "slurm-%A_%a.out"
+		}
+	}
+	if stderr == "" {
+		stderr = stdout
+	}
+	return resolve(stdout), resolve(stderr)
+}
+
+// override replaces *field with value unless value is empty
+func override(field *string, value string) {
+	if value != "" {
+		*field = value
+	}
+}
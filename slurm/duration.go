@@ -0,0 +1,90 @@
+package slurm
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FormatSlurmDuration formats a duration the way squeue and sinfo print
+// times: "M:SS", "H:MM:SS" or "D-HH:MM:SS"
+func FormatSlurmDuration(d time.Duration) string {
+	if d < 0 {
+		d = 0
+	}
+
+	secs := int64(d / time.Second)
+	days := secs / 86400
+	hours := secs / 3600 % 24
+	minutes := secs / 60 % 60
+	seconds := secs % 60
+
+	switch {
+	case days > 0:
+		return fmt.Sprintf("%d-%02d:%02d:%02d", days, hours, minutes, seconds)
+	case hours > 0:
+		return fmt.Sprintf("%d:%02d:%02d", hours, minutes, seconds)
+	default:
+		return fmt.Sprintf("%d:%02d", minutes, seconds)
+	}
+}
+
+// ParseSlurmDuration parses a Slurm time specification. Accepted forms are
+// "minutes", "minutes:seconds", "hours:minutes:seconds", "days-hours",
+// "days-hours:minutes" and "days-hours:minutes:seconds".
+func ParseSlurmDuration(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, fmt.Errorf("empty time value")
+	}
+
+	var days int64
+	rest := value
+	hasDays := false
+	if i := strings.Index(value, "-"); i >= 0 {
+		d, err := strconv.ParseInt(value[:i], 10, 64)
+		if err != nil || d < 0 {
+			return 0, fmt.Errorf("invalid time value: %s", value)
+		}
+		days = d
+		rest = value[i+1:]
+		hasDays = true
+	}
+
+	parts := strings.Split(rest, ":")
+	if len(parts) > 3 {
+		return 0, fmt.Errorf("invalid time value: %s", value)
+	}
+
+	nums := make([]int64, len(parts))
+	for i, part := range parts {
+		n, err := strconv.ParseInt(part, 10, 64)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid time value: %s", value)
+		}
+		nums[i] = n
+	}
+
+	var hours, minutes, seconds int64
+	switch {
+	case hasDays:
+		// days-hours[:minutes[:seconds]]
+		hours = nums[0]
+		if len(nums) > 1 {
+			minutes = nums[1]
+		}
+		if len(nums) > 2 {
+			seconds = nums[2]
+		}
+	case len(nums) == 3:
+		hours, minutes, seconds = nums[0], nums[1], nums[2]
+	case len(nums) == 2:
+		minutes, seconds = nums[0], nums[1]
+	default:
+		minutes = nums[0]
+	}
+
+	total := ((days*24+hours)*60+minutes)*60 + seconds
+	return time.Duration(total) * time.Second, nil
+}
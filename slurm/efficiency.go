@@ -0,0 +1,207 @@
+package slurm
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// efficiencyFormat is the sacct format used for efficiency reports. The
+// usage figures come from the text output even when sacct speaks JSON,
+// since every release prints them the same way there.
+var efficiencyFormat = strings.Join([]string{
+	"JobID", "State", "Elapsed", "Timelimit", "AllocCPUS", "NNodes",
+	"TotalCPU", "ReqMem", "MaxRSS", "User", "Account", "Partition",
+	"Start", "End", "JobName",
+}, ",")
+
+// efficiencyFieldCount is the number of fields in efficiencyFormat
+const efficiencyFieldCount = 15
+
+// JobUsage is what a job asked for and what it used, as needed to judge
+// its efficiency
+type JobUsage struct {
+	ID        string        `json:"id"`
+	Name      string        `json:"name"`
+	User      string        `json:"user"`
+	Account   string        `json:"account,omitempty"`
+	Partition string        `json:"partition"`
+	State     string        `json:"state"`
+	Nodes     int           `json:"nodes"`
+	CPUs      int           `json:"cpus"`
+	Elapsed   time.Duration `json:"elapsed"`
+	TimeLimit time.Duration `json:"time_limit,omitempty"`
+	TotalCPU  time.Duration `json:"total_cpu"`
+	ReqMemMB  int64         `json:"req_mem_mb"`
+	MaxRSSMB  int64         `json:"max_rss_mb"`
+	StartTime time.Time     `json:"start_time,omitempty"`
+	EndTime   time.Time     `json:"end_time,omitempty"`
+}
+
+// CoreWalltime returns the CPU time the job had allocated
+func (u *JobUsage) CoreWalltime() time.Duration {
+	return u.Elapsed * time.Duration(u.CPUs)
+}
+
+// CPUEfficiency returns the share of the allocated CPU time the job used,
+// and false when nothing was allocated yet
+func (u *JobUsage) CPUEfficiency() (float64, bool) {
+	if u.CoreWalltime() <= 0 {
+		return 0, false
+	}
+	return float64(u.TotalCPU) / float64(u.CoreWalltime()), true
+}
+
+// MemoryPerNodeMB returns the memory requested on each node
+func (u *JobUsage) MemoryPerNodeMB() int64 {
+	return u.ReqMemMB / int64(max(u.Nodes, 1))
+}
+
+// MemoryPerCPUMB returns the memory requested for each CPU
+func (u *JobUsage) MemoryPerCPUMB() int64 {
+	return u.ReqMemMB / int64(max(u.CPUs, 1))
+}
+
+// MemoryEfficiency returns the largest resident set of any task against
+// the memory requested per node, and false when either is unknown.
+// MaxRSS is per task, so jobs running several tasks per node used more
+// than this suggests.
+func (u *JobUsage) MemoryEfficiency() (float64, bool) {
+	if u.MemoryPerNodeMB() <= 0 {
+		return 0, false
+	}
+	return float64(u.MaxRSSMB) / float64(u.MemoryPerNodeMB()), true
+}
+
+// WalltimeUse returns the share of the time limit the job ran for, and
+// false when it has no limit
+func (u *JobUsage) WalltimeUse() (float64, bool) {
+	if u.TimeLimit <= 0 {
+		return 0, false
+	}
+	return float64(u.Elapsed) / float64(u.TimeLimit), true
+}
+
+// JobUsage returns the usage of a single job
+func (c *Client) JobUsage(jobID string) (*JobUsage, error) {
+	usages, err := c.ListUsage(&JobFilter{JobIDs: []string{jobID}})
+	if err != nil {
+		return nil, err
+	}
+	if len(usages) == 0 {
+		return nil, fmt.Errorf("job %s not found in accounting", jobID)
+	}
+	return &usages[0], nil
+}
+
+// ListUsage returns the usage of the jobs matching filter. Without job
+// IDs or a start time, sacct only reports jobs since midnight.
+func (c *Client) ListUsage(filter *JobFilter) ([]JobUsage, error) {
+	args := append([]string{"--noheader", "--parsable2", "--format=" + efficiencyFormat}, accountingFilterArgs(filter)...)
+	result, err := c.Execute("sacct", args...)
+	if err != nil {
+		return nil, commandError(result, err)
+	}
+
+	usages, err := ParseJobUsage(result.Output)
+	if err != nil {
+		return nil, err
+	}
+	if filter == nil || len(filter.JobIDs) == 0 {
+		return usages, nil
+	}
+
+	var matched []JobUsage
+	for _, usage := range usages {
+		if matchesJobID(filter.JobIDs, usage.ID) {
+			matched = append(matched, usage)
+		}
+	}
+	return matched, nil
+}
+
+// ParseJobUsage parses sacct --parsable2 output produced with
+// efficiencyFormat. The lines of a job's steps, such as "1001.batch",
+// only contribute their MaxRSS to the job, which sacct does not report
+// for the job as a whole.
+func ParseJobUsage(output string) ([]JobUsage, error) {
+	var usages []JobUsage
+	index := make(map[string]int)
+
+	for _, line := range strings.Split(output, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		fields := strings.SplitN(line, "|", efficiencyFieldCount)
+		if len(fields) != efficiencyFieldCount {
+			return nil, fmt.Errorf("unexpected sacct line: %q", line)
+		}
+
+		rss, _ := ParseMemoryMB(fields[8])
+		if jobID, _, isStep := strings.Cut(fields[0], "."); isStep {
+			if i, ok := index[jobID]; ok {
+				usages[i].MaxRSSMB = max(usages[i].MaxRSSMB, rss)
+			}
+			continue
+		}
+
+		usage := JobUsage{
+			ID:        fields[0],
+			State:     accountingState(fields[1]),
+			CPUs:      atoi(fields[4]),
+			Nodes:     atoi(fields[5]),
+			MaxRSSMB:  rss,
+			User:      fields[9],
+			Account:   fields[10],
+			Partition: fields[11],
+			StartTime: parseSlurmTime(fields[12]),
+			EndTime:   parseSlurmTime(fields[13]),
+			Name:      fields[14],
+		}
+		usage.Elapsed, _ = ParseSlurmDuration(fields[2])
+		usage.TimeLimit, _ = ParseSlurmDuration(fields[3])
+		usage.TotalCPU, _ = parseCPUTime(fields[6])
+		usage.ReqMemMB = parseReqMem(fields[7], usage.Nodes, usage.CPUs)
+
+		index[usage.ID] = len(usages)
+		usages = append(usages, usage)
+	}
+
+	return usages, nil
+}
+
+// parseCPUTime parses sacct's TotalCPU, which has fractions of a second,
+// as in "01:02.345" or "1-02:03:04.5"
+func parseCPUTime(value string) (time.Duration, error) {
+	whole, fraction, _ := strings.Cut(strings.TrimSpace(value), ".")
+	d, err := ParseSlurmDuration(whole)
+	if err != nil {
+		return 0, err
+	}
+	if fraction != "" {
+		if f, err := time.ParseDuration("0." + fraction + "s"); err == nil {
+			d += f
+		}
+	}
+	return d, nil
+}
+
+// parseReqMem returns the memory a job requested in total. Releases
+// before 21.08 mark the size as per node ("4000Mn") or per CPU ("4000Mc");
+// later ones print the total for the job.
+func parseReqMem(value string, nodes, cpus int) int64 {
+	value = strings.TrimSpace(value)
+	multiplier := int64(1)
+	if trimmed, ok := strings.CutSuffix(value, "n"); ok {
+		value, multiplier = trimmed, int64(max(nodes, 1))
+	} else if trimmed, ok := strings.CutSuffix(value, "c"); ok {
+		value, multiplier = trimmed, int64(max(cpus, 1))
+	}
+
+	mb, err := ParseMemoryMB(value)
+	if err != nil {
+		return 0
+	}
+	return mb * multiplier
+}
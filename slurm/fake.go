@@ -0,0 +1,278 @@
+package slurm
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Fixture is a canned response for a Slurm command
+type Fixture struct {
+	Command  string   `json:"command"`
+	Args     []string `json:"args,omitempty"`
+	Output   string   `json:"output"`
+	Error    string   `json:"error,omitempty"`
+	ExitCode int      `json:"exit_code"`
+}
+
+// Call records a command received by a FakeRunner
+type Call struct {
+	Command string
+	Args    []string
+}
+
+// FakeRunner answers Slurm commands from fixtures instead of running them.
+// A fixture matches when its command is equal and all of its args appear
+// in the call; the fixture with the most args wins.
+type FakeRunner struct {
+	mu       sync.Mutex
+	fixtures []Fixture
+	calls    []Call
+}
+
+// NewFakeRunner creates a fake runner with the given fixtures
+func NewFakeRunner(fixtures []Fixture) *FakeRunner {
+	return &FakeRunner{fixtures: fixtures}
+}
+
+// NewFakeRunnerFromFile creates a fake runner from a fixture file, falling
+// back to the built-in fixtures when path is empty
+func NewFakeRunnerFromFile(path string) (*FakeRunner, error) {
+	if path == "" {
+		return NewFakeRunner(DefaultFixtures()), nil
+	}
+
+	fixtures, err := LoadFixtures(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewFakeRunner(fixtures), nil
+}
+
+// Run returns the best matching fixture for the command
+func (f *FakeRunner) Run(ctx context.Context, command string, args ...string) (*CommandResult, error) {
+	f.mu.Lock()
+	f.calls = append(f.calls, Call{Command: command, Args: append([]string(nil), args...)})
+	fixture, ok := f.match(command, args)
+	f.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return &CommandResult{ExitCode: -1}, fmt.Errorf("command failed: %v", err)
+	}
+
+	if !ok {
+		result := &CommandResult{
+			ExitCode: 1,
+			Error:    fmt.Sprintf("fake: no fixture for %s %s\n", command, strings.Join(args, " ")),
+		}
+		return result, fmt.Errorf("command failed: exit status 1")
+	}
+
+	result := &CommandResult{
+		Success:  fixture.ExitCode == 0,
+		ExitCode: fixture.ExitCode,
+		Output:   fixture.Output,
+		Error:    fixture.Error,
+	}
+	if !result.Success {
+		return result, fmt.Errorf("command failed: exit status %d", fixture.ExitCode)
+	}
+	return result, nil
+}
+
+// RunStreaming answers from fixtures and emits the output line by line
+func (f *FakeRunner) RunStreaming(ctx context.Context, handler LineHandler, command string, args ...string) (*CommandResult, error) {
+	result, err := f.Run(ctx, command, args...)
+	if result != nil {
+		replayLines(handler, result)
+	}
+	return result, err
+}
+
+// RunTerminal answers from fixtures, printing the output as a terminal would
+func (f *FakeRunner) RunTerminal(command string, args ...string) error {
+	result, err := f.Run(context.Background(), command, args...)
+	fmt.Fprint(os.Stdout, result.Output+result.Error)
+	return err
+}
+
+// match finds the most specific fixture for a call
+func (f *FakeRunner) match(command string, args []string) (Fixture, bool) {
+	best := -1
+	for i, fixture := range f.fixtures {
+		if fixture.Command != command || !containsAll(args, fixture.Args) {
+			continue
+		}
+		if best < 0 || len(fixture.Args) > len(f.fixtures[best].Args) {
+			best = i
+		}
+	}
+	if best < 0 {
+		return Fixture{}, false
+	}
+	return f.fixtures[best], true
+}
+
+// Add registers an additional fixture
+func (f *FakeRunner) Add(fixture Fixture) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.fixtures = append(f.fixtures, fixture)
+}
+
+// Calls returns the commands received so far
+func (f *FakeRunner) Calls() []Call {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Call(nil), f.calls...)
+}
+
+// containsAll reports whether every element of want appears in have
+func containsAll(have, want []string) bool {
+	for _, w := range want {
+		found := false
+		for _, h := range have {
+			if h == w {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// RecordingRunner runs commands through another runner and saves every
+// result as a fixture, so real cluster output can be replayed later
+type RecordingRunner struct {
+	mu       sync.Mutex
+	runner   Runner
+	path     string
+	fixtures []Fixture
+}
+
+// NewRecordingRunner creates a recorder writing to the given fixture file.
+// Existing fixtures in the file are kept.
+func NewRecordingRunner(runner Runner, path string) (*RecordingRunner, error) {
+	if path == "" {
+		return nil, fmt.Errorf("record mode requires a fixture file")
+	}
+
+	fixtures, err := LoadFixtures(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	return &RecordingRunner{runner: runner, path: path, fixtures: fixtures}, nil
+}
+
+// Run executes the command and records its result
+func (r *RecordingRunner) Run(ctx context.Context, command string, args ...string) (*CommandResult, error) {
+	return r.RunStreaming(ctx, nil, command, args...)
+}
+
+// RunTerminal runs an interactive command through the wrapped runner.
+// Interactive sessions are not recorded.
+func (r *RecordingRunner) RunTerminal(command string, args ...string) error {
+	runner, ok := r.runner.(TerminalRunner)
+	if !ok {
+		return fmt.Errorf("%s needs a terminal, which this backend cannot provide", command)
+	}
+	return runner.RunTerminal(command, args...)
+}
+
+// RunStreaming executes the command, streaming output when the wrapped
+// runner supports it, and records its result
+func (r *RecordingRunner) RunStreaming(ctx context.Context, handler LineHandler, command string, args ...string) (*CommandResult, error) {
+	streamer, ok := r.runner.(StreamingRunner)
+	if !ok {
+		streamer = &replayRunner{r.runner}
+	}
+
+	result, err := streamer.RunStreaming(ctx, handler, command, args...)
+	if result == nil {
+		return result, err
+	}
+
+	fixture := Fixture{
+		Command:  command,
+		Args:     append([]string(nil), args...),
+		Output:   result.Output,
+		Error:    result.Error,
+		ExitCode: result.ExitCode,
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	replaced := false
+	for i, existing := range r.fixtures {
+		if existing.Command == command && strings.Join(existing.Args, "\x00") == strings.Join(args, "\x00") {
+			r.fixtures[i] = fixture
+			replaced = true
+			break
+		}
+	}
+	if !replaced {
+		r.fixtures = append(r.fixtures, fixture)
+	}
+
+	if saveErr := SaveFixtures(r.path, r.fixtures); saveErr != nil {
+		fmt.Fprintf(os.Stderr, "Warning: Failed to record fixture: %v\n", saveErr)
+	}
+
+	return result, err
+}
+
+// LoadFixtures reads fixtures from a JSON file
+func LoadFixtures(path string) ([]Fixture, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var fixtures []Fixture
+	if err := json.Unmarshal(data, &fixtures); err != nil {
+		return nil, fmt.Errorf("failed to parse fixture file: %v", err)
+	}
+	return fixtures, nil
+}
+
+// SaveFixtures writes fixtures to a JSON file
+func SaveFixtures(path string, fixtures []Fixture) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create fixture directory: %v", err)
+	}
+
+	data, err := json.MarshalIndent(fixtures, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal fixtures: %v", err)
+	}
+
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write fixture file: %v", err)
+	}
+	return nil
+}
+
+// defaultFixtures is the canned cluster of the fake backend
+//
+//go:embed fixtures/cluster.json
+var defaultFixtures []byte
+
+// DefaultFixtures returns a small canned cluster used when no fixture file
+// is configured
+func DefaultFixtures() []Fixture {
+	var fixtures []Fixture
+	if err := json.Unmarshal(defaultFixtures, &fixtures); err != nil {
+		panic(fmt.Sprintf("invalid built-in fixtures: %v", err))
+	}
+	return fixtures
+}
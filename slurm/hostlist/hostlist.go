@@ -0,0 +1,390 @@
+// Package hostlist expands and compresses Slurm hostlist expressions such
+// as "gpu[001-016,020],cpu-a[1-3]" or "rack[1-2]-node[01-04]".
+package hostlist
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// MaxHosts limits the number of hosts a single expression may expand to
+const MaxHosts = 1 << 20
+
+// Expand returns the host names described by a hostlist expression, in
+// the order they appear. Several bracket groups in one name expand to
+// their cartesian product.
+func Expand(expr string) ([]string, error) {
+	expr = strings.TrimSpace(expr)
+	if expr == "" {
+		return nil, nil
+	}
+
+	terms, err := split(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	var hosts []string
+	for _, term := range terms {
+		expanded, err := expandTerm(term)
+		if err != nil {
+			return nil, err
+		}
+		if len(hosts)+len(expanded) > MaxHosts {
+			return nil, fmt.Errorf("hostlist %q expands to more than %d hosts", expr, MaxHosts)
+		}
+		hosts = append(hosts, expanded...)
+	}
+
+	return hosts, nil
+}
+
+// Count returns the number of hosts in a hostlist expression
+func Count(expr string) (int, error) {
+	hosts, err := Expand(expr)
+	if err != nil {
+		return 0, err
+	}
+	return len(hosts), nil
+}
+
+// Intersect returns the hosts of a that are also in b, in the order of a
+func Intersect(a, b string) ([]string, error) {
+	left, err := Expand(a)
+	if err != nil {
+		return nil, err
+	}
+	right, err := Expand(b)
+	if err != nil {
+		return nil, err
+	}
+
+	inRight := make(map[string]bool, len(right))
+	for _, host := range right {
+		inRight[host] = true
+	}
+
+	var result []string
+	seen := make(map[string]bool)
+	for _, host := range left {
+		if inRight[host] && !seen[host] {
+			seen[host] = true
+			result = append(result, host)
+		}
+	}
+	return result, nil
+}
+
+// Normalize expands and re-compresses an expression, which validates it and
+// folds duplicate or overlapping ranges
+func Normalize(expr string) (string, error) {
+	hosts, err := Expand(expr)
+	if err != nil {
+		return "", err
+	}
+	return Compress(hosts), nil
+}
+
+// Compress returns a compact hostlist expression for hosts. Duplicates are
+// dropped, and names that differ in a single number are merged into a
+// range; this is repeated for every numeric position, so grids of names
+// compress to multi-dimensional expressions.
+func Compress(hosts []string) string {
+	var terms []*term
+	seen := make(map[string]bool)
+	for _, host := range hosts {
+		host = strings.TrimSpace(host)
+		if host == "" || seen[host] {
+			continue
+		}
+		seen[host] = true
+		terms = append(terms, parseHost(host))
+	}
+
+	for changed := true; changed; {
+		changed = false
+		for dim := maxNumbers(terms) - 1; dim >= 0; dim-- {
+			merged := mergeOn(terms, dim)
+			if len(merged) < len(terms) {
+				changed = true
+			}
+			terms = merged
+		}
+	}
+
+	parts := make([]string, len(terms))
+	for i, t := range terms {
+		parts[i] = t.String()
+	}
+	return strings.Join(parts, ",")
+}
+
+// split splits an expression on commas outside brackets
+func split(expr string) ([]string, error) {
+	var terms []string
+	depth, start := 0, 0
+
+	for i, r := range expr {
+		switch r {
+		case '[':
+			if depth > 0 {
+				return nil, fmt.Errorf("nested brackets in hostlist %q", expr)
+			}
+			depth++
+		case ']':
+			if depth == 0 {
+				return nil, fmt.Errorf("unbalanced brackets in hostlist %q", expr)
+			}
+			depth--
+		case ',':
+			if depth == 0 {
+				terms = append(terms, expr[start:i])
+				start = i + 1
+			}
+		}
+	}
+
+	if depth != 0 {
+		return nil, fmt.Errorf("unbalanced brackets in hostlist %q", expr)
+	}
+
+	terms = append(terms, expr[start:])
+	for _, t := range terms {
+		if strings.TrimSpace(t) == "" {
+			return nil, fmt.Errorf("empty host name in hostlist %q", expr)
+		}
+	}
+	return terms, nil
+}
+
+// expandTerm expands a single term such as "rack[1-2]-node[01-03]"
+func expandTerm(t string) ([]string, error) {
+	open := strings.Index(t, "[")
+	if open < 0 {
+		return []string{t}, nil
+	}
+
+	close := strings.Index(t[open:], "]") + open
+	values, err := expandRanges(t[open+1 : close])
+	if err != nil {
+		return nil, fmt.Errorf("invalid range in %q: %v", t, err)
+	}
+
+	suffixes, err := expandTerm(t[close+1:])
+	if err != nil {
+		return nil, err
+	}
+	if len(values)*len(suffixes) > MaxHosts {
+		return nil, fmt.Errorf("hostlist %q expands to more than %d hosts", t, MaxHosts)
+	}
+
+	prefix := t[:open]
+	hosts := make([]string, 0, len(values)*len(suffixes))
+	for _, v := range values {
+		for _, s := range suffixes {
+			hosts = append(hosts, prefix+v+s)
+		}
+	}
+	return hosts, nil
+}
+
+// expandRanges expands the inside of a bracket group, e.g. "001-003,7"
+func expandRanges(spec string) ([]string, error) {
+	var values []string
+
+	for _, item := range strings.Split(spec, ",") {
+		bounds := strings.SplitN(item, "-", 2)
+		if !isDigits(bounds[0]) {
+			return nil, fmt.Errorf("%q is not a number", bounds[0])
+		}
+		if len(bounds) == 1 {
+			values = append(values, bounds[0])
+			continue
+		}
+		if !isDigits(bounds[1]) {
+			return nil, fmt.Errorf("%q is not a number", bounds[1])
+		}
+
+		lo, _ := strconv.Atoi(bounds[0])
+		hi, _ := strconv.Atoi(bounds[1])
+		if hi < lo {
+			return nil, fmt.Errorf("range %s is reversed", item)
+		}
+		if hi-lo >= MaxHosts {
+			return nil, fmt.Errorf("range %s is too large", item)
+		}
+
+		width := len(bounds[0])
+		for n := lo; n <= hi; n++ {
+			values = append(values, fmt.Sprintf("%0*d", width, n))
+		}
+	}
+
+	return values, nil
+}
+
+// isDigits reports whether s is a non-empty string of ASCII digits
+func isDigits(s string) bool {
+	if s == "" || len(s) > 9 {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// term is a host name, or a set of host names, split into literal text
+// and numeric positions
+type term struct {
+	literals []string   // literal text; one more element than numbers
+	numbers  [][]string // each numeric position holds a set of digit strings
+}
+
+// parseHost splits a host name into literal and numeric runs
+func parseHost(host string) *term {
+	t := &term{}
+	var literal strings.Builder
+
+	for i := 0; i < len(host); {
+		if host[i] < '0' || host[i] > '9' {
+			literal.WriteByte(host[i])
+			i++
+			continue
+		}
+		j := i
+		for j < len(host) && host[j] >= '0' && host[j] <= '9' {
+			j++
+		}
+		t.literals = append(t.literals, literal.String())
+		t.numbers = append(t.numbers, []string{host[i:j]})
+		literal.Reset()
+		i = j
+	}
+	t.literals = append(t.literals, literal.String())
+
+	return t
+}
+
+// key identifies a term with the numeric position dim left out
+func (t *term) key(dim int) string {
+	var b strings.Builder
+	for i, lit := range t.literals {
+		b.WriteString(lit)
+		b.WriteByte(0)
+		if i < len(t.numbers) {
+			if i == dim {
+				b.WriteString("*")
+			} else {
+				b.WriteString(strings.Join(t.numbers[i], ","))
+			}
+			b.WriteByte(0)
+		}
+	}
+	return b.String()
+}
+
+// mergeOn merges terms that only differ in numeric position dim
+func mergeOn(terms []*term, dim int) []*term {
+	var merged []*term
+	index := make(map[string]int)
+
+	for _, t := range terms {
+		if dim >= len(t.numbers) {
+			merged = append(merged, t)
+			continue
+		}
+
+		k := t.key(dim)
+		if i, exists := index[k]; exists {
+			merged[i].numbers[dim] = union(merged[i].numbers[dim], t.numbers[dim])
+			continue
+		}
+
+		index[k] = len(merged)
+		merged = append(merged, t)
+	}
+
+	return merged
+}
+
+// maxNumbers returns the largest number of numeric positions in terms
+func maxNumbers(terms []*term) int {
+	max := 0
+	for _, t := range terms {
+		if len(t.numbers) > max {
+			max = len(t.numbers)
+		}
+	}
+	return max
+}
+
+// union returns the sorted union of two sets of digit strings
+func union(a, b []string) []string {
+	seen := make(map[string]bool, len(a)+len(b))
+	var result []string
+	for _, s := range append(append([]string(nil), a...), b...) {
+		if !seen[s] {
+			seen[s] = true
+			result = append(result, s)
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		vi, _ := strconv.Atoi(result[i])
+		vj, _ := strconv.Atoi(result[j])
+		if vi != vj {
+			return vi < vj
+		}
+		return len(result[i]) < len(result[j])
+	})
+	return result
+}
+
+// String renders the term as a hostlist expression
+func (t *term) String() string {
+	var b strings.Builder
+	for i, lit := range t.literals {
+		b.WriteString(lit)
+		if i >= len(t.numbers) {
+			continue
+		}
+		if len(t.numbers[i]) == 1 {
+			b.WriteString(t.numbers[i][0])
+		} else {
+			b.WriteString("[" + formatRanges(t.numbers[i]) + "]")
+		}
+	}
+	return b.String()
+}
+
+// formatRanges renders sorted digit strings as "001-003,007". A range is
+// only formed when padding to the width of its first value reproduces
+// every member exactly.
+func formatRanges(values []string) string {
+	var parts []string
+
+	for i := 0; i < len(values); {
+		lo := values[i]
+		width := len(lo)
+		n, _ := strconv.Atoi(lo)
+
+		j := i + 1
+		for j < len(values) && values[j] == fmt.Sprintf("%0*d", width, n+(j-i)) {
+			j++
+		}
+
+		if j-i > 1 {
+			parts = append(parts, lo+"-"+values[j-1])
+		} else {
+			parts = append(parts, lo)
+		}
+		i = j
+	}
+
+	return strings.Join(parts, ",")
+}
@@ -0,0 +1,74 @@
+package slurm
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrInterrupted is returned by commands cancelled through Client.Interrupt
+var ErrInterrupted = errors.New("interrupted")
+
+// newContext creates the context for one command. A zero timeout means
+// the command may run indefinitely. The context is cancelled by Interrupt
+// until the returned cancel function is called.
+func (c *Client) newContext(timeout time.Duration) (context.Context, context.CancelFunc) {
+	var ctx context.Context
+	var cancel context.CancelFunc
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(context.Background(), timeout)
+	} else {
+		ctx, cancel = context.WithCancel(context.Background())
+	}
+
+	c.mu.Lock()
+	c.nextID++
+	id := c.nextID
+	if c.inflight == nil {
+		c.inflight = make(map[int]context.CancelFunc)
+	}
+	c.inflight[id] = cancel
+	c.mu.Unlock()
+
+	return ctx, func() {
+		c.mu.Lock()
+		delete(c.inflight, id)
+		c.mu.Unlock()
+		cancel()
+	}
+}
+
+// Interrupt cancels every command currently running through the client
+// and reports whether there was any
+func (c *Client) Interrupt() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, cancel := range c.inflight {
+		cancel()
+	}
+	return len(c.inflight) > 0
+}
+
+// Interruptible returns a context without timeout that Interrupt cancels,
+// for long-running work that spans several commands
+func (c *Client) Interruptible() (context.Context, context.CancelFunc) {
+	return c.newContext(0)
+}
+
+// InInteractive reports whether an interactive command owns the terminal.
+// Such commands receive Ctrl+C from the terminal themselves.
+func (c *Client) InInteractive() bool {
+	return c.interactive.Load() > 0
+}
+
+// contextError converts the error of a finished context into a command error
+func contextError(ctx context.Context, timeout time.Duration) error {
+	switch ctx.Err() {
+	case context.Canceled:
+		return ErrInterrupted
+	case context.DeadlineExceeded:
+		return errors.New("timed out after " + timeout.String())
+	}
+	return nil
+}
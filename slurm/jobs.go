@@ -0,0 +1,261 @@
+package slurm
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// jobFieldSeparator separates squeue fields. The job name is printed last
+// so that separators inside names are kept intact.
+const jobFieldSeparator = "|"
+
+// jobFormat is the squeue format used by ListJobs
+var jobFormat = strings.Join([]string{
+	"%i", "%T", "%P", "%u", "%D", "%C", "%l", "%V", "%S", "%e", "%M", "%N", "%Z", "%a", "%r", "%j",
+}, jobFieldSeparator)
+
+// jobFieldCount is the number of fields in jobFormat
+const jobFieldCount = 16
+
+// slurmTimeLayout is the timestamp layout used by Slurm commands
+const slurmTimeLayout = "2006-01-02T15:04:05"
+
+// JobFilter restricts the jobs returned by ListJobs. Empty fields match
+// every job; names may be glob patterns.
+type JobFilter struct {
+	Users      []string
+	JobIDs     []string
+	States     []string
+	Partitions []string
+	Accounts   []string
+	Names      []string
+
+	// Since and Until limit accounting queries to jobs that ran between
+	// them; the queue ignores them
+	Since time.Time
+	Until time.Time
+}
+
+// ListJobs returns the jobs in the queue matching filter
+func (c *Client) ListJobs(filter *JobFilter) ([]Job, error) {
+	if c.rest != nil {
+		var jobs []Job
+		err := c.restQuery(func(ctx context.Context) (err error) {
+			jobs, err = c.rest.ListJobs(ctx, filter)
+			return err
+		})
+		return jobs, err
+	}
+
+	if c.useJSON() {
+		jobs, err := c.listJobsJSON(filter)
+		if err != errJSONUnavailable {
+			return jobs, err
+		}
+	}
+
+	args := append([]string{"--noheader", "--format=" + jobFormat}, jobFilterArgs(filter)...)
+	result, err := c.Execute("squeue", args...)
+	if err != nil {
+		return nil, commandError(result, err)
+	}
+
+	jobs, err := ParseJobs(result.Output)
+	if err != nil {
+		return nil, err
+	}
+	return filterJobs(jobs, filter), nil
+}
+
+// jobFilterArgs converts a JobFilter to squeue options
+func jobFilterArgs(filter *JobFilter) []string {
+	var args []string
+	if filter == nil {
+		return args
+	}
+	if len(filter.Users) > 0 {
+		args = append(args, "-u", strings.Join(filter.Users, ","))
+	}
+	if len(filter.JobIDs) > 0 {
+		args = append(args, "-j", strings.Join(filter.JobIDs, ","))
+	}
+	if len(filter.States) > 0 {
+		args = append(args, "-t", strings.Join(filter.States, ","))
+	}
+	if len(filter.Partitions) > 0 {
+		args = append(args, "-p", strings.Join(filter.Partitions, ","))
+	}
+	if len(filter.Accounts) > 0 {
+		args = append(args, "-A", strings.Join(filter.Accounts, ","))
+	}
+	// squeue only matches exact names, so patterns are left to filterJobs
+	if len(filter.Names) > 0 && !hasGlob(filter.Names) {
+		args = append(args, "-n", strings.Join(filter.Names, ","))
+	}
+	return args
+}
+
+// hasGlob reports whether any of patterns contains glob metacharacters
+func hasGlob(patterns []string) bool {
+	for _, pattern := range patterns {
+		if strings.ContainsAny(pattern, "*?[") {
+			return true
+		}
+	}
+	return false
+}
+
+// jobStateCodes maps the compact state codes of squeue to state names
+var jobStateCodes = map[string]string{
+	"BF":  "BOOT_FAIL",
+	"CA":  JobStateCancelled,
+	"CD":  JobStateCompleted,
+	"CF":  "CONFIGURING",
+	"CG":  "COMPLETING",
+	"DL":  "DEADLINE",
+	"F":   JobStateFailed,
+	"NF":  "NODE_FAIL",
+	"OOM": "OUT_OF_MEMORY",
+	"PD":  JobStatePending,
+	"PR":  "PREEMPTED",
+	"R":   JobStateRunning,
+	"RQ":  "REQUEUED",
+	"S":   "SUSPENDED",
+	"TO":  JobStateTimeout,
+}
+
+// NormalizeJobState converts a state name or squeue state code such as
+// "pd" to the state name squeue prints
+func NormalizeJobState(state string) string {
+	state = strings.ToUpper(strings.TrimSpace(state))
+	if name, ok := jobStateCodes[state]; ok {
+		return name
+	}
+	return state
+}
+
+// jobIDPatterns match the messages in which sbatch, srun and salloc
+// report the ID of a new job
+var jobIDPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`Submitted batch job (\d+)`),
+	regexp.MustCompile(`job (\d+) queued and waiting for resources`),
+	regexp.MustCompile(`job (\d+) has been allocated resources`),
+	regexp.MustCompile(`Granted job allocation (\d+)`),
+	regexp.MustCompile(`^srun: jobid (\d+): nodes`),
+}
+
+// submittedPattern matches sbatch's report of a new job, which names the
+// cluster on multi-cluster setups
+var submittedPattern = regexp.MustCompile(`Submitted batch job (\d+)(?: on cluster (\S+))?`)
+
+// parsablePattern matches the output of sbatch --parsable: the job ID,
+// followed by the cluster on multi-cluster setups
+var parsablePattern = regexp.MustCompile(`(?m)^\s*(\d+)(?:;(\S+))?\s*$`)
+
+// ParseSubmittedJobID extracts the ID of a new job from the output of
+// sbatch, srun or salloc
+func ParseSubmittedJobID(output string) (string, bool) {
+	for _, pattern := range jobIDPatterns {
+		if m := pattern.FindStringSubmatch(output); m != nil {
+			return m[1], true
+		}
+	}
+	return "", false
+}
+
+// srunNoticePatterns match the srun messages that are shown without -v:
+// problems, and news about the job's place in the queue
+var srunNoticePatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)^srun: (error|warning|fatal)\b`),
+	regexp.MustCompile(`queued and waiting for resources|has been allocated resources`),
+	regexp.MustCompile(`step creation|Force Terminated|Job step aborted|Terminating|Cancelled|Requested partition configuration not available|Exceeded`),
+}
+
+// IsSrunVerbose reports whether a line of srun's stderr is progress that
+// srun prints only when run with -v
+func IsSrunVerbose(line string) bool {
+	if !strings.HasPrefix(line, "srun: ") {
+		return false
+	}
+	for _, pattern := range srunNoticePatterns {
+		if pattern.MatchString(line) {
+			return false
+		}
+	}
+	return true
+}
+
+// ParseSubmittedJob extracts the ID and cluster of a new job from the
+// output of sbatch, with or without --parsable. The cluster is empty
+// unless sbatch named one.
+func ParseSubmittedJob(output string) (jobID, cluster string, ok bool) {
+	if m := submittedPattern.FindStringSubmatch(output); m != nil {
+		return m[1], m[2], true
+	}
+	if m := parsablePattern.FindStringSubmatch(output); m != nil {
+		return m[1], m[2], true
+	}
+	return "", "", false
+}
+
+// ParseJobs parses squeue output produced with jobFormat
+func ParseJobs(output string) ([]Job, error) {
+	var jobs []Job
+
+	for _, line := range strings.Split(output, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		fields := strings.SplitN(line, jobFieldSeparator, jobFieldCount)
+		if len(fields) != jobFieldCount {
+			return nil, fmt.Errorf("unexpected squeue line: %q", line)
+		}
+
+		job := Job{
+			ID:        fields[0],
+			State:     fields[1],
+			Partition: fields[2],
+			User:      fields[3],
+			Nodes:     atoi(fields[4]),
+			CPUs:      atoi(fields[5]),
+			TimeLimit: fields[6],
+			TimeUsed:  fields[10],
+			NodeList:  fields[11],
+			WorkDir:   fields[12],
+			Account:   fields[13],
+			Reason:    fields[14],
+			Name:      fields[15],
+		}
+		job.SubmitTime = parseSlurmTime(fields[7])
+		job.StartTime = parseSlurmTime(fields[8])
+		job.EndTime = parseSlurmTime(fields[9])
+
+		jobs = append(jobs, job)
+	}
+
+	return jobs, nil
+}
+
+// parseSlurmTime parses a Slurm timestamp, returning the zero time for
+// values such as "N/A" or "Unknown"
+func parseSlurmTime(value string) time.Time {
+	t, err := time.ParseInLocation(slurmTimeLayout, strings.TrimSpace(value), time.Local)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+// atoi parses an integer, returning 0 for empty or invalid values
+func atoi(value string) int {
+	n, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil {
+		return 0
+	}
+	return n
+}
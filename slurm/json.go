@@ -0,0 +1,165 @@
+package slurm
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// errJSONUnavailable reports that a --json query is not supported and the
+// format-string query should be used instead
+var errJSONUnavailable = errors.New("json output unavailable")
+
+// queryJSON runs a command with --json output and decodes it into out.
+// When the tool rejects the option or prints something that is not JSON,
+// JSON queries are disabled for the session and errJSONUnavailable is
+// returned.
+func (c *Client) queryJSON(out interface{}, command string, args ...string) error {
+	result, err := c.Execute(command, args...)
+	if err != nil {
+		if result != nil && isUnsupportedOption(result.Error) {
+			c.disableJSON()
+			return errJSONUnavailable
+		}
+		return commandError(result, err)
+	}
+
+	if err := json.Unmarshal([]byte(result.Output), out); err != nil {
+		c.disableJSON()
+		return errJSONUnavailable
+	}
+	return nil
+}
+
+// isUnsupportedOption reports whether stderr says --json is not supported,
+// either as an unknown option or for lack of a data_parser plugin. Other
+// errors, including those of other plugins, are real failures.
+func isUnsupportedOption(stderr string) bool {
+	stderr = strings.ToLower(stderr)
+	if strings.Contains(stderr, "data_parser") {
+		return true
+	}
+	return strings.Contains(stderr, "--json") &&
+		(strings.Contains(stderr, "unrecognized option") ||
+			strings.Contains(stderr, "invalid option") ||
+			strings.Contains(stderr, "unknown option") ||
+			strings.Contains(stderr, "not supported"))
+}
+
+// commandError returns the error printed by a failed command, or err when
+// the command printed nothing
+func commandError(result *CommandResult, err error) error {
+	if result != nil && strings.TrimSpace(result.Error) != "" {
+		return fmt.Errorf("%s", strings.TrimSpace(result.Error))
+	}
+	return err
+}
+
+// listJobsJSON lists jobs using squeue --json. Older releases ignore the
+// filter options in JSON mode, so the filter is applied again here.
+func (c *Client) listJobsJSON(filter *JobFilter) ([]Job, error) {
+	args := append([]string{"--json"}, jobFilterArgs(filter)...)
+
+	var resp apiJobsResponse
+	if err := c.queryJSON(&resp, "squeue", args...); err != nil {
+		return nil, err
+	}
+	if err := resp.err(); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	jobs := make([]Job, 0, len(resp.Jobs))
+	for _, j := range resp.Jobs {
+		jobs = append(jobs, j.toJob(now))
+	}
+	return filterJobs(jobs, filter), nil
+}
+
+// listNodesJSON lists nodes using scontrol --json. The layout of sinfo
+// --json differs between Slurm releases, while scontrol's matches the
+// slurmrestd node model.
+func (c *Client) listNodesJSON() ([]Node, error) {
+	var resp apiNodesResponse
+	if err := c.queryJSON(&resp, "scontrol", "--json", "show", "nodes"); err != nil {
+		return nil, err
+	}
+	if err := resp.err(); err != nil {
+		return nil, err
+	}
+
+	nodes := make([]Node, 0, len(resp.Nodes))
+	for _, n := range resp.Nodes {
+		nodes = append(nodes, n.toNode())
+	}
+	return nodes, nil
+}
+
+// listPartitionsJSON lists partitions using scontrol --json
+func (c *Client) listPartitionsJSON() ([]Partition, error) {
+	var resp apiPartitionsResponse
+	if err := c.queryJSON(&resp, "scontrol", "--json", "show", "partitions"); err != nil {
+		return nil, err
+	}
+	if err := resp.err(); err != nil {
+		return nil, err
+	}
+
+	nodes, err := c.listNodesJSON()
+	if err != nil {
+		return nil, err
+	}
+
+	partitions := make([]Partition, 0, len(resp.Partitions))
+	for _, p := range resp.Partitions {
+		partitions = append(partitions, p.toPartition(nodes))
+	}
+	return partitions, nil
+}
+
+// queryAccountingJSON runs sacct --json. The JSON output always includes
+// the steps of each job, so -X is not passed.
+func (c *Client) queryAccountingJSON(args ...string) ([]apiAccountingJob, error) {
+	var resp apiAccountingResponse
+	if err := c.queryJSON(&resp, "sacct", append([]string{"--json"}, args...)...); err != nil {
+		return nil, err
+	}
+	if err := resp.err(); err != nil {
+		return nil, err
+	}
+	return resp.Jobs, nil
+}
+
+// listAccountingJSON lists accounting records using sacct --json
+func (c *Client) listAccountingJSON(filter *JobFilter) ([]JobRecord, error) {
+	jobs, err := c.queryAccountingJSON(accountingFilterArgs(filter)...)
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]JobRecord, 0, len(jobs))
+	for _, j := range jobs {
+		records = append(records, j.toRecord())
+	}
+	return filterRecords(records, filter), nil
+}
+
+// jobStepsJSON returns the records of a job and its steps using sacct
+// --json, in the order the text output lists them
+func (c *Client) jobStepsJSON(jobID string) ([]JobRecord, error) {
+	jobs, err := c.queryAccountingJSON("-j", jobID)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []JobRecord
+	for _, j := range jobs {
+		records = append(records, j.toRecord())
+		for _, step := range j.Steps {
+			records = append(records, step.toRecord())
+		}
+	}
+	return records, nil
+}
@@ -0,0 +1,322 @@
+package slurm
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"slsh/slurm/hostlist"
+)
+
+// nodeFormat is the sinfo format used by ListNodes. The reason is printed
+// last so that separators inside it are kept intact.
+var nodeFormat = strings.Join([]string{"%N", "%T", "%P", "%C", "%m", "%f", "%E"}, jobFieldSeparator)
+
+// nodeFieldCount is the number of fields in nodeFormat
+const nodeFieldCount = 7
+
+// partitionFormat is the sinfo format used by ListPartitions
+var partitionFormat = strings.Join([]string{"%P", "%a", "%l", "%L", "%D", "%s", "%C", "%N"}, jobFieldSeparator)
+
+// partitionFieldCount is the number of fields in partitionFormat
+const partitionFieldCount = 8
+
+// Node state flags reported by sinfo as state suffixes
+const (
+	NodeFlagNotResponding = "NOT_RESPONDING"
+	NodeFlagPoweredDown   = "POWERED_DOWN"
+	NodeFlagPoweringUp    = "POWERING_UP"
+	NodeFlagDrain         = "DRAIN"
+)
+
+// ListNodes returns all nodes known to the cluster. Nodes that belong to
+// several partitions are returned once with the partitions joined by commas.
+func (c *Client) ListNodes() ([]Node, error) {
+	if c.rest != nil {
+		var nodes []Node
+		err := c.restQuery(func(ctx context.Context) (err error) {
+			nodes, err = c.rest.ListNodes(ctx)
+			return err
+		})
+		return nodes, err
+	}
+
+	if c.useJSON() {
+		nodes, err := c.listNodesJSON()
+		if err != errJSONUnavailable {
+			return nodes, err
+		}
+	}
+
+	result, err := c.Execute("sinfo", "-N", "--noheader", "--format="+nodeFormat)
+	if err != nil {
+		return nil, commandError(result, err)
+	}
+
+	return ParseNodes(result.Output)
+}
+
+// ListPartitions returns all partitions of the cluster
+func (c *Client) ListPartitions() ([]Partition, error) {
+	if c.rest != nil {
+		var partitions []Partition
+		err := c.restQuery(func(ctx context.Context) (err error) {
+			partitions, err = c.rest.ListPartitions(ctx)
+			return err
+		})
+		return partitions, err
+	}
+
+	if c.useJSON() {
+		partitions, err := c.listPartitionsJSON()
+		if err != errJSONUnavailable {
+			return partitions, err
+		}
+	}
+
+	result, err := c.Execute("sinfo", "--noheader", "--format="+partitionFormat)
+	if err != nil {
+		return nil, commandError(result, err)
+	}
+
+	return ParsePartitions(result.Output)
+}
+
+// ParseNodes parses sinfo output produced with nodeFormat
+func ParseNodes(output string) ([]Node, error) {
+	var nodes []Node
+	index := make(map[string]int)
+
+	for _, line := range strings.Split(output, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		fields := strings.SplitN(line, jobFieldSeparator, nodeFieldCount)
+		if len(fields) != nodeFieldCount {
+			return nil, fmt.Errorf("unexpected sinfo line: %q", line)
+		}
+
+		partition := strings.TrimSuffix(fields[2], "*")
+		if i, exists := index[fields[0]]; exists {
+			nodes[i].Partition += "," + partition
+			continue
+		}
+
+		node := Node{
+			Name:      fields[0],
+			Partition: partition,
+			Memory:    atoi(fields[4]),
+			Features:  nullable(fields[5]),
+			Reason:    nullable(fields[6]),
+		}
+		node.State, node.Flags = parseNodeState(fields[1])
+		node.CPUsAlloc, node.CPUsIdle, node.CPUsOther, node.CPUs = parseCPUCounts(fields[3])
+
+		index[node.Name] = len(nodes)
+		nodes = append(nodes, node)
+	}
+
+	return nodes, nil
+}
+
+// ParsePartitions parses sinfo output produced with partitionFormat
+func ParsePartitions(output string) ([]Partition, error) {
+	var partitions []Partition
+	index := make(map[string]int)
+
+	for _, line := range strings.Split(output, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		fields := strings.SplitN(line, jobFieldSeparator, partitionFieldCount)
+		if len(fields) != partitionFieldCount {
+			return nil, fmt.Errorf("unexpected sinfo line: %q", line)
+		}
+
+		name := strings.TrimSuffix(fields[0], "*")
+		alloc, idle, other, total := parseCPUCounts(fields[6])
+		members := expandNodeList(fields[7])
+
+		// sinfo may split a partition over several lines
+		if i, exists := index[name]; exists {
+			p := &partitions[i]
+			p.TotalNodes += atoi(fields[4])
+			p.CPUsAlloc += alloc
+			p.CPUsIdle += idle
+			p.CPUsOther += other
+			p.CPUs += total
+			p.Nodes = append(p.Nodes, members...)
+			p.NodeList = hostlist.Compress(p.Nodes)
+			continue
+		}
+
+		partition := Partition{
+			Name:        name,
+			State:       strings.ToUpper(fields[1]),
+			Default:     strings.HasSuffix(fields[0], "*"),
+			MaxTime:     fields[2],
+			DefaultTime: nullable(fields[3]),
+			MaxNodes:    parseMaxNodes(fields[5]),
+			TotalNodes:  atoi(fields[4]),
+			CPUsAlloc:   alloc,
+			CPUsIdle:    idle,
+			CPUsOther:   other,
+			CPUs:        total,
+			NodeList:    nullable(fields[7]),
+			Nodes:       members,
+		}
+
+		index[name] = len(partitions)
+		partitions = append(partitions, partition)
+	}
+
+	return partitions, nil
+}
+
+// nodeStateMarkers maps sinfo state suffixes to node flags. Maintenance,
+// reboot and similar markers carry no flag of their own.
+var nodeStateMarkers = map[byte]string{
+	'*': NodeFlagNotResponding,
+	'~': NodeFlagPoweredDown,
+	'#': NodeFlagPoweringUp,
+	'$': "",
+	'@': "",
+	'^': "",
+	'-': "",
+	'!': "",
+	'%': "",
+}
+
+// parseNodeState splits a sinfo node state such as "idle~" or
+// "mixed+drain" into a normalized base state and its flags
+func parseNodeState(raw string) (string, []string) {
+	state := strings.ToLower(strings.TrimSpace(raw))
+	var flags []string
+
+	for len(state) > 0 {
+		flag, isMarker := nodeStateMarkers[state[len(state)-1]]
+		if !isMarker {
+			break
+		}
+		if flag != "" {
+			flags = append(flags, flag)
+		}
+		state = state[:len(state)-1]
+	}
+
+	parts := strings.Split(state, "+")
+	return normalizeNodeState(parts[0], append(flags, parts[1:]...))
+}
+
+// nodeFlagOrder fixes the order of well-known node flags
+var nodeFlagOrder = map[string]int{
+	NodeFlagNotResponding: 1,
+	NodeFlagPoweredDown:   2,
+	NodeFlagPoweringUp:    3,
+	NodeFlagDrain:         4,
+}
+
+// normalizeNodeState maps a Slurm base state and its flags to one of the
+// NodeState constants. Drained and draining nodes are reported as DRAIN
+// with the DRAIN flag set, whichever way Slurm spelled it.
+func normalizeNodeState(base string, extra []string) (string, []string) {
+	var flags []string
+	for _, flag := range extra {
+		flag = strings.ToUpper(strings.TrimSpace(flag))
+		if flag != "" && !hasFlag(flags, flag) {
+			flags = append(flags, flag)
+		}
+	}
+
+	state := strings.ToUpper(strings.TrimSpace(base))
+	switch state {
+	case "ALLOCATED", "ALLOC":
+		state = NodeStateAlloc
+	case "MIXED", "MIX":
+		state = NodeStateMixed
+	case "DRAINED", "DRAINING", "DRNG", "DRAIN":
+		state = NodeStateDrain
+	case "RESERVED", "RESV":
+		state = NodeStateReserved
+	case "FAILING", "FAIL":
+		state = "FAIL"
+	case "COMPLETING", "COMP":
+		state = "COMPLETING"
+	}
+
+	if hasFlag(flags, NodeFlagDrain) {
+		state = NodeStateDrain
+	} else if state == NodeStateDrain {
+		flags = append(flags, NodeFlagDrain)
+	}
+
+	sort.SliceStable(flags, func(i, j int) bool {
+		return flagRank(flags[i]) < flagRank(flags[j])
+	})
+	return state, flags
+}
+
+// flagRank returns the sort position of a node flag
+func flagRank(flag string) int {
+	if rank, known := nodeFlagOrder[flag]; known {
+		return rank
+	}
+	return len(nodeFlagOrder) + 1
+}
+
+// hasFlag reports whether flags contains flag
+func hasFlag(flags []string, flag string) bool {
+	for _, f := range flags {
+		if f == flag {
+			return true
+		}
+	}
+	return false
+}
+
+// parseCPUCounts parses a sinfo %C value (allocated/idle/other/total)
+func parseCPUCounts(value string) (alloc, idle, other, total int) {
+	parts := strings.Split(strings.TrimSpace(value), "/")
+	if len(parts) != 4 {
+		return 0, 0, 0, 0
+	}
+	return atoi(parts[0]), atoi(parts[1]), atoi(parts[2]), atoi(parts[3])
+}
+
+// parseMaxNodes parses a sinfo %s job size such as "1-infinite" or "1-16",
+// returning 0 when the size is unlimited
+func parseMaxNodes(value string) int {
+	value = strings.TrimSpace(value)
+	if i := strings.LastIndex(value, "-"); i >= 0 {
+		value = value[i+1:]
+	}
+	return atoi(value)
+}
+
+// nullable returns an empty string for Slurm's "(null)" and "none" markers
+func nullable(value string) string {
+	value = strings.TrimSpace(value)
+	switch strings.ToLower(value) {
+	case "(null)", "none", "n/a":
+		return ""
+	}
+	return value
+}
+
+// expandNodeList expands a node list, keeping it as a single name when it
+// is not a valid hostlist expression
+func expandNodeList(expr string) []string {
+	expr = nullable(expr)
+	if expr == "" {
+		return nil
+	}
+
+	hosts, err := hostlist.Expand(expr)
+	if err != nil {
+		return []string{expr}
+	}
+	return hosts
+}
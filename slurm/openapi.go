@@ -0,0 +1,626 @@
+package slurm
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"slsh/slurm/hostlist"
+)
+
+// The types in this file decode the data model shared by slurmrestd and
+// the --json output of squeue, sinfo and sacct. They accept both the
+// current encoding and the plainer one of older API versions.
+
+// noVal is a number that may be unset or infinite. Older API versions
+// encode it as a plain number.
+type noVal struct {
+	Set      bool
+	Infinite bool
+	Number   int64
+}
+
+// UnmarshalJSON decodes either {"set":..,"infinite":..,"number":..} or a number
+func (n *noVal) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 || string(data) == "null" {
+		return nil
+	}
+
+	if data[0] == '{' {
+		var v struct {
+			Set      bool        `json:"set"`
+			Infinite bool        `json:"infinite"`
+			Number   json.Number `json:"number"`
+		}
+		if err := json.Unmarshal(data, &v); err != nil {
+			return err
+		}
+		n.Set, n.Infinite = v.Set, v.Infinite
+		n.Number, _ = parseNumber(v.Number)
+		return nil
+	}
+
+	var num json.Number
+	if err := json.Unmarshal(data, &num); err != nil {
+		return err
+	}
+	value, err := parseNumber(num)
+	if err != nil {
+		return err
+	}
+	n.Set, n.Number = true, value
+	return nil
+}
+
+// parseNumber converts a JSON number to an integer, truncating fractions
+func parseNumber(num json.Number) (int64, error) {
+	if num == "" {
+		return 0, nil
+	}
+	if i, err := num.Int64(); err == nil {
+		return i, nil
+	}
+	f, err := num.Float64()
+	return int64(f), err
+}
+
+// Int returns the number, or 0 when unset or infinite
+func (n noVal) Int() int {
+	if !n.Set || n.Infinite {
+		return 0
+	}
+	return int(n.Number)
+}
+
+// Time converts a Unix timestamp to a time, returning the zero time when unset
+func (n noVal) Time() time.Time {
+	if !n.Set || n.Infinite || n.Number <= 0 {
+		return time.Time{}
+	}
+	return time.Unix(n.Number, 0)
+}
+
+// stringList is a list of strings that older API versions encode as a
+// single comma separated string
+type stringList []string
+
+// UnmarshalJSON decodes either a JSON array or a comma separated string
+func (l *stringList) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 || string(data) == "null" {
+		*l = nil
+		return nil
+	}
+
+	if data[0] == '"' {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		*l = nil
+		if s != "" {
+			*l = strings.Split(s, ",")
+		}
+		return nil
+	}
+
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	*l = list
+	return nil
+}
+
+// apiError is an entry of the errors array of a response
+type apiError struct {
+	Error       string `json:"error"`
+	Description string `json:"description"`
+	ErrorNumber int    `json:"error_number"`
+}
+
+// apiResponse holds the errors common to every response
+type apiResponse struct {
+	Errors []apiError `json:"errors"`
+}
+
+// err returns the first error of the response, if any
+func (r apiResponse) err() error {
+	for _, e := range r.Errors {
+		msg := e.Description
+		if msg == "" {
+			msg = e.Error
+		}
+		if msg != "" {
+			return fmt.Errorf("%s", msg)
+		}
+	}
+	return nil
+}
+
+// apiJob is a job as reported by slurmrestd and squeue --json
+type apiJob struct {
+	JobID           int64      `json:"job_id"`
+	ArrayJobID      noVal      `json:"array_job_id"`
+	ArrayTaskID     noVal      `json:"array_task_id"`
+	ArrayTaskString string     `json:"array_task_string"`
+	Name            string     `json:"name"`
+	UserName        string     `json:"user_name"`
+	Account         string     `json:"account"`
+	JobState        stringList `json:"job_state"`
+	StateReason     string     `json:"state_reason"`
+	Partition       string     `json:"partition"`
+	NodeCount       noVal      `json:"node_count"`
+	CPUs            noVal      `json:"cpus"`
+	TimeLimit       noVal      `json:"time_limit"`
+	SubmitTime      noVal      `json:"submit_time"`
+	StartTime       noVal      `json:"start_time"`
+	EndTime         noVal      `json:"end_time"`
+	Nodes           string     `json:"nodes"`
+	WorkDir         string     `json:"current_working_directory"`
+	Command         string     `json:"command"`
+}
+
+// apiJobsResponse is the body of a job listing
+type apiJobsResponse struct {
+	apiResponse
+	Jobs []apiJob `json:"jobs"`
+}
+
+// toJob converts an API job to the squeue view of a job
+func (j apiJob) toJob(now time.Time) Job {
+	job := Job{
+		ID:         strconv.FormatInt(j.JobID, 10),
+		Name:       j.Name,
+		User:       j.UserName,
+		Account:    j.Account,
+		Partition:  j.Partition,
+		Nodes:      j.NodeCount.Int(),
+		CPUs:       j.CPUs.Int(),
+		TimeLimit:  formatLimit(j.TimeLimit, "UNLIMITED"),
+		SubmitTime: j.SubmitTime.Time(),
+		StartTime:  j.StartTime.Time(),
+		EndTime:    j.EndTime.Time(),
+		NodeList:   j.Nodes,
+		WorkDir:    j.WorkDir,
+		Command:    j.Command,
+		Reason:     j.StateReason,
+	}
+
+	if len(j.JobState) > 0 {
+		job.State = strings.ToUpper(j.JobState[0])
+	}
+
+	if arrayID := j.ArrayJobID.Int(); arrayID > 0 {
+		switch {
+		case j.ArrayTaskString != "":
+			job.ID = fmt.Sprintf("%d_[%s]", arrayID, j.ArrayTaskString)
+		case j.ArrayTaskID.Set && !j.ArrayTaskID.Infinite:
+			job.ID = fmt.Sprintf("%d_%d", arrayID, j.ArrayTaskID.Number)
+		}
+	}
+
+	// squeue reports the run time, which the API leaves to the caller
+	switch {
+	case job.StartTime.IsZero() || job.StartTime.After(now):
+		job.TimeUsed = FormatSlurmDuration(0)
+	case job.State == JobStateRunning || job.EndTime.IsZero():
+		job.TimeUsed = FormatSlurmDuration(now.Sub(job.StartTime))
+	default:
+		job.TimeUsed = FormatSlurmDuration(job.EndTime.Sub(job.StartTime))
+	}
+
+	return job
+}
+
+// formatLimit formats a limit in minutes the way the CLI tools print it
+func formatLimit(limit noVal, infinite string) string {
+	if limit.Infinite {
+		return infinite
+	}
+	if !limit.Set {
+		return ""
+	}
+	return FormatSlurmDuration(time.Duration(limit.Number) * time.Minute)
+}
+
+// apiNode is a node as reported by slurmrestd and sinfo --json
+type apiNode struct {
+	Name       string     `json:"name"`
+	State      stringList `json:"state"`
+	StateFlags stringList `json:"state_flags"`
+	CPUs       int        `json:"cpus"`
+	AllocCPUs  int        `json:"alloc_cpus"`
+	RealMemory int        `json:"real_memory"`
+	Partitions stringList `json:"partitions"`
+	Features   stringList `json:"features"`
+	Reason     string     `json:"reason"`
+}
+
+// apiNodesResponse is the body of a node listing
+type apiNodesResponse struct {
+	apiResponse
+	Nodes []apiNode `json:"nodes"`
+}
+
+// toNode converts an API node to the sinfo view of a node
+func (n apiNode) toNode() Node {
+	var base string
+	var flags []string
+	if len(n.State) > 0 {
+		base = n.State[0]
+		flags = append(flags, n.State[1:]...)
+	}
+	flags = append(flags, n.StateFlags...)
+
+	node := Node{
+		Name:      n.Name,
+		CPUs:      n.CPUs,
+		Memory:    n.RealMemory,
+		Partition: strings.Join(n.Partitions, ","),
+		Features:  strings.Join(n.Features, ","),
+		Reason:    nullable(n.Reason),
+	}
+	node.State, node.Flags = normalizeNodeState(base, flags)
+
+	// sinfo counts the CPUs of unavailable nodes as "other"
+	switch node.State {
+	case NodeStateDown, NodeStateDrain, "FAIL":
+		node.CPUsOther = n.CPUs
+	default:
+		node.CPUsAlloc = n.AllocCPUs
+		node.CPUsIdle = n.CPUs - n.AllocCPUs
+	}
+
+	return node
+}
+
+// apiPartition is a partition as reported by slurmrestd and sinfo --json
+type apiPartition struct {
+	Name  string     `json:"name"`
+	Flags stringList `json:"flags"`
+	Nodes struct {
+		Configured string `json:"configured"`
+		Total      int    `json:"total"`
+	} `json:"nodes"`
+	Partition struct {
+		State stringList `json:"state"`
+	} `json:"partition"`
+	Maximums struct {
+		Time  noVal `json:"time"`
+		Nodes noVal `json:"nodes"`
+	} `json:"maximums"`
+	Defaults struct {
+		Time noVal `json:"time"`
+	} `json:"defaults"`
+}
+
+// apiPartitionsResponse is the body of a partition listing
+type apiPartitionsResponse struct {
+	apiResponse
+	Partitions []apiPartition `json:"partitions"`
+}
+
+// toPartition converts an API partition to the sinfo view of a partition.
+// CPU counts are summed from nodes, which the API reports separately.
+func (p apiPartition) toPartition(nodes []Node) Partition {
+	partition := Partition{
+		Name:        p.Name,
+		MaxTime:     formatLimit(p.Maximums.Time, "infinite"),
+		DefaultTime: formatLimit(p.Defaults.Time, ""),
+		MaxNodes:    p.Maximums.Nodes.Int(),
+		TotalNodes:  p.Nodes.Total,
+		NodeList:    nullable(p.Nodes.Configured),
+		Nodes:       expandNodeList(p.Nodes.Configured),
+	}
+
+	if len(p.Partition.State) > 0 {
+		partition.State = strings.ToUpper(p.Partition.State[0])
+	}
+	for _, flag := range p.Flags {
+		if strings.EqualFold(flag, "DEFAULT") {
+			partition.Default = true
+		}
+	}
+	if partition.NodeList != "" {
+		partition.NodeList = hostlist.Compress(partition.Nodes)
+	}
+
+	members := make(map[string]bool, len(partition.Nodes))
+	for _, name := range partition.Nodes {
+		members[name] = true
+	}
+	for _, node := range nodes {
+		if members[node.Name] {
+			partition.CPUsAlloc += node.CPUsAlloc
+			partition.CPUsIdle += node.CPUsIdle
+			partition.CPUsOther += node.CPUsOther
+			partition.CPUs += node.CPUs
+		}
+	}
+
+	return partition
+}
+
+// apiExitCode is the exit status of a job or step as reported by sacct
+type apiExitCode struct {
+	ReturnCode noVal `json:"return_code"`
+	Signal     struct {
+		ID noVal `json:"id"`
+	} `json:"signal"`
+}
+
+// apiTRES is an entry of a trackable resource list
+type apiTRES struct {
+	Type  string `json:"type"`
+	Name  string `json:"name"`
+	Count int64  `json:"count"`
+}
+
+// formatTRES formats resources the way sacct prints AllocTRES, as in
+// "cpu=4,mem=16G,node=1,gres/gpu=1". Memory is counted in megabytes.
+func formatTRES(list []apiTRES) string {
+	parts := make([]string, 0, len(list))
+	for _, t := range list {
+		name := t.Type
+		if t.Name != "" {
+			name += "/" + t.Name
+		}
+		value := strconv.FormatInt(t.Count, 10)
+		if t.Type == "mem" {
+			if t.Count%1024 == 0 {
+				value = fmt.Sprintf("%dG", t.Count/1024)
+			} else {
+				value += "M"
+			}
+		}
+		parts = append(parts, name+"="+value)
+	}
+	return strings.Join(parts, ",")
+}
+
+// apiStepID is the ID of a step, which older API versions encode as
+// {"job_id":..,"step_id":..} rather than as "1001.batch"
+type apiStepID string
+
+// UnmarshalJSON decodes either a string or a job and step ID pair
+func (id *apiStepID) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '"' {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		*id = apiStepID(s)
+		return nil
+	}
+
+	var v struct {
+		JobID  noVal           `json:"job_id"`
+		StepID json.RawMessage `json:"step_id"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	step := strings.Trim(string(v.StepID), `"`)
+	*id = apiStepID(fmt.Sprintf("%d.%s", v.JobID.Number, step))
+	return nil
+}
+
+// apiStep is a job step as reported by sacct --json
+type apiStep struct {
+	Step struct {
+		ID   apiStepID `json:"id"`
+		Name string    `json:"name"`
+	} `json:"step"`
+	State    stringList  `json:"state"`
+	ExitCode apiExitCode `json:"exit_code"`
+	Time     struct {
+		Elapsed noVal `json:"elapsed"`
+		Start   noVal `json:"start"`
+		End     noVal `json:"end"`
+	} `json:"time"`
+	Nodes struct {
+		Range string `json:"range"`
+	} `json:"nodes"`
+	TRES struct {
+		Allocated []apiTRES `json:"allocated"`
+	} `json:"tres"`
+}
+
+// apiAccountingJob is a job as reported by sacct --json and slurmdbd
+type apiAccountingJob struct {
+	JobID            int64  `json:"job_id"`
+	Name             string `json:"name"`
+	User             string `json:"user"`
+	Account          string `json:"account"`
+	Partition        string `json:"partition"`
+	Nodes            string `json:"nodes"`
+	WorkingDirectory string `json:"working_directory"`
+	StdOut           string `json:"stdout"`
+	StdErr           string `json:"stderr"`
+	Array            struct {
+		JobID noVal  `json:"job_id"`
+		Task  noVal  `json:"task_id"`
+		Tasks string `json:"task"`
+	} `json:"array"`
+	State struct {
+		Current stringList `json:"current"`
+		Reason  string     `json:"reason"`
+	} `json:"state"`
+	ExitCode apiExitCode `json:"exit_code"`
+	Time     struct {
+		Elapsed    noVal `json:"elapsed"`
+		Submission noVal `json:"submission"`
+		Start      noVal `json:"start"`
+		End        noVal `json:"end"`
+		Limit      noVal `json:"limit"`
+	} `json:"time"`
+	TRES struct {
+		Allocated []apiTRES `json:"allocated"`
+	} `json:"tres"`
+	Steps []apiStep `json:"steps"`
+}
+
+// apiAccountingResponse is the body of an accounting query
+type apiAccountingResponse struct {
+	apiResponse
+	Jobs []apiAccountingJob `json:"jobs"`
+}
+
+// toRecord converts an accounting job to the sacct view of a job
+func (j apiAccountingJob) toRecord() JobRecord {
+	record := JobRecord{
+		ID:         strconv.FormatInt(j.JobID, 10),
+		Name:       j.Name,
+		User:       j.User,
+		Account:    j.Account,
+		Partition:  j.Partition,
+		ExitCode:   j.ExitCode.ReturnCode.Int(),
+		Signal:     j.ExitCode.Signal.ID.Int(),
+		Elapsed:    time.Duration(j.Time.Elapsed.Int()) * time.Second,
+		SubmitTime: j.Time.Submission.Time(),
+		StartTime:  j.Time.Start.Time(),
+		EndTime:    j.Time.End.Time(),
+		NodeList:   nullable(j.Nodes),
+		CPUs:       tresCount(j.TRES.Allocated, "cpu"),
+		TimeLimit:  formatLimit(j.Time.Limit, "UNLIMITED"),
+		TRES:       formatTRES(j.TRES.Allocated),
+		WorkDir:    j.WorkingDirectory,
+		Reason:     j.State.Reason,
+		StdOut:     j.StdOut,
+		StdErr:     j.StdErr,
+	}
+	if len(j.State.Current) > 0 {
+		record.State = accountingState(strings.ToUpper(j.State.Current[0]))
+	}
+	if record.NodeList == "None assigned" {
+		record.NodeList = ""
+	}
+	if arrayID := j.Array.JobID.Int(); arrayID > 0 && j.Array.Task.Set && !j.Array.Task.Infinite {
+		record.ID = fmt.Sprintf("%d_%d", arrayID, j.Array.Task.Number)
+	} else if arrayID > 0 && j.Array.Tasks != "" {
+		// The pending tasks of an array, as sacct prints them
+		record.ID = fmt.Sprintf("%d_[%s]", arrayID, j.Array.Tasks)
+	}
+	return record
+}
+
+// toRecord converts an accounting step to a record
+func (s apiStep) toRecord() JobRecord {
+	record := JobRecord{
+		ID:        string(s.Step.ID),
+		Name:      s.Step.Name,
+		ExitCode:  s.ExitCode.ReturnCode.Int(),
+		Signal:    s.ExitCode.Signal.ID.Int(),
+		Elapsed:   time.Duration(s.Time.Elapsed.Int()) * time.Second,
+		StartTime: s.Time.Start.Time(),
+		EndTime:   s.Time.End.Time(),
+		NodeList:  nullable(s.Nodes.Range),
+		CPUs:      tresCount(s.TRES.Allocated, "cpu"),
+		TRES:      formatTRES(s.TRES.Allocated),
+	}
+	if len(s.State) > 0 {
+		record.State = accountingState(strings.ToUpper(s.State[0]))
+	}
+	return record
+}
+
+// tresCount returns the count of the resource of the given type
+func tresCount(list []apiTRES, kind string) int {
+	for _, t := range list {
+		if t.Type == kind && t.Name == "" {
+			return int(t.Count)
+		}
+	}
+	return 0
+}
+
+// filterJobs applies a JobFilter to jobs on the client side
+func filterJobs(jobs []Job, filter *JobFilter) []Job {
+	if filter == nil {
+		return jobs
+	}
+
+	var result []Job
+	for _, job := range jobs {
+		if len(filter.Users) > 0 && !containsString(filter.Users, job.User) {
+			continue
+		}
+		if len(filter.JobIDs) > 0 && !matchesJobID(filter.JobIDs, job.ID) {
+			continue
+		}
+		if len(filter.States) > 0 && !matchesState(filter.States, job.State) {
+			continue
+		}
+		if len(filter.Partitions) > 0 && !matchesPartition(filter.Partitions, job.Partition) {
+			continue
+		}
+		if len(filter.Accounts) > 0 && !containsString(filter.Accounts, job.Account) {
+			continue
+		}
+		if len(filter.Names) > 0 && !matchesGlob(filter.Names, job.Name) {
+			continue
+		}
+		result = append(result, job)
+	}
+	return result
+}
+
+// matchesJobID reports whether id is one of ids, or a task of an array in ids
+func matchesJobID(ids []string, id string) bool {
+	for _, want := range ids {
+		if id == want || strings.HasPrefix(id, want+"_") {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesState reports whether state is one of states, which may be
+// state codes. squeue's "ALL" matches every state.
+func matchesState(states []string, state string) bool {
+	for _, want := range states {
+		want = NormalizeJobState(want)
+		if want == "ALL" || want == state {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesPartition reports whether a job's partition, which is a comma
+// separated list for jobs submitted to several partitions, is in partitions
+func matchesPartition(partitions []string, partition string) bool {
+	for _, p := range strings.Split(partition, ",") {
+		if containsString(partitions, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesGlob reports whether name matches any of the glob patterns
+func matchesGlob(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if ok, err := path.Match(pattern, name); ok || (err != nil && pattern == name) {
+			return true
+		}
+	}
+	return false
+}
+
+// containsString reports whether list contains s
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,191 @@
+package slurm
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// OpClass groups Slurm operations that share a timeout and retry policy
+type OpClass int
+
+const (
+	// OpRead covers queries such as squeue, sinfo, sacct and 'scontrol show'
+	OpRead OpClass = iota
+	// OpSubmit covers sbatch, which should answer quickly
+	OpSubmit
+	// OpRun covers srun, salloc and sattach, which run as long as the job does
+	OpRun
+	// OpMutate covers every other command that changes cluster state
+	OpMutate
+)
+
+// Policy is the timeout and retry behaviour of an operation class
+type Policy struct {
+	// Timeout limits each attempt; zero means no limit
+	Timeout time.Duration
+	// Retries is the number of extra attempts after a transient failure
+	Retries int
+}
+
+// Default policies. Only read-only queries are retried, since repeating
+// a submission or cancellation that may have reached slurmctld is unsafe.
+const (
+	defaultReadTimeout   = 30 * time.Second
+	defaultSubmitTimeout = 15 * time.Second
+	defaultReadRetries   = 3
+	defaultRetryBackoff  = 500 * time.Millisecond
+	maxRetryBackoff      = 8 * time.Second
+)
+
+// defaultPolicies returns the policies of a new client
+func defaultPolicies() map[OpClass]Policy {
+	return map[OpClass]Policy{
+		OpRead:   {Timeout: defaultReadTimeout, Retries: defaultReadRetries},
+		OpSubmit: {Timeout: defaultSubmitTimeout},
+		OpRun:    {},
+		OpMutate: {Timeout: defaultReadTimeout},
+	}
+}
+
+// transientErrors are messages of slurmctld and slurmrestd failures that
+// usually go away when the request is repeated
+var transientErrors = []string{
+	"socket timed out on send/recv operation",
+	"unable to contact slurm controller",
+	"zero bytes were transmitted or received",
+	"resource temporarily unavailable",
+	"slurm backup controller in standby mode",
+	"communication connection failure",
+	"service unavailable",
+	"bad gateway",
+	"gateway timeout",
+}
+
+// IsTransientError reports whether an error message describes a failure
+// that is worth retrying
+func IsTransientError(msg string) bool {
+	msg = strings.ToLower(msg)
+	for _, transient := range transientErrors {
+		if strings.Contains(msg, transient) {
+			return true
+		}
+	}
+	return false
+}
+
+// Classify returns the operation class of a Slurm command line
+func Classify(command string, args []string) OpClass {
+	switch command {
+	case "squeue", "sinfo", "sacct", "sstat", "sprio", "sshare", "sreport", "sdiag":
+		return OpRead
+	case "srun", "salloc", "sattach":
+		return OpRun
+	case "sbatch":
+		return OpSubmit
+	case "scontrol", "sacctmgr":
+		for _, arg := range args {
+			if strings.HasPrefix(arg, "-") {
+				continue
+			}
+			switch strings.ToLower(arg) {
+			case "show", "list", "ping", "version":
+				return OpRead
+			}
+			return OpMutate
+		}
+		return OpRead
+	}
+	return OpMutate
+}
+
+// Policy returns the policy of an operation class
+func (c *Client) Policy(class OpClass) Policy {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.policies[class]
+}
+
+// SetPolicy changes the policy of an operation class. Retries of classes
+// other than OpRead are ignored.
+func (c *Client) SetPolicy(class OpClass, policy Policy) {
+	if class != OpRead {
+		policy.Retries = 0
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.policies[class] = policy
+}
+
+// SetRetryBackoff sets the delay before the first retry. Each further
+// retry waits twice as long, up to a limit.
+func (c *Client) SetRetryBackoff(delay time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.backoff = delay
+}
+
+// withPolicy runs op under the policy of class. op reports whether its
+// failure was transient; transient failures are retried with exponential
+// backoff as long as the policy allows.
+func (c *Client) withPolicy(class OpClass, op func(ctx context.Context) (transient bool, err error)) error {
+	policy := c.Policy(class)
+	c.mu.Lock()
+	delay := c.backoff
+	c.mu.Unlock()
+
+	for attempt := 0; ; attempt++ {
+		ctx, cancel := c.newContext(policy.Timeout)
+		transient, err := op(ctx)
+		if ctxErr := contextError(ctx, policy.Timeout); ctxErr != nil && err != nil {
+			err, transient = ctxErr, false
+		}
+		cancel()
+
+		if err == nil || !transient || attempt >= policy.Retries {
+			return err
+		}
+
+		if err := c.wait(delay); err != nil {
+			return err
+		}
+		delay = min(delay*2, maxRetryBackoff)
+	}
+}
+
+// restQuery runs a read-only slurmrestd request under the OpRead policy
+func (c *Client) restQuery(op func(ctx context.Context) error) error {
+	return c.withPolicy(OpRead, func(ctx context.Context) (bool, error) {
+		err := op(ctx)
+		return err != nil && IsTransientError(err.Error()), err
+	})
+}
+
+// wait sleeps between retries, returning ErrInterrupted when the client
+// is interrupted
+func (c *Client) wait(delay time.Duration) error {
+	ctx, cancel := c.newContext(0)
+	defer cancel()
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ErrInterrupted
+	}
+}
+
+// runnerTransient reports whether a failed command printed a transient error
+func runnerTransient(result *CommandResult, err error) bool {
+	if err == nil {
+		return false
+	}
+	if result != nil && IsTransientError(result.Error) {
+		return true
+	}
+	return IsTransientError(err.Error())
+}
@@ -0,0 +1,352 @@
+package slurm
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultRESTVersion is the slurmrestd API version used when none is configured
+const DefaultRESTVersion = "v0.0.40"
+
+// RESTClient talks to slurmrestd using JWT authentication
+type RESTClient struct {
+	baseURL string
+	version string
+	user    string
+	token   string
+	http    *http.Client
+}
+
+// NewRESTClient creates a slurmrestd client. An empty version selects
+// DefaultRESTVersion; an empty user defaults to $USER.
+func NewRESTClient(baseURL, version, user, token string) *RESTClient {
+	if version == "" {
+		version = DefaultRESTVersion
+	}
+	if user == "" {
+		user = os.Getenv("USER")
+	}
+
+	return &RESTClient{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		version: version,
+		user:    user,
+		token:   token,
+		http:    &http.Client{},
+	}
+}
+
+// ListJobs returns the jobs matching filter
+func (r *RESTClient) ListJobs(ctx context.Context, filter *JobFilter) ([]Job, error) {
+	var resp apiJobsResponse
+	if err := r.do(ctx, http.MethodGet, "/jobs", nil, &resp); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	jobs := make([]Job, 0, len(resp.Jobs))
+	for _, j := range resp.Jobs {
+		jobs = append(jobs, j.toJob(now))
+	}
+	return filterJobs(jobs, filter), nil
+}
+
+// ListNodes returns all nodes of the cluster
+func (r *RESTClient) ListNodes(ctx context.Context) ([]Node, error) {
+	var resp apiNodesResponse
+	if err := r.do(ctx, http.MethodGet, "/nodes", nil, &resp); err != nil {
+		return nil, err
+	}
+
+	nodes := make([]Node, 0, len(resp.Nodes))
+	for _, n := range resp.Nodes {
+		nodes = append(nodes, n.toNode())
+	}
+	return nodes, nil
+}
+
+// ListPartitions returns all partitions of the cluster
+func (r *RESTClient) ListPartitions(ctx context.Context) ([]Partition, error) {
+	var resp apiPartitionsResponse
+	if err := r.do(ctx, http.MethodGet, "/partitions", nil, &resp); err != nil {
+		return nil, err
+	}
+
+	nodes, err := r.ListNodes(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	partitions := make([]Partition, 0, len(resp.Partitions))
+	for _, p := range resp.Partitions {
+		partitions = append(partitions, p.toPartition(nodes))
+	}
+	return partitions, nil
+}
+
+// SubmitJob submits a batch script. The result mimics sbatch's output.
+func (r *RESTClient) SubmitJob(ctx context.Context, scriptPath string, options *JobOptions) (*CommandResult, error) {
+	start := time.Now()
+
+	script, err := os.ReadFile(scriptPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read script: %v", err)
+	}
+
+	desc, err := jobDescription(options)
+	if err != nil {
+		return nil, err
+	}
+	if _, set := desc["current_working_directory"]; !set {
+		dir, _ := filepath.Abs(filepath.Dir(scriptPath))
+		desc["current_working_directory"] = dir
+	}
+
+	body := map[string]interface{}{
+		"script": string(script),
+		"job":    desc,
+	}
+
+	var resp struct {
+		apiResponse
+		JobID   int64  `json:"job_id"`
+		UserMsg string `json:"job_submit_user_msg"`
+	}
+	if err := r.do(ctx, http.MethodPost, "/job/submit", body, &resp); err != nil {
+		return &CommandResult{ExitCode: 1, Error: err.Error(), Duration: time.Since(start)}, err
+	}
+
+	output := fmt.Sprintf("Submitted batch job %d\n", resp.JobID)
+	if resp.UserMsg != "" {
+		output = resp.UserMsg + "\n" + output
+	}
+	return &CommandResult{Success: true, Output: output, Duration: time.Since(start)}, nil
+}
+
+// CancelJob cancels a job
+func (r *RESTClient) CancelJob(ctx context.Context, jobID string) (*CommandResult, error) {
+	start := time.Now()
+
+	var resp apiResponse
+	if err := r.do(ctx, http.MethodDelete, "/job/"+url.PathEscape(jobID), nil, &resp); err != nil {
+		return &CommandResult{ExitCode: 1, Error: err.Error(), Duration: time.Since(start)}, err
+	}
+	return &CommandResult{Success: true, Duration: time.Since(start)}, nil
+}
+
+// do sends a request to slurmrestd and decodes the response into out
+func (r *RESTClient) do(ctx context.Context, method, path string, body interface{}, out interface{}) error {
+	if err := checkTokenExpiry(r.token, time.Now()); err != nil {
+		return err
+	}
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to encode request: %v", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	endpoint := r.baseURL + "/slurm/" + r.version + path
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, reader)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("X-SLURM-USER-NAME", r.user)
+	req.Header.Set("X-SLURM-USER-TOKEN", r.token)
+
+	resp, err := r.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("slurmrestd request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read slurmrestd response: %v", err)
+	}
+
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return fmt.Errorf("slurmrestd rejected the token (%s); run 'scontrol token' to get a new one", resp.Status)
+	}
+
+	// Errors are reported in the body, often together with a non-2xx status
+	var errs apiResponse
+	if json.Unmarshal(data, &errs) == nil {
+		if err := errs.err(); err != nil {
+			return err
+		}
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("slurmrestd returned %s", resp.Status)
+	}
+
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("failed to decode slurmrestd response: %v", err)
+	}
+	return nil
+}
+
+// checkTokenExpiry returns an error when a JWT has expired. Tokens that
+// cannot be decoded are left for slurmrestd to judge.
+func checkTokenExpiry(token string, now time.Time) error {
+	if token == "" {
+		return fmt.Errorf("no slurmrestd token; set SLURM_JWT, e.g. with 'export $(scontrol token)'")
+	}
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil
+	}
+
+	var claims struct {
+		Exp int64 `json:"exp"`
+	}
+	if json.Unmarshal(payload, &claims) != nil || claims.Exp == 0 {
+		return nil
+	}
+	if now.Unix() >= claims.Exp {
+		return fmt.Errorf("slurmrestd token expired at %s; run 'scontrol token' to get a new one",
+			time.Unix(claims.Exp, 0).Format("2006-01-02 15:04:05"))
+	}
+	return nil
+}
+
+// jobDescription converts JobOptions to a slurmrestd job description
+func jobDescription(options *JobOptions) (map[string]interface{}, error) {
+	desc := map[string]interface{}{}
+
+	if options == nil {
+		desc["environment"] = jobEnvironment(nil)
+		return desc, nil
+	}
+
+	if options.Name != "" {
+		desc["name"] = options.Name
+	}
+	if options.Partition != "" {
+		desc["partition"] = options.Partition
+	}
+	if options.Nodes > 0 {
+		desc["minimum_nodes"] = options.Nodes
+	}
+	if options.CPUs > 0 {
+		desc["cpus_per_task"] = options.CPUs
+	}
+	if options.Memory != "" {
+		mb, err := ParseMemoryMB(options.Memory)
+		if err != nil {
+			return nil, err
+		}
+		desc["memory_per_node"] = map[string]interface{}{"set": true, "number": mb}
+	}
+	if options.Time != "" {
+		d, err := ParseSlurmDuration(options.Time)
+		if err != nil {
+			return nil, err
+		}
+		desc["time_limit"] = map[string]interface{}{"set": true, "number": int64(d / time.Minute)}
+	}
+	if options.QoS != "" {
+		desc["qos"] = options.QoS
+	}
+	if options.Account != "" {
+		desc["account"] = options.Account
+	}
+	if options.Output != "" {
+		desc["standard_output"] = options.Output
+	}
+	if options.Error != "" {
+		desc["standard_error"] = options.Error
+	}
+	if options.WorkDir != "" {
+		desc["current_working_directory"] = options.WorkDir
+	}
+	if options.NodeList != "" {
+		desc["required_nodes"] = []string{options.NodeList}
+	}
+	if options.Exclude != "" {
+		desc["excluded_nodes"] = []string{options.Exclude}
+	}
+	if options.Array != "" {
+		desc["array"] = options.Array
+	}
+	if options.Dependency != "" {
+		desc["dependency"] = options.Dependency
+	}
+	desc["environment"] = jobEnvironment(options.Environment)
+
+	if len(options.ExtraArgs) > 0 {
+		return nil, fmt.Errorf("options not supported by the REST backend: %s", strings.Join(options.ExtraArgs, " "))
+	}
+
+	return desc, nil
+}
+
+// jobEnvironment returns the whole environment of slsh with the variables
+// in extra set, as sbatch passes it with its default --export=ALL
+func jobEnvironment(extra map[string]string) []string {
+	var env []string
+	for _, entry := range os.Environ() {
+		key, _, _ := strings.Cut(entry, "=")
+		if _, ok := extra[key]; !ok {
+			env = append(env, entry)
+		}
+	}
+	for _, key := range slices.Sorted(maps.Keys(extra)) {
+		env = append(env, key+"="+extra[key])
+	}
+	return env
+}
+
+// ParseMemoryMB parses a Slurm memory size such as "4G" or "512" (MB)
+func ParseMemoryMB(size string) (int64, error) {
+	value := strings.ToUpper(strings.TrimSpace(size))
+	value = strings.TrimSuffix(value, "B")
+
+	multiplier := float64(1)
+	if value != "" && (value[len(value)-1] < '0' || value[len(value)-1] > '9') {
+		switch value[len(value)-1] {
+		case 'K':
+			multiplier = 1.0 / 1024
+		case 'M':
+			multiplier = 1
+		case 'G':
+			multiplier = 1024
+		case 'T':
+			multiplier = 1024 * 1024
+		default:
+			return 0, fmt.Errorf("invalid memory size: %s", size)
+		}
+		value = value[:len(value)-1]
+	}
+
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid memory size: %s", size)
+	}
+	return int64(n * multiplier), nil
+}
@@ -0,0 +1,75 @@
+package slurm
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+// Runner executes external Slurm commands on behalf of a Client
+type Runner interface {
+	Run(ctx context.Context, command string, args ...string) (*CommandResult, error)
+}
+
+// interruptGrace is how long a cancelled command may take to exit after
+// SIGINT before it is killed
+const interruptGrace = 5 * time.Second
+
+// ExecRunner runs commands as local processes
+type ExecRunner struct{}
+
+// NewExecRunner creates a runner backed by os/exec
+func NewExecRunner() *ExecRunner {
+	return &ExecRunner{}
+}
+
+// Run executes the command and collects its output
+func (r *ExecRunner) Run(ctx context.Context, command string, args ...string) (*CommandResult, error) {
+	return r.RunStreaming(ctx, nil, command, args...)
+}
+
+// RunStreaming executes the command, passing output lines to handler as
+// they are printed
+func (r *ExecRunner) RunStreaming(ctx context.Context, handler LineHandler, command string, args ...string) (*CommandResult, error) {
+	start := time.Now()
+
+	cmd := exec.CommandContext(ctx, command, args...)
+
+	// Forward the interrupt instead of killing outright, so that srun can
+	// cancel its job step before exiting
+	cmd.Cancel = func() error {
+		return cmd.Process.Signal(os.Interrupt)
+	}
+	cmd.WaitDelay = interruptGrace
+
+	var mu sync.Mutex
+	stdout := &lineWriter{mu: &mu, handler: handler}
+	stderr := &lineWriter{mu: &mu, handler: handler, stderr: true}
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	err := cmd.Run()
+	stdout.Flush()
+	stderr.Flush()
+
+	result := &CommandResult{
+		Success:  err == nil,
+		Output:   stdout.String(),
+		Error:    stderr.String(),
+		Duration: time.Since(start),
+	}
+
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			result.ExitCode = exitErr.ExitCode()
+		} else {
+			result.ExitCode = -1
+		}
+		return result, fmt.Errorf("command failed: %v", err)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,220 @@
+package slurm
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// noArrayTask is what sbatch substitutes for %a in jobs that are not
+// part of an array
+const noArrayTask = "4294967294"
+
+// directiveNames maps the short sbatch options to their long names
+var directiveNames = map[string]string{
+	"-A": "account",
+	"-a": "array",
+	"-c": "cpus-per-task",
+	"-D": "chdir",
+	"-d": "dependency",
+	"-e": "error",
+	"-G": "gpus",
+	"-J": "job-name",
+	"-N": "nodes",
+	"-n": "ntasks",
+	"-o": "output",
+	"-p": "partition",
+	"-q": "qos",
+	"-t": "time",
+	"-w": "nodelist",
+	"-x": "exclude",
+}
+
+// directiveFlags are sbatch options that take no value
+var directiveFlags = map[string]bool{
+	"exclusive":  true,
+	"hold":       true,
+	"requeue":    true,
+	"no-requeue": true,
+	"overcommit": true,
+	"parsable":   true,
+	"wait":       true,
+}
+
+// JobFlags returns the sbatch options that take no value as they are
+// typed, such as --exclusive
+func JobFlags() []string {
+	flags := make([]string, 0, len(directiveFlags))
+	for _, name := range slices.Sorted(maps.Keys(directiveFlags)) {
+		flags = append(flags, "--"+name)
+	}
+	return flags
+}
+
+// BatchScript generates a batch script that runs command, with options
+// as #SBATCH directives
+func (c *Client) BatchScript(command string, options *JobOptions) string {
+	var b strings.Builder
+	b.WriteString("#!/bin/bash\n")
+	if options != nil {
+		for _, directive := range directives(c.buildJobArgs(options)) {
+			fmt.Fprintf(&b, "#SBATCH %s\n", directive)
+		}
+	}
+	fmt.Fprintf(&b, "# Generated by slsh on %s\n\n", time.Now().Format("2006-01-02 15:04:05"))
+	b.WriteString(command)
+	b.WriteString("\n")
+	return b.String()
+}
+
+// directives joins sbatch arguments into one directive per option, as in
+// --gres=gpu:1, since every #SBATCH line is read on its own
+func directives(args []string) []string {
+	var lines []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if strings.HasPrefix(arg, "-") && !strings.Contains(arg, "=") && i+1 < len(args) && !strings.HasPrefix(args[i+1], "-") {
+			if strings.HasPrefix(arg, "--") {
+				arg += "=" + args[i+1]
+			} else {
+				arg += " " + args[i+1]
+			}
+			i++
+		}
+		lines = append(lines, arg)
+	}
+	return lines
+}
+
+// ParseScriptDirectives returns the options set by the #SBATCH lines of a
+// batch script, keyed by long option name without dashes. Like sbatch, it
+// stops at the first line that is neither a comment nor blank.
+func ParseScriptDirectives(script string) map[string]string {
+	directives := make(map[string]string)
+
+	for _, line := range strings.Split(script, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if !strings.HasPrefix(line, "#") {
+			break
+		}
+		if !strings.HasPrefix(line, "#SBATCH") {
+			continue
+		}
+
+		words := strings.Fields(strings.TrimPrefix(line, "#SBATCH"))
+		for i := 0; i < len(words); i++ {
+			word := words[i]
+			if strings.HasPrefix(word, "#") {
+				break
+			}
+
+			var name, value string
+			switch {
+			case strings.HasPrefix(word, "--"):
+				name, value, _ = strings.Cut(word[2:], "=")
+				if !strings.Contains(word, "=") && !directiveFlags[name] && i+1 < len(words) {
+					value = words[i+1]
+					i++
+				}
+			case strings.HasPrefix(word, "-") && len(word) >= 2:
+				name = directiveNames[word[:2]]
+				if name == "" {
+					name = word[1:2]
+				}
+				value = word[2:]
+				if value == "" && i+1 < len(words) {
+					value = words[i+1]
+					i++
+				}
+			default:
+				continue
+			}
+			directives[name] = value
+		}
+	}
+
+	return directives
+}
+
+// FilenameFields are the values sbatch substitutes into the names of
+// output files
+type FilenameFields struct {
+	JobID       string
+	ArrayJobID  string
+	ArrayTaskID string
+	Name        string
+	User        string
+	Node        string
+}
+
+// ExpandFilenamePattern replaces the patterns sbatch expands in --output
+// and --error, such as %j, %x and %4a. Patterns whose value is not known
+// are kept.
+func ExpandFilenamePattern(pattern string, fields FilenameFields) string {
+	var b strings.Builder
+
+	for i := 0; i < len(pattern); i++ {
+		if pattern[i] != '%' || i+1 >= len(pattern) {
+			b.WriteByte(pattern[i])
+			continue
+		}
+
+		// An optional width pads numbers with zeros, as in %4a
+		j := i + 1
+		for j < len(pattern) && pattern[j] >= '0' && pattern[j] <= '9' {
+			j++
+		}
+		if j >= len(pattern) {
+			b.WriteString(pattern[i:])
+			break
+		}
+		width, _ := strconv.Atoi(pattern[i+1 : j])
+
+		value, ok := filenameField(pattern[j], fields)
+		if !ok {
+			b.WriteString(pattern[i : j+1])
+		} else {
+			if n, err := strconv.Atoi(value); err == nil && width > 0 {
+				value = fmt.Sprintf("%0*d", width, n)
+			}
+			b.WriteString(value)
+		}
+		i = j
+	}
+
+	return b.String()
+}
+
+// filenameField returns the value of a filename pattern letter
+func filenameField(letter byte, fields FilenameFields) (string, bool) {
+	var value string
+	switch letter {
+	case '%':
+		return "%", true
+	case 'j':
+		value = fields.JobID
+	case 'A':
+		value = fields.ArrayJobID
+		if value == "" {
+			value = fields.JobID
+		}
+	case 'a':
+		value = fields.ArrayTaskID
+		if value == "" && fields.JobID != "" {
+			value = noArrayTask
+		}
+	case 'x':
+		value = fields.Name
+	case 'u':
+		value = fields.User
+	case 'N':
+		value = fields.Node
+	}
+	return value, value != ""
+}
@@ -0,0 +1,168 @@
+package slurm
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"sync"
+)
+
+// OutputLine is a line of output from a running command, without its
+// trailing newline
+type OutputLine struct {
+	Text   string
+	Stderr bool
+}
+
+// LineHandler receives output lines as they arrive. Calls are serialized.
+type LineHandler func(line OutputLine)
+
+// StreamingRunner is a Runner that can report output while the command runs
+type StreamingRunner interface {
+	Runner
+	RunStreaming(ctx context.Context, handler LineHandler, command string, args ...string) (*CommandResult, error)
+}
+
+// ExecuteStream executes a Slurm command, passing each output line to
+// handler as soon as it is printed. The complete output is still returned
+// in the result. A failed attempt is only retried if it printed nothing
+// but errors, so that no line reaches handler twice.
+func (c *Client) ExecuteStream(handler LineHandler, command string, args ...string) (*CommandResult, error) {
+	streamer, ok := c.runner.(StreamingRunner)
+	if !ok {
+		streamer = &replayRunner{c.runner}
+	}
+
+	class := Classify(command, args)
+	retries := c.Policy(class).Retries > 0
+
+	var result *CommandResult
+	var attempt *attemptOutput
+	err := c.withPolicy(class, func(ctx context.Context) (bool, error) {
+		attempt = &attemptOutput{handler: handler, hold: retries}
+		var err error
+		result, err = streamer.RunStreaming(ctx, attempt.emit, command, args...)
+		return runnerTransient(result, err) && !attempt.printed, err
+	})
+	if attempt != nil {
+		attempt.flush()
+	}
+	return result, err
+}
+
+// attemptOutput passes the output of one attempt at a command to a
+// handler. With hold set, error lines are held back until the command
+// prints a normal line, so that an attempt that only printed an error can
+// be retried without showing it.
+type attemptOutput struct {
+	handler LineHandler
+	hold    bool
+	held    []OutputLine
+	printed bool
+}
+
+// emit passes a line on, or holds it back
+func (a *attemptOutput) emit(line OutputLine) {
+	if a.handler == nil {
+		return
+	}
+	if a.hold && !a.printed && line.Stderr {
+		a.held = append(a.held, line)
+		return
+	}
+	a.flush()
+	a.printed = true
+	a.handler(line)
+}
+
+// flush passes on the lines held back
+func (a *attemptOutput) flush() {
+	for _, line := range a.held {
+		a.handler(line)
+	}
+	a.held = nil
+}
+
+// replayRunner adapts a plain Runner by replaying its output once the
+// command has finished
+type replayRunner struct {
+	Runner
+}
+
+// RunStreaming runs the command and then emits its output line by line
+func (r *replayRunner) RunStreaming(ctx context.Context, handler LineHandler, command string, args ...string) (*CommandResult, error) {
+	result, err := r.Run(ctx, command, args...)
+	if result != nil {
+		replayLines(handler, result)
+	}
+	return result, err
+}
+
+// replayLines emits the collected output of a result
+func replayLines(handler LineHandler, result *CommandResult) {
+	if handler == nil {
+		return
+	}
+	for _, stream := range []struct {
+		text   string
+		stderr bool
+	}{{result.Output, false}, {result.Error, true}} {
+		text := strings.TrimSuffix(stream.text, "\n")
+		if text == "" {
+			continue
+		}
+		for _, line := range strings.Split(text, "\n") {
+			handler(OutputLine{Text: line, Stderr: stream.stderr})
+		}
+	}
+}
+
+// lineWriter collects everything written to it and passes complete lines
+// to a handler. Writers sharing a mutex never call the handler concurrently.
+type lineWriter struct {
+	mu      *sync.Mutex
+	handler LineHandler
+	stderr  bool
+	all     bytes.Buffer
+	partial []byte
+}
+
+// Write records p and emits every completed line
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.all.Write(p)
+	if w.handler == nil {
+		return len(p), nil
+	}
+
+	w.partial = append(w.partial, p...)
+	for {
+		i := bytes.IndexByte(w.partial, '\n')
+		if i < 0 {
+			break
+		}
+		line := strings.TrimSuffix(string(w.partial[:i]), "\r")
+		w.partial = w.partial[i+1:]
+		w.emit(line)
+	}
+	return len(p), nil
+}
+
+// Flush emits a final line that did not end with a newline
+func (w *lineWriter) Flush() {
+	if w.handler != nil && len(w.partial) > 0 {
+		w.emit(string(w.partial))
+		w.partial = nil
+	}
+}
+
+// emit passes a line to the handler
+func (w *lineWriter) emit(line string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.handler(OutputLine{Text: line, Stderr: w.stderr})
+}
+
+// String returns everything written so far
+func (w *lineWriter) String() string {
+	return w.all.String()
+}
@@ -0,0 +1,65 @@
+package slurm
+
+import (
+	"fmt"
+	"strings"
+)
+
+// TerminalRunner is a Runner that can run commands that need the user's
+// terminal, such as 'srun --pty', salloc and sattach
+type TerminalRunner interface {
+	Runner
+	RunTerminal(command string, args ...string) error
+}
+
+// RunTerminal runs the command on a pseudo-terminal connected to the
+// user's terminal
+func (r *ExecRunner) RunTerminal(command string, args ...string) error {
+	return runTerminal(command, args)
+}
+
+// NeedsTerminal reports whether a Slurm command is interactive and must
+// be run on a pseudo-terminal
+func NeedsTerminal(command string, args []string) bool {
+	switch command {
+	case "salloc", "sattach":
+		return true
+	case "srun":
+		for _, arg := range args {
+			if arg == "--pty" || strings.HasPrefix(arg, "--pty=") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ExecuteTerminal runs an interactive command on a pseudo-terminal. It
+// has no timeout; while it runs the command receives Ctrl+C itself.
+func (c *Client) ExecuteTerminal(command string, args ...string) error {
+	runner, ok := c.runner.(TerminalRunner)
+	if !ok {
+		return fmt.Errorf("%s needs a terminal, which this backend cannot provide", command)
+	}
+
+	c.interactive.Add(1)
+	defer c.interactive.Add(-1)
+
+	return runner.RunTerminal(command, args...)
+}
+
+// RunJobTerminal runs an interactive job step with 'srun --pty'
+func (c *Client) RunJobTerminal(command string, options *JobOptions) error {
+	args := []string{}
+	if options != nil {
+		args = append(args, c.buildJobArgs(options)...)
+	}
+	if !NeedsTerminal("srun", args) {
+		args = append(args, "--pty")
+	}
+	if command != "" {
+		args = append(args, command)
+	}
+
+	return c.ExecuteTerminal("srun", args...)
+}
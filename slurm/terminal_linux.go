@@ -0,0 +1,141 @@
+//go:build linux
+
+package slurm
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"slsh/utils"
+)
+
+// ptyDrain is how long output left in the pseudo-terminal is copied after
+// the command exits, in case a background process still holds it open
+const ptyDrain = time.Second
+
+// runTerminal runs a command attached to a new pseudo-terminal that
+// mirrors the user's terminal. The user's terminal is put in raw mode so
+// that keys such as Ctrl+C reach the command, and is restored on return.
+func runTerminal(command string, args []string) error {
+	master, slave, err := openPTY()
+	if err != nil {
+		return fmt.Errorf("failed to allocate a pseudo-terminal: %v", err)
+	}
+	defer master.Close()
+
+	cmd := exec.Command(command, args...)
+	cmd.Stdin = slave
+	cmd.Stdout = slave
+	cmd.Stderr = slave
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true, Setctty: true, Ctty: 0}
+
+	stdinFd := int(os.Stdin.Fd())
+	isTerminal := utils.IsTerminal(stdinFd)
+	if isTerminal {
+		copyWinsize(stdinFd, master)
+		state, err := utils.MakeRaw(stdinFd)
+		if err != nil {
+			return fmt.Errorf("failed to set terminal mode: %v", err)
+		}
+		defer utils.RestoreTerminal(stdinFd, state)
+	}
+
+	if err := cmd.Start(); err != nil {
+		slave.Close()
+		return err
+	}
+	slave.Close()
+
+	// Follow resizes of the user's terminal
+	if isTerminal {
+		winch := make(chan os.Signal, 1)
+		signal.Notify(winch, syscall.SIGWINCH)
+		defer signal.Stop(winch)
+		go func() {
+			for range winch {
+				copyWinsize(stdinFd, master)
+			}
+		}()
+	}
+
+	input, restoreInput := pollableStdin()
+	defer restoreInput()
+	if input != nil {
+		go io.Copy(master, input)
+	}
+
+	copied := make(chan struct{})
+	go func() {
+		// Reading the master fails with EIO once the command has exited
+		io.Copy(os.Stdout, master)
+		close(copied)
+	}()
+
+	err = cmd.Wait()
+	select {
+	case <-copied:
+	case <-time.After(ptyDrain):
+	}
+	return err
+}
+
+// openPTY allocates a pseudo-terminal pair
+func openPTY() (*os.File, *os.File, error) {
+	master, err := os.OpenFile("/dev/ptmx", os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var unlock int32
+	if err := utils.Ioctl(master.Fd(), syscall.TIOCSPTLCK, uintptr(unsafe.Pointer(&unlock))); err != nil {
+		master.Close()
+		return nil, nil, err
+	}
+
+	var n uint32
+	if err := utils.Ioctl(master.Fd(), syscall.TIOCGPTN, uintptr(unsafe.Pointer(&n))); err != nil {
+		master.Close()
+		return nil, nil, err
+	}
+
+	slave, err := os.OpenFile(fmt.Sprintf("/dev/pts/%d", n), os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		master.Close()
+		return nil, nil, err
+	}
+	return master, slave, nil
+}
+
+// pollableStdin returns a copy of stdin whose pending read can be
+// abandoned, so that no keystroke typed after the command exits is lost
+// to it. The returned function restores stdin to blocking mode.
+func pollableStdin() (*os.File, func()) {
+	fd, err := syscall.Dup(int(os.Stdin.Fd()))
+	if err != nil {
+		return nil, func() {}
+	}
+	if err := syscall.SetNonblock(fd, true); err != nil {
+		syscall.Close(fd)
+		return nil, func() {}
+	}
+
+	input := os.NewFile(uintptr(fd), "stdin")
+	return input, func() {
+		input.Close()
+		syscall.SetNonblock(int(os.Stdin.Fd()), false)
+	}
+}
+
+// copyWinsize gives the pseudo-terminal the size of the terminal fd
+func copyWinsize(fd int, pty *os.File) {
+	var ws struct{ Row, Col, X, Y uint16 }
+	if utils.Ioctl(uintptr(fd), syscall.TIOCGWINSZ, uintptr(unsafe.Pointer(&ws))) == nil {
+		utils.Ioctl(pty.Fd(), syscall.TIOCSWINSZ, uintptr(unsafe.Pointer(&ws)))
+	}
+}
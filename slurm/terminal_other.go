@@ -0,0 +1,13 @@
+//go:build !linux
+
+package slurm
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// runTerminal is only implemented on Linux
+func runTerminal(command string, args []string) error {
+	return fmt.Errorf("pseudo-terminals are not supported on %s", runtime.GOOS)
+}
@@ -7,36 +7,85 @@ type Job struct {
 	ID          string    `json:"id"`
 	Name        string    `json:"name"`
 	User        string    `json:"user"`
+	Account     string    `json:"account,omitempty"`
 	State       string    `json:"state"`
 	Partition   string    `json:"partition"`
 	Nodes       int       `json:"nodes"`
 	CPUs        int       `json:"cpus"`
 	TimeLimit   string    `json:"time_limit"`
+	TimeUsed    string    `json:"time_used,omitempty"`
 	SubmitTime  time.Time `json:"submit_time"`
 	StartTime   time.Time `json:"start_time,omitempty"`
 	EndTime     time.Time `json:"end_time,omitempty"`
 	NodeList    string    `json:"node_list,omitempty"`
 	WorkDir     string    `json:"work_dir,omitempty"`
 	Command     string    `json:"command,omitempty"`
+	Reason      string    `json:"reason,omitempty"`
+}
+
+// JobRecord is a job as recorded by the accounting database (sacct)
+type JobRecord struct {
+	ID         string        `json:"id"`
+	Name       string        `json:"name"`
+	User       string        `json:"user"`
+	Account    string        `json:"account,omitempty"`
+	Partition  string        `json:"partition"`
+	State      string        `json:"state"`
+	ExitCode   int           `json:"exit_code"`
+	Signal     int           `json:"signal,omitempty"`
+	Elapsed    time.Duration `json:"elapsed"`
+	SubmitTime time.Time     `json:"submit_time"`
+	StartTime  time.Time     `json:"start_time,omitempty"`
+	EndTime    time.Time     `json:"end_time,omitempty"`
+	NodeList   string        `json:"node_list,omitempty"`
+	CPUs       int           `json:"cpus"`
+	TimeLimit  string        `json:"time_limit,omitempty"`
+	TRES       string        `json:"tres,omitempty"`
+	WorkDir    string        `json:"work_dir,omitempty"`
+	Reason     string        `json:"reason,omitempty"`
+	StdOut     string        `json:"stdout,omitempty"`
+	StdErr     string        `json:"stderr,omitempty"`
+}
+
+// JobDetail combines what scontrol, squeue and sacct know about a job
+type JobDetail struct {
+	JobRecord
+	Command     string      `json:"command,omitempty"`
+	ArrayJobID  string      `json:"array_job_id,omitempty"`
+	ArrayTaskID string      `json:"array_task_id,omitempty"`
+	Steps       []JobRecord `json:"steps,omitempty"`
+	Sources     []string    `json:"sources"`
 }
 
 // Node represents a Slurm node
 type Node struct {
-	Name      string `json:"name"`
-	State     string `json:"state"`
-	CPUs      int    `json:"cpus"`
-	Memory    int    `json:"memory"`
-	Partition string `json:"partition"`
-	Features  string `json:"features,omitempty"`
+	Name      string   `json:"name"`
+	State     string   `json:"state"`
+	Flags     []string `json:"flags,omitempty"`
+	CPUs      int      `json:"cpus"`
+	CPUsAlloc int      `json:"cpus_alloc"`
+	CPUsIdle  int      `json:"cpus_idle"`
+	CPUsOther int      `json:"cpus_other"`
+	Memory    int      `json:"memory"`
+	Partition string   `json:"partition"`
+	Features  string   `json:"features,omitempty"`
+	Reason    string   `json:"reason,omitempty"`
 }
 
 // Partition represents a Slurm partition
 type Partition struct {
 	Name        string   `json:"name"`
 	State       string   `json:"state"`
+	Default     bool     `json:"default,omitempty"`
 	MaxTime     string   `json:"max_time"`
 	MaxNodes    int      `json:"max_nodes"`
 	DefaultTime string   `json:"default_time"`
+	TotalNodes  int      `json:"total_nodes"`
+	CPUs        int      `json:"cpus"`
+	CPUsAlloc   int      `json:"cpus_alloc"`
+	CPUsIdle    int      `json:"cpus_idle"`
+	CPUsOther   int      `json:"cpus_other"`
+	NodeList    string   `json:"node_list,omitempty"`
 	Nodes       []string `json:"nodes"`
 }
 
@@ -53,6 +102,13 @@ type JobOptions struct {
 	Output      string            `json:"output,omitempty"`
 	Error       string            `json:"error,omitempty"`
 	WorkDir     string            `json:"work_dir,omitempty"`
+	NodeList    string            `json:"node_list,omitempty"`
+	Exclude     string            `json:"exclude,omitempty"`
+	Array       string            `json:"array,omitempty"`
+	Dependency  string            `json:"dependency,omitempty"`
+	JobID       string            `json:"job_id,omitempty"`
+	Label       bool              `json:"label,omitempty"`
+	PTY         bool              `json:"pty,omitempty"`
 	Environment map[string]string `json:"environment,omitempty"`
 	ExtraArgs   []string          `json:"extra_args,omitempty"`
 }
@@ -62,6 +118,19 @@ type Command struct {
 	Name    string            `json:"name"`
 	Args    []string          `json:"args"`
 	Options map[string]string `json:"options"`
+	// OptionOrder lists the options in the order they were given
+	OptionOrder []string `json:"option_order,omitempty"`
+	// EndOfOptions is set when "--" ended the options, so that Args are
+	// a command with options of its own
+	EndOfOptions bool `json:"end_of_options,omitempty"`
+}
+
+// SetOption sets an option, remembering the order options were given in
+func (c *Command) SetOption(opt, value string) {
+	if _, exists := c.Options[opt]; !exists {
+		c.OptionOrder = append(c.OptionOrder, opt)
+	}
+	c.Options[opt] = value
 }
 
 // CommandResult represents the result of a command execution
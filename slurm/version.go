@@ -0,0 +1,90 @@
+package slurm
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+// SlurmVersion is the version of the installed Slurm tools
+type SlurmVersion struct {
+	Major int
+	Minor int
+	Patch int
+}
+
+// jsonMinVersion is the first release whose --json output slsh relies on
+var jsonMinVersion = SlurmVersion{Major: 23, Minor: 2}
+
+// versionPattern matches versions such as "slurm 23.11.4" or "slurm-wlm 22.05.8"
+var versionPattern = regexp.MustCompile(`(\d+)\.(\d+)(?:\.(\d+))?`)
+
+// ParseSlurmVersion parses the output of 'sinfo --version'
+func ParseSlurmVersion(output string) (SlurmVersion, error) {
+	m := versionPattern.FindStringSubmatch(output)
+	if m == nil {
+		return SlurmVersion{}, fmt.Errorf("unrecognized Slurm version: %q", output)
+	}
+
+	v := SlurmVersion{}
+	v.Major, _ = strconv.Atoi(m[1])
+	v.Minor, _ = strconv.Atoi(m[2])
+	if m[3] != "" {
+		v.Patch, _ = strconv.Atoi(m[3])
+	}
+	return v, nil
+}
+
+// AtLeast reports whether v is the same as or newer than other
+func (v SlurmVersion) AtLeast(other SlurmVersion) bool {
+	if v.Major != other.Major {
+		return v.Major > other.Major
+	}
+	if v.Minor != other.Minor {
+		return v.Minor > other.Minor
+	}
+	return v.Patch >= other.Patch
+}
+
+// String formats the version as Slurm does
+func (v SlurmVersion) String() string {
+	return fmt.Sprintf("%d.%02d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// Version returns the Slurm version, detecting it on first use
+func (c *Client) Version() (SlurmVersion, error) {
+	c.detectVersion()
+	return c.version, c.versionErr
+}
+
+// detectVersion runs 'sinfo --version' once per client and decides
+// whether the --json output can be used
+func (c *Client) detectVersion() {
+	c.versionOnce.Do(func() {
+		result, err := c.Execute("sinfo", "--version")
+		if err != nil {
+			c.versionErr = fmt.Errorf("failed to detect Slurm version: %v", err)
+			return
+		}
+
+		c.version, c.versionErr = ParseSlurmVersion(result.Output)
+		if c.versionErr == nil {
+			c.jsonEnabled.Store(c.version.AtLeast(jsonMinVersion))
+		}
+	})
+}
+
+// useJSON reports whether queries should use the --json output
+func (c *Client) useJSON() bool {
+	if c.rest != nil {
+		return false
+	}
+	c.detectVersion()
+	return c.jsonEnabled.Load()
+}
+
+// disableJSON falls back to the format-string queries for the rest of
+// the session, after the JSON output turned out to be unusable
+func (c *Client) disableJSON() {
+	c.jsonEnabled.Store(false)
+}
@@ -0,0 +1,94 @@
+package test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"slsh/slurm"
+	"slsh/utils"
+)
+
+func TestAllocRunsStepsInAllocation(t *testing.T) {
+	sh := newFakeShell(t)
+	sh.GetConfig().DefaultPartition = "compute"
+	runner := sh.GetClient().GetRunner().(*slurm.FakeRunner)
+
+	var err error
+	output := captureOutput(t, func() { err = sh.ExecuteDirectCommand("alloc -N 2 -t 2:00:00") })
+	if err != nil {
+		t.Fatalf("alloc failed: %v", err)
+	}
+	if !strings.Contains(output, "Inside allocation 1010 on node[002-003]") {
+		t.Errorf("unexpected output:\n%s", output)
+	}
+
+	alloc := sh.Allocation()
+	if alloc == nil || alloc.JobID != "1010" {
+		t.Fatalf("shell is not inside the allocation: %+v", alloc)
+	}
+	if remaining, limited := alloc.Remaining(); !limited || remaining > 2*time.Hour || remaining < 119*time.Minute {
+		t.Errorf("unexpected remaining walltime: %s", remaining)
+	}
+
+	salloc := strings.Join(callArgs(runner.Calls(), "salloc"), " ")
+	for _, want := range []string{"--nodes=2", "--time=2:00:00", "--partition=compute", "--no-shell"} {
+		if !strings.Contains(salloc, want) {
+			t.Errorf("salloc arguments lack %s: %s", want, salloc)
+		}
+	}
+
+	// Steps run in the allocation, without the configured defaults
+	captureOutput(t, func() { err = sh.ExecuteDirectCommand("run hostname") })
+	if err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	srun := callArgs(runner.Calls(), "srun")
+	if strings.Join(srun, " ") != "--jobid=1010 hostname" {
+		t.Errorf("unexpected srun arguments: %v", srun)
+	}
+
+	if err := sh.ExecuteDirectCommand("alloc"); err == nil {
+		t.Error("expected an error for a second allocation")
+	}
+
+	output = captureOutput(t, func() { err = sh.ExecuteDirectCommand("release") })
+	if err != nil {
+		t.Fatalf("release failed: %v", err)
+	}
+	if sh.Allocation() != nil || !strings.Contains(output, "Released allocation 1010") {
+		t.Errorf("allocation not released:\n%s", output)
+	}
+	if scancel := callArgs(runner.Calls(), "scancel"); len(scancel) == 0 || scancel[len(scancel)-1] != "1010" {
+		t.Errorf("unexpected scancel arguments: %v", scancel)
+	}
+
+	// Outside the allocation, run asks for resources again
+	captureOutput(t, func() { err = sh.ExecuteDirectCommand("run hostname") })
+	calls := runner.Calls()
+	if srun := strings.Join(calls[len(calls)-1].Args, " "); strings.Contains(srun, "--jobid") || !strings.Contains(srun, "--partition=compute") {
+		t.Errorf("unexpected srun arguments after release: %s", srun)
+	}
+
+	if err := sh.ExecuteDirectCommand("release"); err == nil {
+		t.Error("expected an error outside an allocation")
+	}
+}
+
+func TestPromptShowsAllocation(t *testing.T) {
+	prompt := utils.NewPrompt("slsh> ")
+	prompt.SetAllocation("1010", time.Now().Add(90*time.Minute+30*time.Second))
+	if got := prompt.Format(); !strings.HasPrefix(got, "[alloc 1010 1:30:") || !strings.HasSuffix(got, "] slsh> ") {
+		t.Errorf("unexpected prompt: %q", got)
+	}
+
+	prompt.SetAllocation("1010", time.Time{})
+	if got := prompt.Format(); got != "[alloc 1010] slsh> " {
+		t.Errorf("unexpected prompt without a limit: %q", got)
+	}
+
+	prompt.SetAllocation("", time.Time{})
+	if got := prompt.Format(); got != "slsh> " {
+		t.Errorf("allocation not cleared: %q", got)
+	}
+}
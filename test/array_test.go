@@ -0,0 +1,139 @@
+package test
+
+import (
+	"strings"
+	"testing"
+
+	"slsh/commands"
+	"slsh/config"
+	"slsh/shell"
+	"slsh/slurm"
+)
+
+// runArray runs an array command line against the fake cluster, returning
+// the output and the sbatch arguments, if any
+func runArray(t *testing.T, line string) (string, []string, error) {
+	t.Helper()
+
+	client, runner := newFakeClient()
+	cfg := config.Default()
+	cfg.ColorOutput = false
+
+	array := commands.NewArrayCommand(client, cfg)
+	cmd, err := shell.ParseCommand(line, flagsOf(array))
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+
+	var runErr error
+	output := captureOutput(t, func() {
+		runErr = array.Execute(cmd, nil)
+	})
+	return output, callArgs(runner.Calls(), "sbatch"), runErr
+}
+
+func TestParseArrayIndices(t *testing.T) {
+	tests := []struct {
+		spec     string
+		indices  string
+		throttle int
+	}{
+		{"1-5", "1-5", 0},
+		{"1-100%10", "1-100", 10},
+		{"0-30:10", "0,10,20,30", 0},
+		{"7,1-3,2", "1-3,7", 0},
+		{"4", "4", 0},
+	}
+	for _, tt := range tests {
+		indices, throttle, err := slurm.ParseArrayIndices(tt.spec)
+		if err != nil {
+			t.Errorf("%s: %v", tt.spec, err)
+			continue
+		}
+		if got := slurm.FormatArrayIndices(indices); got != tt.indices || throttle != tt.throttle {
+			t.Errorf("%s: got %s %%%d, want %s %%%d", tt.spec, got, throttle, tt.indices, tt.throttle)
+		}
+	}
+
+	for _, spec := range []string{"", "5-1", "a-b", "1-10%x", "3:2", "1-10:0", "0-99999999"} {
+		if _, _, err := slurm.ParseArrayIndices(spec); err == nil {
+			t.Errorf("%q: expected an error", spec)
+		}
+	}
+}
+
+func TestArraySummary(t *testing.T) {
+	output, _, err := runArray(t, "array 1005")
+	if err != nil {
+		t.Fatalf("array failed: %v", err)
+	}
+
+	if !strings.Contains(output, "Array 1005 (sweep): 10 tasks") {
+		t.Errorf("missing header:\n%s", output)
+	}
+	if !strings.Contains(output, "2 COMPLETED, 1 FAILED [2], 1 OUT_OF_MEMORY [3], 1 RUNNING, 5 PENDING") {
+		t.Errorf("unexpected summary:\n%s", output)
+	}
+	// Pending tasks are only counted, started ones are listed
+	if !strings.Contains(output, "0 (signal 125)") || strings.Contains(output, "JobArrayTaskLimit") {
+		t.Errorf("unexpected task table:\n%s", output)
+	}
+
+	output, _, err = runArray(t, "array 1005_2 --failed")
+	if err != nil {
+		t.Fatalf("array --failed failed: %v", err)
+	}
+	if strings.Contains(output, "node002") {
+		t.Errorf("--failed lists tasks that did not fail:\n%s", output)
+	}
+
+	if _, _, err := runArray(t, "array 1001"); err == nil {
+		t.Error("expected an error for a job that is not an array")
+	}
+}
+
+func TestArrayResubmitFailedTasks(t *testing.T) {
+	output, args, err := runArray(t, "array 1005 --resubmit -t 2:00:00")
+	if err != nil {
+		t.Fatalf("resubmit failed: %v", err)
+	}
+
+	joined := strings.Join(args, " ")
+	for _, want := range []string{"--array=2-3", "--time=2:00:00", "--partition=compute", "--job-name=sweep"} {
+		if !strings.Contains(joined, want) {
+			t.Errorf("sbatch arguments lack %s: %s", want, joined)
+		}
+	}
+	if len(args) == 0 || args[len(args)-1] != "/home/alice/sweep/sweep.sh" {
+		t.Errorf("original script not resubmitted: %s", joined)
+	}
+	if !strings.Contains(output, "Resubmitting 2 tasks of array 1005: 2-3") {
+		t.Errorf("unexpected output:\n%s", output)
+	}
+
+	_, args, err = runArray(t, "array 1005 --resubmit retry.sh")
+	if err != nil {
+		t.Fatalf("resubmit with a script failed: %v", err)
+	}
+	if len(args) == 0 || args[len(args)-1] != "retry.sh" {
+		t.Errorf("given script not used: %v", args)
+	}
+}
+
+func TestSubmitArray(t *testing.T) {
+	output, args := runSubmit(t, "submit --array 1-100%10 sweep.sh", config.Default())
+
+	if !strings.Contains(strings.Join(args, " "), "--array=1-100%10") {
+		t.Errorf("sbatch arguments lack the array: %v", args)
+	}
+	if !strings.Contains(output, "1-100%10 (100 tasks, 10 at a time)") {
+		t.Errorf("array not described:\n%s", output)
+	}
+
+	client, _ := newFakeClient()
+	submit := commands.NewSubmitCommand(client, config.Default())
+	cmd, _ := shell.ParseCommand("submit --array 1-x sweep.sh", flagsOf(submit))
+	if err := submit.Execute(cmd, nil); err == nil {
+		t.Error("expected an error for an invalid array")
+	}
+}
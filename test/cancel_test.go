@@ -0,0 +1,173 @@
+package test
+
+import (
+	"strings"
+	"testing"
+
+	"slsh/commands"
+	"slsh/config"
+	"slsh/shell"
+	"slsh/slurm"
+)
+
+// arrayQueue answers squeue -j with a job array whose pending tasks are
+// listed as one entry
+var arrayQueue = slurm.Fixture{
+	Command: "squeue",
+	Args:    []string{"-j"},
+	Output: "1001|RUNNING|compute|alice|1|4|1-00:00:00|2024-01-15T10:30:00|2024-01-15T10:31:00|2024-01-16T10:31:00|1:02:03|node001|/home/alice/train|physics|None|train\n" +
+		"1002|PENDING|gpu|alice|2|8|2:00:00|2024-01-15T11:00:00|N/A|N/A|0:00||/home/alice/eval|physics|Resources|eval, final\n" +
+		"1005_4|RUNNING|compute|alice|1|1|1:00:00|2024-01-15T12:00:00|2024-01-15T12:01:00|2024-01-15T13:01:00|5:00|node002|/home/alice/sweep|physics|None|sweep\n" +
+		"1005_[5-9%2]|PENDING|compute|alice|1|1|1:00:00|2024-01-15T12:00:00|N/A|N/A|0:00||/home/alice/sweep|physics|JobArrayTaskLimit|sweep\n",
+}
+
+// answeringShell answers confirmation prompts with a fixed answer
+type answeringShell struct {
+	*shell.Shell
+	answer bool
+	asked  []string
+}
+
+func (s *answeringShell) Confirm(question string) bool {
+	s.asked = append(s.asked, question)
+	return s.answer
+}
+
+// runCancel runs a cancel command line against the fake cluster with the
+// array fixture, returning the output and the scancel arguments
+func runCancel(t *testing.T, line string, cfg *config.Config, sh commands.ShellInterface) (string, []string, error) {
+	t.Helper()
+	t.Setenv("USER", "alice")
+
+	runner := slurm.NewFakeRunner(append([]slurm.Fixture{arrayQueue}, slurm.DefaultFixtures()...))
+	client := slurm.NewClientWithRunner(runner)
+	if cfg == nil {
+		cfg = config.Default()
+	}
+	cfg.ColorOutput = false
+
+	cancel := commands.NewCancelCommand(client, cfg)
+	cmd, err := shell.ParseCommand(line, flagsOf(cancel))
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+
+	var runErr error
+	output := captureOutput(t, func() {
+		runErr = cancel.Execute(cmd, sh)
+	})
+
+	var cancelled []string
+	for _, call := range runner.Calls() {
+		if call.Command == "scancel" {
+			cancelled = append(cancelled, call.Args...)
+		}
+	}
+	return output, cancelled, runErr
+}
+
+func TestCancelListsRangesAndArrayTasks(t *testing.T) {
+	output, cancelled, err := runCancel(t, "cancel 1001-1002,1005_[4-6] --yes", nil, nil)
+	if err != nil {
+		t.Fatalf("cancel failed: %v", err)
+	}
+	if got := strings.Join(cancelled, " "); got != "1001 1002 1005_4 1005_5 1005_6" {
+		t.Errorf("unexpected scancel arguments: %s", got)
+	}
+	if !strings.Contains(output, "1005_[5-9%2]") || !strings.Contains(output, "Cancelled 5 jobs") {
+		t.Errorf("unexpected output:\n%s", output)
+	}
+}
+
+func TestCancelWholeArrayKeepsPendingEntry(t *testing.T) {
+	output, cancelled, err := runCancel(t, "cancel 1005 1001 -y", nil, nil)
+	if err != nil {
+		t.Fatalf("cancel failed: %v", err)
+	}
+	if got := strings.Join(cancelled, " "); got != "1001 1005_4 1005_[5-9]" {
+		t.Errorf("unexpected scancel arguments: %s", got)
+	}
+	// The pending entry stands for five tasks
+	if !strings.Contains(output, "Cancelled 7 jobs") {
+		t.Errorf("unexpected output:\n%s", output)
+	}
+}
+
+func TestCancelPendingInPartition(t *testing.T) {
+	sh := &answeringShell{answer: true}
+	output, cancelled, err := runCancel(t, "cancel -p gpu -t pd", nil, sh)
+	if err != nil {
+		t.Fatalf("cancel failed: %v", err)
+	}
+	if len(sh.asked) != 1 || sh.asked[0] != "Cancel 1 job?" {
+		t.Errorf("unexpected prompts: %v", sh.asked)
+	}
+	if got := strings.Join(cancelled, " "); got != "1002" {
+		t.Errorf("expected alice's pending gpu job, got %s", got)
+	}
+	if !strings.Contains(output, "eval, final") {
+		t.Errorf("preview is missing the job:\n%s", output)
+	}
+}
+
+func TestCancelByNamePattern(t *testing.T) {
+	_, cancelled, err := runCancel(t, "cancel -n 'tr*' --yes", nil, nil)
+	if err != nil {
+		t.Fatalf("cancel failed: %v", err)
+	}
+	if got := strings.Join(cancelled, " "); got != "1001" {
+		t.Errorf("unexpected scancel arguments: %s", got)
+	}
+}
+
+func TestCancelDeclined(t *testing.T) {
+	sh := &answeringShell{answer: false}
+	output, cancelled, err := runCancel(t, "cancel 1001 1002", nil, sh)
+	if err != nil {
+		t.Fatalf("cancel failed: %v", err)
+	}
+	if len(cancelled) != 0 {
+		t.Errorf("jobs cancelled despite the answer: %v", cancelled)
+	}
+	if !strings.Contains(output, "No jobs cancelled") {
+		t.Errorf("unexpected output:\n%s", output)
+	}
+}
+
+func TestCancelConfirmation(t *testing.T) {
+	// Without a shell to ask, bulk cancellation needs --yes
+	if _, cancelled, err := runCancel(t, "cancel 1001 1002", nil, nil); err == nil || len(cancelled) != 0 {
+		t.Errorf("expected a confirmation error, got %v (cancelled %v)", err, cancelled)
+	}
+
+	cfg := config.Default()
+	cfg.ConfirmDangerous = false
+	sh := &answeringShell{}
+	_, cancelled, err := runCancel(t, "cancel 1001 1002", cfg, sh)
+	if err != nil {
+		t.Fatalf("cancel failed: %v", err)
+	}
+	if len(sh.asked) != 0 || len(cancelled) != 2 {
+		t.Errorf("expected no prompt and two jobs, got prompts %v, cancelled %v", sh.asked, cancelled)
+	}
+}
+
+func TestCancelRejectsInvalidSelector(t *testing.T) {
+	if _, _, err := runCancel(t, "cancel 1001,abc", nil, nil); err == nil {
+		t.Error("expected an error for an invalid job ID")
+	}
+}
+
+func TestShellConfirmReadsInput(t *testing.T) {
+	sh := newFakeShell(t)
+	sh.SetInput(strings.NewReader("yes\n\n"))
+
+	var first, second bool
+	captureOutput(t, func() {
+		first = sh.Confirm("Proceed?")
+		second = sh.Confirm("Again?")
+	})
+	if !first || second {
+		t.Errorf("got %v and %v, want yes then the default no", first, second)
+	}
+}
@@ -1 +1,311 @@
-package test
\ No newline at end of file
+package test
+
+import (
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"slsh/commands"
+	"slsh/config"
+	"slsh/shell"
+	"slsh/slurm"
+)
+
+// captureOutput returns everything fn writes to stdout
+func captureOutput(t *testing.T, fn func()) string {
+	t.Helper()
+	return capture(t, &os.Stdout, fn)
+}
+
+// captureStderr returns everything fn writes to stderr
+func captureStderr(t *testing.T, fn func()) string {
+	t.Helper()
+	return capture(t, &os.Stderr, fn)
+}
+
+func capture(t *testing.T, file **os.File, fn func()) string {
+	t.Helper()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("failed to create pipe: %v", err)
+	}
+
+	saved := *file
+	*file = w
+	defer func() { *file = saved }()
+
+	done := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(r)
+		done <- string(data)
+	}()
+
+	fn()
+	w.Close()
+	return <-done
+}
+
+// flagsOf returns a FlagLookup for the options of handler that take no value
+func flagsOf(handler commands.CommandHandler) shell.FlagLookup {
+	return func(string) map[string]bool { return commands.HandlerFlags(handler) }
+}
+
+// newFakeClient returns a client backed by the built-in fake cluster
+func newFakeClient() (*slurm.Client, *slurm.FakeRunner) {
+	runner := slurm.NewFakeRunner(slurm.DefaultFixtures())
+	return slurm.NewClientWithRunner(runner), runner
+}
+
+func TestSubmitCommandUsesSbatch(t *testing.T) {
+	client, runner := newFakeClient()
+	submit := commands.NewSubmitCommand(client, config.Default())
+
+	cmd := &slurm.Command{
+		Name:    "submit",
+		Args:    []string{"job.sh"},
+		Options: map[string]string{"-p": "gpu"},
+	}
+
+	var err error
+	out := captureOutput(t, func() { err = submit.Execute(cmd, nil) })
+	if err != nil {
+		t.Fatalf("submit failed: %v", err)
+	}
+	if !strings.Contains(out, "Submitted batch job 1003") {
+		t.Errorf("unexpected output: %q", out)
+	}
+
+	calls := runner.Calls()
+	if len(calls) != 1 || calls[0].Command != "sbatch" {
+		t.Fatalf("expected one sbatch call, got %+v", calls)
+	}
+	args := strings.Join(calls[0].Args, " ")
+	if !strings.Contains(args, "--partition=gpu") || !strings.HasSuffix(args, "job.sh") {
+		t.Errorf("unexpected sbatch args: %s", args)
+	}
+}
+
+func TestCancelCommandUsesScancel(t *testing.T) {
+	client, runner := newFakeClient()
+	cancel := commands.NewCancelCommand(client, config.Default())
+
+	cmd := &slurm.Command{Name: "cancel", Args: []string{"1001"}, Options: map[string]string{}}
+
+	var err error
+	out := captureOutput(t, func() { err = cancel.Execute(cmd, nil) })
+	if err != nil {
+		t.Fatalf("cancel failed: %v", err)
+	}
+	if !strings.Contains(out, "Job 1001 cancelled") {
+		t.Errorf("unexpected output: %q", out)
+	}
+
+	calls := runner.Calls()
+	if len(calls) != 1 || calls[0].Command != "scancel" || calls[0].Args[0] != "1001" {
+		t.Errorf("unexpected calls: %+v", calls)
+	}
+}
+
+func TestFakeRunnerPrefersSpecificFixture(t *testing.T) {
+	client, _ := newFakeClient()
+
+	jobs, err := client.ListJobs(&slurm.JobFilter{JobIDs: []string{"1001"}})
+	if err != nil {
+		t.Fatalf("status failed: %v", err)
+	}
+	if len(jobs) != 1 || jobs[0].ID != "1001" {
+		t.Errorf("expected the squeue -j fixture, got %+v", jobs)
+	}
+}
+
+func TestParseJobsKeepsSeparatorsInName(t *testing.T) {
+	output := "7|PENDING|gpu|bob|2|8|2:00:00|2024-01-15T11:00:00|N/A|N/A|0:00||/work|ml|Priority|a,b|c\n"
+
+	jobs, err := slurm.ParseJobs(output)
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	if len(jobs) != 1 {
+		t.Fatalf("expected one job, got %d", len(jobs))
+	}
+
+	job := jobs[0]
+	if job.Name != "a,b|c" || job.User != "bob" || job.Nodes != 2 || job.CPUs != 8 {
+		t.Errorf("unexpected job: %+v", job)
+	}
+	if job.SubmitTime.IsZero() || !job.StartTime.IsZero() {
+		t.Errorf("unexpected times: submit=%v start=%v", job.SubmitTime, job.StartTime)
+	}
+	if job.Reason != "Priority" || job.WorkDir != "/work" || job.Account != "ml" {
+		t.Errorf("unexpected reason, workdir or account: %+v", job)
+	}
+}
+
+func TestQueueCommandRendersTable(t *testing.T) {
+	client, _ := newFakeClient()
+	cfg := config.Default()
+	cfg.ColorOutput = false
+	queue := commands.NewQueueCommand(client, cfg)
+
+	cmd := &slurm.Command{Name: "queue", Args: []string{"alice"}, Options: map[string]string{}}
+
+	var err error
+	out := captureOutput(t, func() { err = queue.Execute(cmd, nil) })
+	if err != nil {
+		t.Fatalf("queue failed: %v", err)
+	}
+	for _, want := range []string{"JOBID", "eval, final", "(Resources)", "node001"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("queue output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestFakeRunnerMissingFixture(t *testing.T) {
+	client, _ := newFakeClient()
+
+	result, err := client.Execute("sprio")
+	if err == nil {
+		t.Fatal("expected an error for a command without fixture")
+	}
+	if result.Success || result.ExitCode != 1 {
+		t.Errorf("unexpected result: %+v", result)
+	}
+}
+
+func TestRecordingRunnerWritesFixtures(t *testing.T) {
+	path := t.TempDir() + "/fixtures.json"
+	source := slurm.NewFakeRunner([]slurm.Fixture{
+		{Command: "squeue", Output: "JOBID\n42\n"},
+	})
+
+	recorder, err := slurm.NewRecordingRunner(source, path)
+	if err != nil {
+		t.Fatalf("failed to create recorder: %v", err)
+	}
+
+	client := slurm.NewClientWithRunner(recorder)
+	if _, err := client.GetQueue("bob"); err != nil {
+		t.Fatalf("queue failed: %v", err)
+	}
+
+	replay, err := slurm.NewFakeRunnerFromFile(path)
+	if err != nil {
+		t.Fatalf("failed to load recorded fixtures: %v", err)
+	}
+	result, err := slurm.NewClientWithRunner(replay).GetQueue("bob")
+	if err != nil {
+		t.Fatalf("replay failed: %v", err)
+	}
+	if result.Output != "JOBID\n42\n" {
+		t.Errorf("unexpected replayed output: %q", result.Output)
+	}
+}
+
+func TestListNodesParsesStateFlags(t *testing.T) {
+	client, _ := newFakeClient()
+
+	nodes, err := client.ListNodes()
+	if err != nil {
+		t.Fatalf("nodes failed: %v", err)
+	}
+	if len(nodes) != 4 {
+		t.Fatalf("expected 4 nodes, got %d", len(nodes))
+	}
+
+	byName := make(map[string]slurm.Node)
+	for _, node := range nodes {
+		byName[node.Name] = node
+	}
+
+	if n := byName["node001"]; n.State != slurm.NodeStateMixed || n.CPUsAlloc != 16 || n.CPUs != 32 || n.Memory != 128000 {
+		t.Errorf("unexpected node001: %+v", n)
+	}
+	if n := byName["node002"]; n.State != slurm.NodeStateIdle || len(n.Flags) != 1 || n.Flags[0] != slurm.NodeFlagPoweredDown {
+		t.Errorf("unexpected node002: %+v", n)
+	}
+	n := byName["node003"]
+	if n.State != slurm.NodeStateDrain || n.Reason != "disk failure" {
+		t.Errorf("unexpected node003: %+v", n)
+	}
+	if strings.Join(n.Flags, ",") != slurm.NodeFlagNotResponding+","+slurm.NodeFlagDrain {
+		t.Errorf("unexpected node003 flags: %v", n.Flags)
+	}
+	if n := byName["gpu001"]; n.Partition != "gpu,debug" || n.Features != "a100,nvlink" {
+		t.Errorf("unexpected gpu001: %+v", n)
+	}
+}
+
+func TestListPartitionsExpandsNodes(t *testing.T) {
+	client, _ := newFakeClient()
+
+	partitions, err := client.ListPartitions()
+	if err != nil {
+		t.Fatalf("partitions failed: %v", err)
+	}
+	if len(partitions) != 3 {
+		t.Fatalf("expected 3 partitions, got %d", len(partitions))
+	}
+
+	compute := partitions[0]
+	if compute.Name != "compute" || !compute.Default || compute.MaxNodes != 0 || compute.DefaultTime != "1:00:00" {
+		t.Errorf("unexpected compute partition: %+v", compute)
+	}
+	if strings.Join(compute.Nodes, ",") != "node001,node002,node003" {
+		t.Errorf("unexpected compute nodes: %v", compute.Nodes)
+	}
+	if gpu := partitions[1]; gpu.MaxNodes != 4 || gpu.DefaultTime != "" || gpu.CPUsAlloc != 32 {
+		t.Errorf("unexpected gpu partition: %+v", gpu)
+	}
+}
+
+func TestRunCommandNormalizesNodeList(t *testing.T) {
+	client, runner := newFakeClient()
+	run := commands.NewRunCommand(client, config.Default())
+
+	cmd := &slurm.Command{
+		Name:    "run",
+		Args:    []string{"hostname"},
+		Options: map[string]string{"-w": "node3,node1,node2", "-x": "gpu[01-02]"},
+	}
+
+	var err error
+	captureOutput(t, func() { err = run.Execute(cmd, nil) })
+	if err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+
+	args := strings.Join(runner.Calls()[0].Args, " ")
+	if !strings.Contains(args, "--nodelist=node[1-3]") || !strings.Contains(args, "--exclude=gpu[01-02]") {
+		t.Errorf("unexpected srun args: %s", args)
+	}
+	if strings.Contains(args, "--nodes=") {
+		t.Errorf("default node count should not be applied with a node list: %s", args)
+	}
+
+	cmd.Options["-x"] = "node2"
+	if err := run.Execute(cmd, nil); err == nil {
+		t.Error("expected an error when a node is both requested and excluded")
+	}
+}
+
+func TestRunExclusiveKeepsCommand(t *testing.T) {
+	client, runner := newFakeClient()
+	run := commands.NewRunCommand(client, config.Default())
+
+	cmd, err := shell.ParseCommand("run --exclusive hostname", flagsOf(run))
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	captureOutput(t, func() { err = run.Execute(cmd, nil) })
+	if err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+
+	args := runner.Calls()[0].Args
+	if joined := strings.Join(args, " "); !strings.Contains(joined, "--exclusive") || args[len(args)-1] != "hostname" {
+		t.Errorf("--exclusive took the command as its value: %s", joined)
+	}
+}
@@ -0,0 +1,161 @@
+package test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"slsh/commands"
+	"slsh/config"
+	"slsh/shell"
+	"slsh/slurm"
+)
+
+// runEff runs an eff command line against the fake cluster
+func runEff(t *testing.T, line string) (string, []slurm.Call, error) {
+	t.Helper()
+	t.Setenv("USER", "alice")
+
+	client, runner := newFakeClient()
+	cfg := config.Default()
+	cfg.ColorOutput = false
+
+	eff := commands.NewEffCommand(client, cfg)
+	cmd, err := shell.ParseCommand(line, flagsOf(eff))
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+
+	output := captureOutput(t, func() { err = eff.Execute(cmd, nil) })
+	return output, runner.Calls(), err
+}
+
+func TestParseJobUsage(t *testing.T) {
+	output := "42|COMPLETED|00:10:00|01:00:00|4|2|00:30:00.500|4000Mn||alice|physics|compute|2024-01-14T08:00:00|2024-01-14T08:10:00|sim\n" +
+		"42.batch|COMPLETED|00:10:00||2|1|00:10:00||512M||physics||2024-01-14T08:00:00|2024-01-14T08:10:00|batch\n" +
+		"42.0|COMPLETED|00:09:00||4|2|00:20:00.500||1536000K||physics||2024-01-14T08:01:00|2024-01-14T08:10:00|sim\n" +
+		"43|FAILED|00:01:00|00:30:00|8|1|00:00:30|1000Mc||alice|physics|compute|2024-01-14T09:00:00|2024-01-14T09:01:00|other\n"
+
+	usages, err := slurm.ParseJobUsage(output)
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	if len(usages) != 2 {
+		t.Fatalf("expected 2 jobs, got %d", len(usages))
+	}
+
+	job := usages[0]
+	if job.ID != "42" || job.Nodes != 2 || job.CPUs != 4 || job.Name != "sim" {
+		t.Errorf("unexpected job: %+v", job)
+	}
+	if job.TotalCPU != 30*time.Minute+500*time.Millisecond {
+		t.Errorf("unexpected TotalCPU: %s", job.TotalCPU)
+	}
+	if job.ReqMemMB != 8000 || job.MemoryPerNodeMB() != 4000 || job.MemoryPerCPUMB() != 2000 {
+		t.Errorf("unexpected memory request: %d MB", job.ReqMemMB)
+	}
+	// MaxRSS comes from the largest step
+	if job.MaxRSSMB != 1500 {
+		t.Errorf("unexpected MaxRSS: %d MB", job.MaxRSSMB)
+	}
+	if eff, ok := job.CPUEfficiency(); !ok || eff < 0.75 || eff > 0.76 {
+		t.Errorf("unexpected CPU efficiency: %v", eff)
+	}
+	if use, ok := job.WalltimeUse(); !ok || use < 0.16 || use > 0.17 {
+		t.Errorf("unexpected walltime use: %v", use)
+	}
+
+	if usages[1].ReqMemMB != 8000 {
+		t.Errorf("per-CPU memory not multiplied: %d MB", usages[1].ReqMemMB)
+	}
+
+	if _, err := slurm.ParseJobUsage("42|COMPLETED|00:10:00\n"); err == nil {
+		t.Error("expected an error for a short line")
+	}
+}
+
+func TestEffReportsWastedResources(t *testing.T) {
+	output, _, err := runEff(t, "eff 999")
+	if err != nil {
+		t.Fatalf("eff failed: %v", err)
+	}
+	for _, want := range []string{
+		"Job 999 (big model): OUT_OF_MEMORY",
+		"1 node, 8 CPUs, 32.0 GB memory (4.0 GB per CPU)",
+		"9.7%",
+		"Used about 1 of 8 CPUs",
+		"Ran out of memory with 32.0 GB per node",
+		"--mem=64G",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output lacks %q:\n%s", want, output)
+		}
+	}
+
+	output, _, err = runEff(t, "eff 998")
+	if err != nil {
+		t.Fatalf("eff failed: %v", err)
+	}
+	if !strings.Contains(output, "Used at most 3.0 GB of 16.0 GB per node") || !strings.Contains(output, "--mem=4G would do") {
+		t.Errorf("expected advice on the memory request:\n%s", output)
+	}
+	if strings.Contains(output, "CPUs on average") {
+		t.Errorf("unexpected CPU warning for a busy job:\n%s", output)
+	}
+
+	output, _, err = runEff(t, "eff 1001")
+	if err != nil {
+		t.Fatalf("eff failed: %v", err)
+	}
+	if !strings.Contains(output, "still running") || strings.Contains(output, "⚠") {
+		t.Errorf("running jobs should not be judged yet:\n%s", output)
+	}
+
+	if _, _, err := runEff(t, "eff 4242"); err == nil {
+		t.Error("expected an error for an unknown job")
+	}
+}
+
+func TestEffSummarizesRange(t *testing.T) {
+	output, calls, err := runEff(t, "eff --since 2024-01-01 --until 2024-02-01")
+	if err != nil {
+		t.Fatalf("eff failed: %v", err)
+	}
+
+	args := strings.Join(callArgs(calls, "sacct"), " ")
+	for _, want := range []string{"-u alice", "-S 2024-01-01T00:00:00", "-E 2024-02-01T00:00:00"} {
+		if !strings.Contains(args, want) {
+			t.Errorf("sacct arguments lack %q: %s", want, args)
+		}
+	}
+
+	// Running and pending jobs say nothing about their requests yet
+	for _, id := range []string{"1001", "1002"} {
+		if strings.Contains(output, "\n"+id+" ") {
+			t.Errorf("job %s should not be summarized:\n%s", id, output)
+		}
+	}
+	for _, want := range []string{
+		"3 jobs",
+		"8.8 core-hours allocated, 7.9 used (89.0% CPU efficiency)",
+		"1 job used less than half of the CPUs requested",
+		"1 job ran out of memory",
+		"1 job hit the time limit",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output lacks %q:\n%s", want, output)
+		}
+	}
+}
+
+func TestEffRejectsBadRanges(t *testing.T) {
+	if _, _, err := runEff(t, "eff --since yesterday-ish"); err == nil || !strings.Contains(err.Error(), "invalid time") {
+		t.Errorf("expected an invalid time error, got %v", err)
+	}
+	if _, _, err := runEff(t, "eff --since 2024-02-01 --until 2024-01-01"); err == nil {
+		t.Error("expected an error when since is after until")
+	}
+	if _, _, err := runEff(t, "eff 999 --since 7d"); err == nil {
+		t.Error("expected an error for a range with a job ID")
+	}
+}
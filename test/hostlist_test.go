@@ -0,0 +1,91 @@
+package test
+
+import (
+	"strings"
+	"testing"
+
+	"slsh/slurm/hostlist"
+)
+
+func TestHostlistExpand(t *testing.T) {
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{"node1", "node1"},
+		{"gpu[001-003,020],cpu-a[1-3]", "gpu001,gpu002,gpu003,gpu020,cpu-a1,cpu-a2,cpu-a3"},
+		{"n[8-10]", "n8,n9,n10"},
+		{"n[08-10]", "n08,n09,n10"},
+		{"rack[1-2]-node[01-02]", "rack1-node01,rack1-node02,rack2-node01,rack2-node02"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		hosts, err := hostlist.Expand(tt.expr)
+		if err != nil {
+			t.Errorf("Expand(%q) failed: %v", tt.expr, err)
+			continue
+		}
+		if got := strings.Join(hosts, ","); got != tt.want {
+			t.Errorf("Expand(%q) = %s, want %s", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestHostlistExpandErrors(t *testing.T) {
+	for _, expr := range []string{"n[1-", "n]1[", "n[a-b]", "n[5-1]", "n[[1]]", "a,,b"} {
+		if _, err := hostlist.Expand(expr); err == nil {
+			t.Errorf("Expand(%q) should fail", expr)
+		}
+	}
+}
+
+func TestHostlistCompress(t *testing.T) {
+	tests := []struct {
+		hosts string
+		want  string
+	}{
+		{"gpu001,gpu002,gpu003,gpu020", "gpu[001-003,020]"},
+		{"n9,n10,n11", "n[9-11]"},
+		{"n09,n10,n1", "n[1,09-10]"},
+		{"rack1-node01,rack1-node02,rack2-node01,rack2-node02", "rack[1-2]-node[01-02]"},
+		{"login,node2,node1,node2", "login,node[1-2]"},
+	}
+
+	for _, tt := range tests {
+		if got := hostlist.Compress(strings.Split(tt.hosts, ",")); got != tt.want {
+			t.Errorf("Compress(%s) = %s, want %s", tt.hosts, got, tt.want)
+		}
+	}
+}
+
+func TestHostlistRoundTrip(t *testing.T) {
+	expr := "a[1-3,7]b[01-02],login[1-2],gpu[001-016,020]"
+
+	hosts, err := hostlist.Expand(expr)
+	if err != nil {
+		t.Fatalf("Expand failed: %v", err)
+	}
+	again, err := hostlist.Expand(hostlist.Compress(hosts))
+	if err != nil {
+		t.Fatalf("Expand of compressed list failed: %v", err)
+	}
+	if strings.Join(again, ",") != strings.Join(hosts, ",") {
+		t.Errorf("round trip changed hosts:\n%v\n%v", hosts, again)
+	}
+}
+
+func TestHostlistCountAndIntersect(t *testing.T) {
+	n, err := hostlist.Count("gpu[001-016,020],cpu-a[1-3]")
+	if err != nil || n != 20 {
+		t.Errorf("Count = %d, %v; want 20", n, err)
+	}
+
+	common, err := hostlist.Intersect("node[01-10]", "node[08-12],login")
+	if err != nil {
+		t.Fatalf("Intersect failed: %v", err)
+	}
+	if got := hostlist.Compress(common); got != "node[08-10]" {
+		t.Errorf("Intersect = %s, want node[08-10]", got)
+	}
+}
@@ -0,0 +1,75 @@
+package test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"slsh/slurm"
+)
+
+// runInterrupted starts a command through the client and interrupts it
+// shortly after it started
+func runInterrupted(t *testing.T, command string, args ...string) (*slurm.CommandResult, error, time.Duration) {
+	t.Helper()
+
+	client := slurm.NewClient()
+	type outcome struct {
+		result *slurm.CommandResult
+		err    error
+	}
+	done := make(chan outcome, 1)
+
+	start := time.Now()
+	go func() {
+		result, err := client.Execute(command, args...)
+		done <- outcome{result, err}
+	}()
+
+	// Give the child time to set up its signal handlers, then interrupt it
+	time.Sleep(200 * time.Millisecond)
+	deadline := time.Now().Add(5 * time.Second)
+	for !client.Interrupt() {
+		if time.Now().After(deadline) {
+			t.Fatal("command never started")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	select {
+	case o := <-done:
+		return o.result, o.err, time.Since(start)
+	case <-time.After(10 * time.Second):
+		t.Fatal("interrupted command did not return")
+		return nil, nil, 0
+	}
+}
+
+func TestInterruptCancelsRunningCommand(t *testing.T) {
+	_, err, elapsed := runInterrupted(t, "sleep", "30")
+	if !errors.Is(err, slurm.ErrInterrupted) {
+		t.Errorf("expected ErrInterrupted, got %v", err)
+	}
+	if elapsed > 5*time.Second {
+		t.Errorf("interrupt took too long: %v", elapsed)
+	}
+}
+
+func TestInterruptForwardsSIGINT(t *testing.T) {
+	// The child gets SIGINT, so it can clean up the way srun cancels its step
+	script := `trap 'echo cleanup; exit 3' INT; echo started; while :; do sleep 0.05; done`
+	result, err, _ := runInterrupted(t, "sh", "-c", script)
+	if !errors.Is(err, slurm.ErrInterrupted) {
+		t.Errorf("expected ErrInterrupted, got %v", err)
+	}
+	if result == nil || !strings.Contains(result.Output, "cleanup") {
+		t.Errorf("child did not handle SIGINT: %+v", result)
+	}
+}
+
+func TestInterruptWithoutCommand(t *testing.T) {
+	if slurm.NewClient().Interrupt() {
+		t.Error("Interrupt should report false when nothing runs")
+	}
+}
@@ -0,0 +1,149 @@
+package test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"slsh/commands"
+	"slsh/config"
+	"slsh/shell"
+	"slsh/slurm"
+)
+
+// runJhist runs a jhist command line against the fake cluster
+func runJhist(t *testing.T, line string, history commands.JobHistory) (string, []slurm.Call, error) {
+	t.Helper()
+	t.Setenv("USER", "alice")
+
+	client, runner := newFakeClient()
+	cfg := config.Default()
+	cfg.ColorOutput = false
+
+	jhist := commands.NewJhistCommand(client, cfg, history)
+	cmd, err := shell.ParseCommand(line, flagsOf(jhist))
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+
+	output := captureOutput(t, func() { err = jhist.Execute(cmd, nil) })
+	return output, runner.Calls(), err
+}
+
+func TestHistoryLinksJobs(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	// Lines written before jobs were recorded still load
+	old := "1705222800|true|1000|run hostname\n"
+	if err := os.WriteFile(filepath.Join(home, ".slsh_history"), []byte(old), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	history := shell.NewHistory(100)
+	if err := history.Load(); err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	history.AddWithJobs("submit --array 0-9 sweep.sh", true, 0, "1005")
+	history.AddWithJobs("submit -- echo a|b", true, 0, "1003", "1004")
+	history.Add("queue", true, 0)
+	if err := history.Save(); err != nil {
+		t.Fatalf("save failed: %v", err)
+	}
+
+	loaded := shell.NewHistory(100)
+	if err := loaded.Load(); err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	entries := loaded.GetAll()
+	if len(entries) != 4 || entries[0].Command != "run hostname" || entries[2].Command != "submit -- echo a|b" {
+		t.Fatalf("unexpected entries: %+v", entries)
+	}
+
+	tests := []struct {
+		jobID   string
+		index   int
+		command string
+	}{
+		{"1005_3", 2, "submit --array 0-9 sweep.sh"},
+		{"1004", 3, "submit -- echo a|b"},
+	}
+	for _, tt := range tests {
+		index, command, ok := loaded.FindJob(tt.jobID)
+		if !ok || index != tt.index || command != tt.command {
+			t.Errorf("FindJob(%s) = %d, %q, %v", tt.jobID, index, command, ok)
+		}
+	}
+	if _, _, ok := loaded.FindJob("100"); ok {
+		t.Error("found a job that was not submitted from the shell")
+	}
+}
+
+func TestHistoryKeepsRepeatedSubmissions(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	history := shell.NewHistory(100)
+	history.AddWithJobs("submit job.sh", true, 0, "1003")
+	history.Add("queue", true, 0)
+	history.Add("queue", true, 0)
+	history.AddWithJobs("submit job.sh", true, 0, "1004")
+	history.AddWithJobs("submit job.sh", true, 0, "1005")
+
+	if entries := history.GetAll(); len(entries) != 4 {
+		t.Fatalf("unexpected entries: %+v", entries)
+	}
+	for jobID, want := range map[string]int{"1003": 1, "1004": 3, "1005": 4} {
+		if index, _, ok := history.FindJob(jobID); !ok || index != want {
+			t.Errorf("FindJob(%s) = %d, %v, want entry %d", jobID, index, ok, want)
+		}
+	}
+}
+
+func TestJhistListsAccounting(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	history := shell.NewHistory(100)
+	history.AddWithJobs("submit -p gpu --mem 32G model.sh", true, 0, "999")
+
+	output, calls, err := runJhist(t, "jhist --since 2024-01-01 --until 2024-02-01 -t F,oom -p gpu", history)
+	if err != nil {
+		t.Fatalf("jhist failed: %v", err)
+	}
+
+	args := strings.Join(callArgs(calls, "sacct"), " ")
+	for _, want := range []string{"-u alice", "-s FAILED,OUT_OF_MEMORY", "-r gpu", "-S 2024-01-01T00:00:00", "-E 2024-02-01T00:00:00"} {
+		if !strings.Contains(args, want) {
+			t.Errorf("sacct arguments lack %q: %s", want, args)
+		}
+	}
+
+	for _, want := range []string{"999", "OUT_OF_MEMORY", "0 (signal 125)", "5:12", "gpu001", "#1 submit -p gpu --mem 32G model.sh", "1 job: 1 OUT_OF_MEMORY"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output lacks %q:\n%s", want, output)
+		}
+	}
+	if strings.Contains(output, "998") {
+		t.Errorf("filtered jobs are listed:\n%s", output)
+	}
+}
+
+func TestJhistFiltersNames(t *testing.T) {
+	output, calls, err := runJhist(t, "jhist --since 2024-01-01 --name 'eval*'", nil)
+	if err != nil {
+		t.Fatalf("jhist failed: %v", err)
+	}
+	// Patterns are matched here, since sacct only knows exact names
+	if args := strings.Join(callArgs(calls, "sacct"), " "); strings.Contains(args, "--name") {
+		t.Errorf("patterns passed to sacct: %s", args)
+	}
+	if !strings.Contains(output, "eval, final") || strings.Contains(output, "prep") {
+		t.Errorf("unexpected jobs:\n%s", output)
+	}
+
+	if _, _, err := runJhist(t, "jhist --since soon", nil); err == nil {
+		t.Error("expected an error for an invalid time")
+	}
+	if _, _, err := runJhist(t, "jhist 999", nil); err == nil {
+		t.Error("expected an error for arguments")
+	}
+}
@@ -0,0 +1,147 @@
+package test
+
+import (
+	"strings"
+	"testing"
+
+	"slsh/commands"
+	"slsh/config"
+	"slsh/shell"
+	"slsh/slurm"
+)
+
+func newSessionJobs() *shell.SessionJobs {
+	jobs := shell.NewSessionJobs()
+	jobs.Add("1003", "train")
+	jobs.Add("1004", "eval")
+	jobs.Add("1005", "train")
+	jobs.Add("1005", "train")
+	return jobs
+}
+
+func TestSessionJobReferences(t *testing.T) {
+	jobs := newSessionJobs()
+
+	tests := map[string]string{
+		"%last":  "1005",
+		"%-1":    "1005",
+		"%-2":    "1004",
+		"%-3":    "1003",
+		"%train": "1005",
+		"%ev*":   "1004",
+	}
+	for ref, want := range tests {
+		got, ok, err := jobs.Resolve(ref)
+		if err != nil || !ok || got != want {
+			t.Errorf("%s: got %q, %v, %v; want %s", ref, got, ok, err, want)
+		}
+	}
+
+	for _, ref := range []string{"%-4", "%-0"} {
+		if _, _, err := jobs.Resolve(ref); err == nil {
+			t.Errorf("%s: expected an error", ref)
+		}
+	}
+	for _, word := range []string{"%s", "1003", "%5d", "train"} {
+		if _, ok, err := jobs.Resolve(word); ok || err != nil {
+			t.Errorf("%s: not a reference, got ok=%v err=%v", word, ok, err)
+		}
+	}
+}
+
+func TestSessionJobsResolveCommand(t *testing.T) {
+	jobs := newSessionJobs()
+
+	cmd, err := shell.ParseCommand("cancel %-2,%last %s -j %train -o %x.out", nil)
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	if err := jobs.ResolveCommand(cmd); err != nil {
+		t.Fatalf("resolve failed: %v", err)
+	}
+	if got := strings.Join(cmd.Args, " "); got != "1004,1005 %s" {
+		t.Errorf("unexpected args: %s", got)
+	}
+	if cmd.Options["-j"] != "1005" || cmd.Options["-o"] != "%x.out" {
+		t.Errorf("unexpected options: %v", cmd.Options)
+	}
+}
+
+func TestShellRemembersSubmittedJobs(t *testing.T) {
+	sh := newFakeShell(t)
+
+	if err := sh.ExecuteDirectCommand("status %last"); err == nil || !strings.Contains(err.Error(), "no jobs submitted") {
+		t.Errorf("expected an empty session error, got %v", err)
+	}
+
+	var err error
+	captureOutput(t, func() { err = sh.ExecuteDirectCommand("submit -J train job.sh") })
+	if err != nil {
+		t.Fatalf("submit failed: %v", err)
+	}
+	if jobs := sh.GetSessionJobs().List(); len(jobs) != 1 || jobs[0].ID != "1003" || jobs[0].Name != "train" {
+		t.Fatalf("unexpected session jobs: %+v", jobs)
+	}
+
+	out := captureOutput(t, func() { err = sh.ExecuteDirectCommand("cancel %train") })
+	if err != nil {
+		t.Fatalf("cancel failed: %v", err)
+	}
+	if !strings.Contains(out, "Job 1003 cancelled") {
+		t.Errorf("unexpected output: %q", out)
+	}
+}
+
+func TestParseSubmittedJob(t *testing.T) {
+	tests := []struct {
+		output, id, cluster string
+	}{
+		{"Submitted batch job 1234\n", "1234", ""},
+		{"Submitted batch job 1234 on cluster west\n", "1234", "west"},
+		{"1234\n", "1234", ""},
+		{"1234;west\n", "1234", "west"},
+		{"sbatch: Warning: low priority\n1234;west\n", "1234", "west"},
+	}
+	for _, tt := range tests {
+		id, cluster, ok := slurm.ParseSubmittedJob(tt.output)
+		if !ok || id != tt.id || cluster != tt.cluster {
+			t.Errorf("%q: got %q, %q, %v", tt.output, id, cluster, ok)
+		}
+	}
+
+	if _, _, ok := slurm.ParseSubmittedJob("sbatch: error: invalid partition\n"); ok {
+		t.Error("expected no job ID in an error")
+	}
+
+	client, _ := newFakeClient()
+	cmd, _ := shell.ParseCommand("submit --parsable job.sh", flagsOf(commands.NewSubmitCommand(client, config.Default())))
+	if len(cmd.Args) != 1 || cmd.Args[0] != "job.sh" {
+		t.Errorf("--parsable took the script as its value: %+v", cmd)
+	}
+}
+
+func TestRunRecordsJob(t *testing.T) {
+	sh := newFakeShell(t)
+
+	// run asks srun to name the job, without showing srun's progress
+	var err error
+	out := captureStderr(t, func() { err = sh.ExecuteDirectCommand("run hostname") })
+	if err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	if jobs := sh.GetSessionJobs().List(); len(jobs) != 1 || jobs[0].ID != "1011" || jobs[0].Name != "hostname" {
+		t.Errorf("unexpected session jobs: %+v", jobs)
+	}
+	if strings.Contains(out, "srun:") {
+		t.Errorf("unexpected srun progress:\n%s", out)
+	}
+
+	// With -v the user sees everything srun says
+	out = captureStderr(t, func() { err = sh.ExecuteDirectCommand("run -v -J probe hostname") })
+	if err != nil {
+		t.Fatalf("run -v failed: %v", err)
+	}
+	if !strings.Contains(out, "srun: jobid 1011") || !strings.Contains(out, "srun: launching StepId=1011.0") {
+		t.Errorf("expected srun progress:\n%s", out)
+	}
+}
@@ -0,0 +1,132 @@
+package test
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+
+	"slsh/slurm"
+)
+
+// newJSONFakeClient returns a client for a fake Slurm release with --json
+// support, answering from the same cluster as the text fixtures
+func newJSONFakeClient(t *testing.T, extra ...slurm.Fixture) (*slurm.Client, *slurm.FakeRunner) {
+	t.Helper()
+
+	jobs, nodes, partitions := fakeClusterJSON(t)
+	encode := func(v interface{}) string {
+		data, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+
+	fixtures := append(extra,
+		slurm.Fixture{Command: "sinfo", Args: []string{"--version"}, Output: "slurm 23.11.4\n"},
+		slurm.Fixture{Command: "squeue", Args: []string{"--json"}, Output: encode(jobs)},
+		slurm.Fixture{Command: "scontrol", Args: []string{"--json", "show", "nodes"}, Output: encode(nodes)},
+		slurm.Fixture{Command: "scontrol", Args: []string{"--json", "show", "partitions"}, Output: encode(partitions)},
+	)
+	runner := slurm.NewFakeRunner(append(fixtures, slurm.DefaultFixtures()...))
+	return slurm.NewClientWithRunner(runner), runner
+}
+
+func TestJSONOutputMatchesFormatOutput(t *testing.T) {
+	textClient, _ := newFakeClient()
+	jsonClient, runner := newJSONFakeClient(t)
+
+	assertSameCluster(t, textClient, jsonClient)
+
+	for _, call := range runner.Calls() {
+		for _, arg := range call.Args {
+			if len(arg) > 9 && arg[:9] == "--format=" {
+				t.Errorf("format-string query used despite JSON support: %s %v", call.Command, call.Args)
+			}
+		}
+	}
+}
+
+func TestJSONOutputFallsBackWhenUnsupported(t *testing.T) {
+	unsupported := slurm.Fixture{
+		Command:  "squeue",
+		Args:     []string{"--json", "-u"},
+		Error:    "squeue: unrecognized option '--json'\n",
+		ExitCode: 1,
+	}
+	textClient, _ := newFakeClient()
+	jsonClient, runner := newJSONFakeClient(t, unsupported)
+
+	jobs, err := jsonClient.ListJobs(&slurm.JobFilter{Users: []string{"alice"}})
+	if err != nil {
+		t.Fatalf("jobs failed: %v", err)
+	}
+	want, _ := textClient.ListJobs(&slurm.JobFilter{Users: []string{"alice"}})
+	if len(jobs) != len(want) || jobs[1].Name != want[1].Name {
+		t.Errorf("unexpected fallback jobs: %+v", jobs)
+	}
+
+	// Once JSON failed, the session sticks to format strings
+	before := len(runner.Calls())
+	if _, err := jsonClient.ListNodes(); err != nil {
+		t.Fatalf("nodes failed: %v", err)
+	}
+	if call := runner.Calls()[before]; call.Command != "sinfo" {
+		t.Errorf("expected sinfo after JSON fallback, got %s %v", call.Command, call.Args)
+	}
+}
+
+func TestJSONOutputReportsPluginErrors(t *testing.T) {
+	missingParser := slurm.Fixture{
+		Command:  "squeue",
+		Args:     []string{"--json", "-u"},
+		Error:    "squeue: error: cannot find data_parser plugin for data_parser/v0.0.40\n",
+		ExitCode: 1,
+	}
+	client, _ := newJSONFakeClient(t, missingParser)
+	if _, err := client.ListJobs(&slurm.JobFilter{Users: []string{"alice"}}); err != nil {
+		t.Errorf("expected a fallback without a data_parser plugin, got %v", err)
+	}
+
+	// A broken plugin is an error, not a missing JSON feature
+	brokenAuth := slurm.Fixture{
+		Command:  "squeue",
+		Args:     []string{"--json", "-u"},
+		Error:    "squeue: error: Couldn't load specified plugin name for auth/munge: Plugin init() callback failed\n",
+		ExitCode: 1,
+	}
+	client, runner := newJSONFakeClient(t, brokenAuth)
+	if _, err := client.ListJobs(&slurm.JobFilter{Users: []string{"alice"}}); err == nil || !strings.Contains(err.Error(), "auth/munge") {
+		t.Errorf("expected the plugin error, got %v", err)
+	}
+	for _, call := range runner.Calls() {
+		if call.Command == "squeue" && !slices.Contains(call.Args, "--json") {
+			t.Errorf("fell back to format strings after a plugin error: %v", call.Args)
+		}
+	}
+}
+
+func TestSlurmVersionDetectedOnce(t *testing.T) {
+	client, runner := newJSONFakeClient(t)
+
+	client.ListJobs(nil)
+	client.ListNodes()
+	v, err := client.Version()
+	if err != nil {
+		t.Fatalf("version failed: %v", err)
+	}
+	if v.Major != 23 || v.Minor != 11 || v.Patch != 4 {
+		t.Errorf("unexpected version: %s", v)
+	}
+
+	count := 0
+	for _, call := range runner.Calls() {
+		if call.Command == "sinfo" && len(call.Args) == 1 && call.Args[0] == "--version" {
+			count++
+		}
+	}
+	if count != 1 {
+		t.Errorf("expected one version check, got %d", count)
+	}
+}
@@ -0,0 +1,147 @@
+package test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"slsh/commands"
+	"slsh/config"
+	"slsh/shell"
+	"slsh/slurm"
+)
+
+func TestJobDetailOutputPaths(t *testing.T) {
+	tests := []struct {
+		detail slurm.JobDetail
+		stdout string
+		stderr string
+	}{
+		{
+			slurm.JobDetail{JobRecord: slurm.JobRecord{ID: "1001", WorkDir: "/home/alice/train"}},
+			"/home/alice/train/slurm-1001.out",
+			"/home/alice/train/slurm-1001.out",
+		},
+		{
+			slurm.JobDetail{JobRecord: slurm.JobRecord{ID: "1001", Name: "train", User: "alice", NodeList: "node[003-004]",
+				WorkDir: "/home/alice/train", StdOut: "logs/%x-%j.out", StdErr: "/scratch/%u/%N-%j.err"}},
+			"/home/alice/train/logs/train-1001.out",
+			"/scratch/alice/node003-1001.err",
+		},
+		{
+			slurm.JobDetail{JobRecord: slurm.JobRecord{ID: "1005_3", Name: "sweep", WorkDir: "/home/alice/sweep"}},
+			"/home/alice/sweep/slurm-1005_3.out",
+			"/home/alice/sweep/slurm-1005_3.out",
+		},
+		{
+			// The job ID of a task is unknown once it has left the queue
+			slurm.JobDetail{JobRecord: slurm.JobRecord{ID: "1005_3", WorkDir: "/w", StdOut: "%A_%2a.out", StdErr: "%j.err"}},
+			"/w/1005_03.out",
+			"/w/%j.err",
+		},
+		{
+			slurm.JobDetail{JobRecord: slurm.JobRecord{ID: "1009", WorkDir: "/w", StdOut: "%A_%a-%j.out"},
+				ArrayJobID: "1005", ArrayTaskID: "4"},
+			"/w/1005_4-1009.out",
+			"/w/1005_4-1009.out",
+		},
+	}
+	for _, tt := range tests {
+		stdout, stderr := tt.detail.OutputPaths()
+		if stdout != tt.stdout || stderr != tt.stderr {
+			t.Errorf("%s: got %s and %s, want %s and %s", tt.detail.ID, stdout, stderr, tt.stdout, tt.stderr)
+		}
+	}
+}
+
+// runLogs runs a logs command line for a finished job 3001 whose output
+// files are in dir
+func runLogs(t *testing.T, dir, line string) (string, error) {
+	t.Helper()
+
+	client, runner := newFakeClient()
+	runner.Add(slurm.Fixture{
+		Command: "scontrol",
+		Args:    []string{"show", "job", "3001"},
+		Output: "JobId=3001 JobName=train\n" +
+			"   UserId=alice(1000) GroupId=alice(1000) MCS_label=N/A\n" +
+			"   JobState=COMPLETED Reason=None Dependency=(null)\n" +
+			"   WorkDir=" + dir + "\n" +
+			"   StdErr=" + dir + "/%x-%j.err\n" +
+			"   StdOut=" + dir + "/%x-%j.out\n",
+	})
+
+	cfg := config.Default()
+	cfg.ColorOutput = false
+
+	logs := commands.NewLogsCommand(client, cfg)
+	cmd, err := shell.ParseCommand(line, flagsOf(logs))
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+
+	var runErr error
+	output := captureOutput(t, func() {
+		runErr = logs.Execute(cmd, nil)
+	})
+	return output, runErr
+}
+
+func TestLogsPrintsTails(t *testing.T) {
+	dir := t.TempDir()
+	var out strings.Builder
+	for i := 1; i <= 30; i++ {
+		fmt.Fprintf(&out, "step %d\n", i)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "train-3001.out"), []byte(out.String()), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "train-3001.err"), []byte("warning\nTraceback: oops"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	output, err := runLogs(t, dir, "logs 3001 -n 5")
+	if err != nil {
+		t.Fatalf("logs failed: %v", err)
+	}
+	if !strings.Contains(output, "step 26\nstep 27\nstep 28\nstep 29\nstep 30\n") || strings.Contains(output, "step 25\n") {
+		t.Errorf("expected the last 5 lines of stdout:\n%s", output)
+	}
+	if !strings.Contains(output, "==> "+filepath.Join(dir, "train-3001.err")+" (stderr) <==") ||
+		!strings.Contains(output, "warning\nTraceback: oops\n") {
+		t.Errorf("stderr missing:\n%s", output)
+	}
+
+	output, err = runLogs(t, dir, "logs 3001 --err -n 1")
+	if err != nil {
+		t.Fatalf("logs --err failed: %v", err)
+	}
+	if strings.Contains(output, "step") || strings.Contains(output, "warning") || !strings.Contains(output, "Traceback: oops") {
+		t.Errorf("expected only the last line of stderr:\n%s", output)
+	}
+
+	// Following a job that has ended prints its output and returns
+	output, err = runLogs(t, dir, "logs 3001 -f")
+	if err != nil {
+		t.Fatalf("logs -f failed: %v", err)
+	}
+	if !strings.Contains(output, "step 11\n") || !strings.Contains(output, "has already ended (COMPLETED)") {
+		t.Errorf("unexpected output:\n%s", output)
+	}
+}
+
+func TestLogsMissingFile(t *testing.T) {
+	output, err := runLogs(t, t.TempDir(), "logs 3001")
+	if err != nil {
+		t.Fatalf("logs failed: %v", err)
+	}
+	if !strings.Contains(output, "train-3001.out does not exist (yet)") {
+		t.Errorf("missing file not reported:\n%s", output)
+	}
+
+	if _, err := runLogs(t, t.TempDir(), "logs 3001 -n many"); err == nil {
+		t.Error("expected an error for an invalid line count")
+	}
+}
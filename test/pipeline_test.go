@@ -0,0 +1,201 @@
+package test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"slsh/commands"
+	"slsh/config"
+	"slsh/shell"
+	"slsh/slurm"
+)
+
+const chainPipeline = `{
+  "name": "chain",
+  "defaults": {"account": "physics"},
+  "steps": [
+    {"name": "eval", "command": "python eval.py", "after": ["train"]},
+    {"name": "prep", "script": "prep.sh"},
+    {"name": "train", "command": "python train.py", "after": ["prep"],
+     "options": {"partition": "gpu", "time": "8:00:00"}}
+  ]
+}`
+
+// writePipeline writes a pipeline file and a prep.sh script to a
+// temporary directory, returning the path of the pipeline file
+func writePipeline(t *testing.T, content string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "chain.json")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "prep.sh"), []byte("#!/bin/bash\n#SBATCH --cpus-per-task=2\n./prep\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// runPipeline runs a pipeline command line against a fake cluster that
+// numbers the jobs of the chain pipeline 2001 to 2003
+func runPipeline(t *testing.T, line string, fixtures ...slurm.Fixture) (string, []slurm.Call, error) {
+	t.Helper()
+
+	client, runner := newFakeClient()
+	for i, step := range []string{"prep", "train", "eval"} {
+		runner.Add(slurm.Fixture{
+			Command: "sbatch",
+			Args:    []string{"--job-name=" + step},
+			Output:  fmt.Sprintf("Submitted batch job %d\n", 2001+i),
+		})
+	}
+	for _, fixture := range fixtures {
+		runner.Add(fixture)
+	}
+
+	cfg := config.Default()
+	cfg.ColorOutput = false
+
+	pipeline := commands.NewPipelineCommand(client, cfg)
+	cmd, err := shell.ParseCommand(line, flagsOf(pipeline))
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+
+	var runErr error
+	output := captureOutput(t, func() {
+		runErr = pipeline.Execute(cmd, nil)
+	})
+	return output, runner.Calls(), runErr
+}
+
+func TestPipelineValidation(t *testing.T) {
+	tests := map[string]string{
+		"cycle":   `{"steps": [{"name": "a", "command": "x", "after": ["b"]}, {"name": "b", "command": "y", "after": ["a"]}]}`,
+		"unknown": `{"steps": [{"name": "a", "command": "x", "after": ["missing"]}]}`,
+		"both":    `{"steps": [{"name": "a", "command": "x", "script": "a.sh"}]}`,
+		"neither": `{"steps": [{"name": "a"}]}`,
+		"twice":   `{"steps": [{"name": "a", "command": "x"}, {"name": "a", "command": "y"}]}`,
+		"type":    `{"steps": [{"name": "a", "command": "x"}, {"name": "b", "command": "y", "after": ["a"], "dependency": "afterwards"}]}`,
+		"field":   `{"steps": [{"name": "a", "command": "x", "options": {"partiton": "gpu"}}]}`,
+		"empty":   `{"name": "chain"}`,
+	}
+	for name, content := range tests {
+		if _, err := config.LoadPipeline(writePipeline(t, content)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+
+	p, err := config.LoadPipeline(writePipeline(t, chainPipeline))
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	steps, err := p.Order()
+	if err != nil {
+		t.Fatalf("order failed: %v", err)
+	}
+	var names []string
+	for _, step := range steps {
+		names = append(names, step.Name)
+	}
+	if got := strings.Join(names, ","); got != "prep,train,eval" {
+		t.Errorf("unexpected order: %s", got)
+	}
+}
+
+func TestPipelineRunSubmitsInOrder(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	path := writePipeline(t, chainPipeline)
+
+	output, calls, err := runPipeline(t, "pipeline run "+path)
+	if err != nil {
+		t.Fatalf("pipeline run failed: %v\n%s", err, output)
+	}
+
+	var submitted [][]string
+	for _, call := range calls {
+		if call.Command == "sbatch" {
+			submitted = append(submitted, call.Args)
+		}
+	}
+	if len(submitted) != 3 {
+		t.Fatalf("expected 3 submissions, got %d", len(submitted))
+	}
+
+	prep, train, eval := strings.Join(submitted[0], " "), strings.Join(submitted[1], " "), strings.Join(submitted[2], " ")
+	if !strings.Contains(prep, "--job-name=prep") || strings.Contains(prep, "--dependency") {
+		t.Errorf("unexpected prep submission: %s", prep)
+	}
+	if !strings.HasSuffix(prep, filepath.Join(filepath.Dir(path), "prep.sh")) {
+		t.Errorf("script not resolved against the pipeline file: %s", prep)
+	}
+	for _, want := range []string{"--dependency=afterok:2001", "--partition=gpu", "--account=physics", "--time=8:00:00"} {
+		if !strings.Contains(train, want) {
+			t.Errorf("train submission lacks %s: %s", want, train)
+		}
+	}
+	if !strings.Contains(eval, "--dependency=afterok:2002") {
+		t.Errorf("eval does not wait for train: %s", eval)
+	}
+
+	run, err := config.LoadPipelineRun("chain")
+	if err != nil {
+		t.Fatalf("run not recorded: %v", err)
+	}
+	if len(run.Jobs) != 3 || run.Jobs[2].JobID != "2003" || run.Jobs[2].After[0] != "train" {
+		t.Errorf("unexpected recorded run: %+v", run.Jobs)
+	}
+	if !strings.Contains(output, "[3/3] eval") || !strings.Contains(output, "pipeline status chain") {
+		t.Errorf("unexpected output:\n%s", output)
+	}
+}
+
+func TestPipelineDryRun(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	output, calls, err := runPipeline(t, "pipeline run --dry-run "+writePipeline(t, chainPipeline))
+	if err != nil {
+		t.Fatalf("dry run failed: %v", err)
+	}
+	if len(calls) != 0 {
+		t.Errorf("dry run ran %d commands", len(calls))
+	}
+	if !strings.Contains(output, "would submit 3 steps") || !strings.Contains(output, "python train.py") {
+		t.Errorf("unexpected output:\n%s", output)
+	}
+}
+
+func TestPipelineStatusFlagsStuckSteps(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	if _, _, err := runPipeline(t, "pipeline run "+writePipeline(t, chainPipeline)); err != nil {
+		t.Fatalf("pipeline run failed: %v", err)
+	}
+
+	output, _, err := runPipeline(t, "pipeline status",
+		slurm.Fixture{
+			Command: "squeue",
+			Args:    []string{"-j", "2001,2002,2003"},
+			Output:  "2003|PENDING|compute|alice|1|1|1:00:00|2024-01-15T11:00:00|N/A|N/A|0:00||/home/alice|physics|DependencyNeverSatisfied|eval\n",
+		},
+		slurm.Fixture{
+			Command: "sacct",
+			Args:    []string{"-j", "2001,2002"},
+			Output: "2001|COMPLETED|0:0|00:10:00|2024-01-15T10:00:00|2024-01-15T10:00:10|2024-01-15T10:10:10|compute|physics|alice|2|node001|1:00:00|billing=2,cpu=2,node=1|/home/alice|None|prep\n" +
+				"2002|FAILED|1:0|00:00:42|2024-01-15T10:00:00|2024-01-15T10:10:10|2024-01-15T10:10:52|gpu|physics|alice|1|gpu001|8:00:00|billing=1,cpu=1,node=1|/home/alice|None|train\n",
+		},
+	)
+	if err != nil {
+		t.Fatalf("pipeline status failed: %v", err)
+	}
+
+	for _, want := range []string{"Pipeline chain", "└─ train", "FAILED", "DependencyNeverSatisfied",
+		"eval (job 2003) will never start: it waits for afterok (train FAILED)", "cancel 2003"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("status output lacks %q:\n%s", want, output)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"slsh/slurm"
+)
+
+// flakyRunner fails the first failures calls with a transient slurmctld error
+type flakyRunner struct {
+	failures int
+	calls    int
+}
+
+func (r *flakyRunner) Run(ctx context.Context, command string, args ...string) (*slurm.CommandResult, error) {
+	r.calls++
+	if r.calls <= r.failures {
+		result := &slurm.CommandResult{
+			ExitCode: 1,
+			Error:    "slurm_load_jobs error: Socket timed out on send/recv operation\n",
+		}
+		return result, context.DeadlineExceeded
+	}
+	return &slurm.CommandResult{Success: true, Output: "ok\n"}, nil
+}
+
+// newFlakyClient returns a client whose runner fails transiently failures times
+func newFlakyClient(failures int) (*slurm.Client, *flakyRunner) {
+	runner := &flakyRunner{failures: failures}
+	client := slurm.NewClientWithRunner(runner)
+	client.SetRetryBackoff(time.Millisecond)
+	return client, runner
+}
+
+func TestReadsRetryTransientErrors(t *testing.T) {
+	client, runner := newFlakyClient(2)
+
+	result, err := client.Execute("squeue", "--noheader")
+	if err != nil {
+		t.Fatalf("squeue failed after retries: %v", err)
+	}
+	if result.Output != "ok\n" || runner.calls != 3 {
+		t.Errorf("got output %q after %d calls, want ok after 3", result.Output, runner.calls)
+	}
+}
+
+func TestReadsGiveUpAfterRetries(t *testing.T) {
+	client, runner := newFlakyClient(10)
+	client.SetPolicy(slurm.OpRead, slurm.Policy{Timeout: time.Second, Retries: 2})
+
+	if _, err := client.Execute("sinfo"); err == nil {
+		t.Fatal("expected sinfo to fail")
+	}
+	if runner.calls != 3 {
+		t.Errorf("sinfo ran %d times, want 3", runner.calls)
+	}
+}
+
+func TestMutationsAreNeverRetried(t *testing.T) {
+	for _, args := range [][]string{
+		{"scancel", "1001"},
+		{"sbatch", "job.sh"},
+		{"scontrol", "hold", "1001"},
+	} {
+		client, runner := newFlakyClient(1)
+		client.SetPolicy(slurm.OpMutate, slurm.Policy{Timeout: time.Second, Retries: 5})
+
+		if _, err := client.Execute(args[0], args[1:]...); err == nil {
+			t.Errorf("%s: expected the transient failure to be returned", args[0])
+		}
+		if runner.calls != 1 {
+			t.Errorf("%s ran %d times, want 1", args[0], runner.calls)
+		}
+	}
+}
+
+func TestReadTimeout(t *testing.T) {
+	client := slurm.NewClient()
+	client.SetTimeout(100 * time.Millisecond)
+
+	start := time.Now()
+	_, err := client.Execute("sh", "-c", "exec sleep 5")
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Fatalf("expected a timeout, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("timeout took %s", elapsed)
+	}
+}
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		line string
+		want slurm.OpClass
+	}{
+		{"squeue -u alice", slurm.OpRead},
+		{"sacct -j 1001", slurm.OpRead},
+		{"scontrol show job 1001", slurm.OpRead},
+		{"scontrol --json show nodes", slurm.OpRead},
+		{"scontrol requeue 1001", slurm.OpMutate},
+		{"sacctmgr list user", slurm.OpRead},
+		{"sacctmgr add user bob", slurm.OpMutate},
+		{"scancel 1001", slurm.OpMutate},
+		{"sbatch job.sh", slurm.OpSubmit},
+		{"srun hostname", slurm.OpRun},
+		{"salloc -N 1", slurm.OpRun},
+	}
+
+	for _, tt := range tests {
+		fields := strings.Fields(tt.line)
+		if got := slurm.Classify(fields[0], fields[1:]); got != tt.want {
+			t.Errorf("Classify(%q) = %d, want %d", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestIsTransientError(t *testing.T) {
+	if !slurm.IsTransientError("squeue: error: Socket timed out on send/recv operation") {
+		t.Error("socket timeout not recognized as transient")
+	}
+	if slurm.IsTransientError("scancel: error: Invalid job id specified") {
+		t.Error("invalid job id recognized as transient")
+	}
+}
+
+// partialRunner prints a line and then fails transiently, every time
+type partialRunner struct {
+	calls int
+}
+
+func (r *partialRunner) Run(ctx context.Context, command string, args ...string) (*slurm.CommandResult, error) {
+	r.calls++
+	result := &slurm.CommandResult{
+		ExitCode: 1,
+		Output:   "JOBID NAME\n",
+		Error:    "slurm_load_jobs error: Socket timed out on send/recv operation\n",
+	}
+	return result, context.DeadlineExceeded
+}
+
+func TestStreamRetriesOnlyBeforeOutput(t *testing.T) {
+	// A failure that printed only an error is retried without showing it
+	client, runner := newFlakyClient(2)
+	var lines []string
+	handler := func(line slurm.OutputLine) { lines = append(lines, line.Text) }
+	if _, err := client.ExecuteStream(handler, "squeue"); err != nil {
+		t.Fatalf("squeue failed after retries: %v", err)
+	}
+	if runner.calls != 3 || strings.Join(lines, "|") != "ok" {
+		t.Errorf("got lines %q after %d calls, want ok after 3", lines, runner.calls)
+	}
+
+	// Once lines were printed, a retry would print them again
+	partial := &partialRunner{}
+	client = slurm.NewClientWithRunner(partial)
+	client.SetRetryBackoff(time.Millisecond)
+	lines = nil
+	if _, err := client.ExecuteStream(handler, "squeue"); err == nil {
+		t.Fatal("expected squeue to fail")
+	}
+	if partial.calls != 1 {
+		t.Errorf("squeue ran %d times after printing, want 1", partial.calls)
+	}
+	if strings.Join(lines, "|") != "JOBID NAME|slurm_load_jobs error: Socket timed out on send/recv operation" {
+		t.Errorf("unexpected lines: %q", lines)
+	}
+
+	// Errors of the last attempt are shown
+	client, _ = newFlakyClient(10)
+	lines = nil
+	if _, err := client.ExecuteStream(handler, "squeue"); err == nil {
+		t.Fatal("expected squeue to fail")
+	}
+	if len(lines) != 1 || !strings.Contains(lines[0], "Socket timed out") {
+		t.Errorf("expected the last error to be shown, got %q", lines)
+	}
+}
@@ -0,0 +1,121 @@
+package test
+
+import (
+	"strings"
+	"testing"
+
+	"slsh/commands"
+	"slsh/config"
+	"slsh/shell"
+	"slsh/slurm"
+)
+
+// runQueue runs a queue command line against the fake cluster
+func runQueue(t *testing.T, line string) (string, *slurm.FakeRunner) {
+	t.Helper()
+	t.Setenv("USER", "alice")
+
+	client, runner := newFakeClient()
+	cfg := config.Default()
+	cfg.ColorOutput = false
+
+	queue := commands.NewQueueCommand(client, cfg)
+	cmd, err := shell.ParseCommand(line, flagsOf(queue))
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+
+	output := captureOutput(t, func() {
+		if err := queue.Execute(cmd, nil); err != nil {
+			t.Fatalf("%s failed: %v", line, err)
+		}
+	})
+	return output, runner
+}
+
+// jobOrder returns the job IDs of a printed table in order
+func jobOrder(output string) []string {
+	var ids []string
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) > 0 && len(fields[0]) == 4 && fields[0] >= "1000" && fields[0] <= "9999" {
+			ids = append(ids, fields[0])
+		}
+	}
+	return ids
+}
+
+func TestQueueDefaultsToCurrentUser(t *testing.T) {
+	output, _ := runQueue(t, "queue")
+	if got := jobOrder(output); strings.Join(got, " ") != "1001 1002" {
+		t.Errorf("got jobs %v, want alice's jobs", got)
+	}
+	if !strings.Contains(output, "2 jobs: 1 PENDING, 1 RUNNING") {
+		t.Errorf("missing summary footer:\n%s", output)
+	}
+}
+
+func TestQueueAllAndFilters(t *testing.T) {
+	output, runner := runQueue(t, "queue --all -t R -p gpu")
+	if got := jobOrder(output); strings.Join(got, " ") != "1000" {
+		t.Errorf("got jobs %v, want 1000", got)
+	}
+
+	args := strings.Join(runner.Calls()[len(runner.Calls())-1].Args, " ")
+	if strings.Contains(args, "-u") || !strings.Contains(args, "-t RUNNING") || !strings.Contains(args, "-p gpu") {
+		t.Errorf("filters not pushed down to squeue: %s", args)
+	}
+}
+
+func TestQueueNameGlobIsAppliedLocally(t *testing.T) {
+	output, runner := runQueue(t, "queue alice bob --name 'eval*'")
+	if got := jobOrder(output); strings.Join(got, " ") != "1002" {
+		t.Errorf("got jobs %v, want 1002", got)
+	}
+
+	args := strings.Join(runner.Calls()[len(runner.Calls())-1].Args, " ")
+	if strings.Contains(args, " -n ") || !strings.Contains(args, "-u alice,bob") {
+		t.Errorf("unexpected squeue arguments: %s", args)
+	}
+}
+
+func TestQueueAccountFilter(t *testing.T) {
+	output, _ := runQueue(t, "queue --all -A chem")
+	if got := jobOrder(output); strings.Join(got, " ") != "1000" {
+		t.Errorf("got jobs %v, want 1000", got)
+	}
+}
+
+func TestQueueSort(t *testing.T) {
+	tests := map[string]string{
+		"queue --all --sort jobid":           "1000 1001 1002",
+		"queue --all --sort cpus --reverse":  "1000 1002 1001",
+		"queue --all --sort state,-time":     "1002 1000 1001",
+		"queue --all --sort limit":           "1002 1000 1001",
+		"queue --all --sort user,-partition": "1002 1001 1000",
+	}
+
+	for line, want := range tests {
+		output, _ := runQueue(t, line)
+		if got := strings.Join(jobOrder(output), " "); got != want {
+			t.Errorf("%s: got %s, want %s", line, got, want)
+		}
+	}
+}
+
+func TestQueueStateOptionIsNotATimeLimit(t *testing.T) {
+	for _, line := range []string{"queue -t R", "cancel -t pending"} {
+		cmd, err := shell.ParseCommand(line, nil)
+		if err != nil {
+			t.Fatalf("parse failed: %v", err)
+		}
+		if err := shell.ValidateCommand(cmd); err != nil {
+			t.Errorf("%s: %v", line, err)
+		}
+	}
+
+	cmd, _ := shell.ParseCommand("run -t soon hostname", nil)
+	if err := shell.ValidateCommand(cmd); err == nil {
+		t.Error("expected run -t to be validated as a time limit")
+	}
+}
@@ -0,0 +1,294 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"slsh/slurm"
+)
+
+const restToken = "test-token"
+
+// fakeClusterJSON describes the built-in fake cluster in the JSON model
+// shared by slurmrestd and the --json output of the Slurm tools
+func fakeClusterJSON(t *testing.T) (jobs, nodes, partitions map[string]interface{}) {
+	t.Helper()
+
+	local := func(s string) int64 {
+		ts, err := time.ParseInLocation("2006-01-02T15:04:05", s, time.Local)
+		if err != nil {
+			t.Fatalf("bad time %s: %v", s, err)
+		}
+		return ts.Unix()
+	}
+	number := func(n int64) map[string]interface{} {
+		return map[string]interface{}{"set": true, "infinite": false, "number": n}
+	}
+	unset := map[string]interface{}{"set": false, "infinite": false, "number": 0}
+
+	jobs = map[string]interface{}{
+		"jobs": []interface{}{
+			map[string]interface{}{
+				"job_id": 1001, "name": "train", "user_name": "alice", "account": "physics", "job_state": []string{"RUNNING"},
+				"state_reason": "None", "partition": "compute", "node_count": number(1), "cpus": number(4),
+				"time_limit": number(1440), "submit_time": number(local("2024-01-15T10:30:00")),
+				"start_time": number(local("2024-01-15T10:31:00")), "end_time": number(local("2024-01-16T10:31:00")),
+				"nodes": "node001", "current_working_directory": "/home/alice/train",
+			},
+			map[string]interface{}{
+				"job_id": 1002, "name": "eval, final", "user_name": "alice", "account": "physics", "job_state": []string{"PENDING"},
+				"state_reason": "Resources", "partition": "gpu", "node_count": number(2), "cpus": number(8),
+				"time_limit": number(120), "submit_time": number(local("2024-01-15T11:00:00")),
+				"start_time": number(0), "end_time": number(0),
+				"nodes": "", "current_working_directory": "/home/alice/eval",
+			},
+			map[string]interface{}{
+				"job_id": 1000, "name": "sim_run", "user_name": "bob", "account": "chem", "job_state": []string{"RUNNING"},
+				"state_reason": "None", "partition": "gpu", "node_count": number(1), "cpus": number(16),
+				"time_limit": number(240), "submit_time": number(local("2024-01-15T09:00:00")),
+				"start_time": number(local("2024-01-15T09:05:00")), "end_time": number(local("2024-01-15T13:05:00")),
+				"nodes": "gpu001", "current_working_directory": "/home/bob/sim",
+			},
+		},
+	}
+	nodes = map[string]interface{}{
+		"nodes": []interface{}{
+			map[string]interface{}{"name": "node001", "state": []string{"MIXED"}, "cpus": 32, "alloc_cpus": 16,
+				"real_memory": 128000, "partitions": []string{"compute"}, "features": []string{"intel"}, "reason": ""},
+			map[string]interface{}{"name": "node002", "state": []string{"IDLE", "POWERED_DOWN"}, "cpus": 32, "alloc_cpus": 0,
+				"real_memory": 128000, "partitions": []string{"compute"}, "features": []string{"intel"}, "reason": ""},
+			map[string]interface{}{"name": "node003", "state": []string{"IDLE", "DRAIN", "NOT_RESPONDING"}, "cpus": 32, "alloc_cpus": 0,
+				"real_memory": 128000, "partitions": []string{"compute"}, "features": []string{"intel"}, "reason": "disk failure"},
+			map[string]interface{}{"name": "gpu001", "state": []string{"ALLOCATED"}, "cpus": 32, "alloc_cpus": 32,
+				"real_memory": 256000, "partitions": []string{"gpu", "debug"}, "features": []string{"a100", "nvlink"}, "reason": ""},
+		},
+	}
+	partition := func(name, nodeList string, total int, flags []string, maxTime, defTime, maxNodes interface{}) map[string]interface{} {
+		return map[string]interface{}{
+			"name":      name,
+			"flags":     flags,
+			"nodes":     map[string]interface{}{"configured": nodeList, "total": total},
+			"partition": map[string]interface{}{"state": []string{"UP"}},
+			"maximums":  map[string]interface{}{"time": maxTime, "nodes": maxNodes},
+			"defaults":  map[string]interface{}{"time": defTime},
+		}
+	}
+	infinite := map[string]interface{}{"set": true, "infinite": true, "number": 0}
+	partitions = map[string]interface{}{
+		"partitions": []interface{}{
+			partition("compute", "node[001-003]", 3, []string{"DEFAULT"}, number(1440), number(60), infinite),
+			partition("gpu", "gpu001", 1, nil, number(2880), unset, number(4)),
+			partition("debug", "gpu001", 1, nil, number(30), number(10), number(1)),
+		},
+	}
+
+	return jobs, nodes, partitions
+}
+
+// newRESTStandIn serves the built-in fake cluster the way slurmrestd does
+func newRESTStandIn(t *testing.T) (*httptest.Server, *[]map[string]interface{}) {
+	t.Helper()
+
+	jobs, nodes, partitions := fakeClusterJSON(t)
+
+	var submitted []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-SLURM-USER-TOKEN") != restToken || r.Header.Get("X-SLURM-USER-NAME") != "alice" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		var body interface{}
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/slurm/v0.0.40/jobs":
+			body = jobs
+		case r.Method == http.MethodGet && r.URL.Path == "/slurm/v0.0.40/nodes":
+			body = nodes
+		case r.Method == http.MethodGet && r.URL.Path == "/slurm/v0.0.40/partitions":
+			body = partitions
+		case r.Method == http.MethodPost && r.URL.Path == "/slurm/v0.0.40/job/submit":
+			var req map[string]interface{}
+			json.NewDecoder(r.Body).Decode(&req)
+			submitted = append(submitted, req)
+			body = map[string]interface{}{"job_id": 2001, "errors": []interface{}{}}
+		case r.Method == http.MethodDelete && r.URL.Path == "/slurm/v0.0.40/job/404":
+			w.WriteHeader(http.StatusInternalServerError)
+			body = map[string]interface{}{"errors": []interface{}{
+				map[string]interface{}{"error": "Invalid job id specified", "error_number": 2017},
+			}}
+		case r.Method == http.MethodDelete && strings.HasPrefix(r.URL.Path, "/slurm/v0.0.40/job/"):
+			body = map[string]interface{}{"errors": []interface{}{}}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(body)
+	}))
+	t.Cleanup(server.Close)
+
+	return server, &submitted
+}
+
+func newRESTClient(t *testing.T) (*slurm.Client, *[]map[string]interface{}) {
+	server, submitted := newRESTStandIn(t)
+	rest := slurm.NewRESTClient(server.URL, "", "alice", restToken)
+	return slurm.NewClientWithREST(rest), submitted
+}
+
+func TestRESTBackendMatchesCLIBackend(t *testing.T) {
+	restClient, _ := newRESTClient(t)
+	cliClient, _ := newFakeClient()
+
+	assertSameCluster(t, cliClient, restClient)
+}
+
+// assertSameCluster checks that two clients report identical typed results
+func assertSameCluster(t *testing.T, want, got *slurm.Client) {
+	t.Helper()
+
+	wantNodes, err := want.ListNodes()
+	if err != nil {
+		t.Fatalf("nodes failed: %v", err)
+	}
+	gotNodes, err := got.ListNodes()
+	if err != nil {
+		t.Fatalf("nodes failed: %v", err)
+	}
+	if !reflect.DeepEqual(wantNodes, gotNodes) {
+		t.Errorf("nodes differ:\nwant: %+v\ngot:  %+v", wantNodes, gotNodes)
+	}
+
+	wantPartitions, err := want.ListPartitions()
+	if err != nil {
+		t.Fatalf("partitions failed: %v", err)
+	}
+	gotPartitions, err := got.ListPartitions()
+	if err != nil {
+		t.Fatalf("partitions failed: %v", err)
+	}
+	if !reflect.DeepEqual(wantPartitions, gotPartitions) {
+		t.Errorf("partitions differ:\nwant: %+v\ngot:  %+v", wantPartitions, gotPartitions)
+	}
+
+	filter := &slurm.JobFilter{Users: []string{"alice"}}
+	wantJobs, err := want.ListJobs(filter)
+	if err != nil {
+		t.Fatalf("jobs failed: %v", err)
+	}
+	gotJobs, err := got.ListJobs(filter)
+	if err != nil {
+		t.Fatalf("jobs failed: %v", err)
+	}
+	// The run time of a running job depends on the current time
+	for i := range wantJobs {
+		wantJobs[i].TimeUsed = ""
+	}
+	for i := range gotJobs {
+		gotJobs[i].TimeUsed = ""
+	}
+	if !reflect.DeepEqual(wantJobs, gotJobs) {
+		t.Errorf("jobs differ:\nwant: %+v\ngot:  %+v", wantJobs, gotJobs)
+	}
+}
+
+func TestRESTBackendSubmitAndCancel(t *testing.T) {
+	client, submitted := newRESTClient(t)
+
+	script := t.TempDir() + "/job.sh"
+	if err := os.WriteFile(script, []byte("#!/bin/bash\nhostname\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := client.SubmitJob(script, &slurm.JobOptions{Name: "train", Memory: "4G", Time: "1:30:00"})
+	if err != nil {
+		t.Fatalf("submit failed: %v", err)
+	}
+	if result.Output != "Submitted batch job 2001\n" {
+		t.Errorf("unexpected submit output: %q", result.Output)
+	}
+
+	job := (*submitted)[0]["job"].(map[string]interface{})
+	if job["name"] != "train" || fmt.Sprint(job["time_limit"].(map[string]interface{})["number"]) != "90" {
+		t.Errorf("unexpected job description: %v", job)
+	}
+	if fmt.Sprint(job["memory_per_node"].(map[string]interface{})["number"]) != "4096" {
+		t.Errorf("unexpected memory: %v", job["memory_per_node"])
+	}
+
+	if _, err := client.CancelJob("1001"); err != nil {
+		t.Errorf("cancel failed: %v", err)
+	}
+	if _, err := client.CancelJob("404"); err == nil || !strings.Contains(err.Error(), "Invalid job id") {
+		t.Errorf("expected slurmrestd error, got %v", err)
+	}
+}
+
+func TestRESTBackendSendsWholeEnvironment(t *testing.T) {
+	client, submitted := newRESTClient(t)
+	t.Setenv("SLSH_TEST_DATA", "/scratch/data")
+	t.Setenv("SLSH_TEST_MODE", "slow")
+
+	script := t.TempDir() + "/job.sh"
+	if err := os.WriteFile(script, []byte("#!/bin/bash\nhostname\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	options := &slurm.JobOptions{Environment: map[string]string{"SLSH_TEST_MODE": "fast"}}
+	if _, err := client.SubmitJob(script, options); err != nil {
+		t.Fatalf("submit failed: %v", err)
+	}
+
+	// Like sbatch, the job gets the whole environment, with the job's own
+	// variables taking precedence
+	env := map[string][]string{}
+	for _, entry := range (*submitted)[0]["job"].(map[string]interface{})["environment"].([]interface{}) {
+		key, value, _ := strings.Cut(entry.(string), "=")
+		env[key] = append(env[key], value)
+	}
+	for key, want := range map[string]string{
+		"PATH":           os.Getenv("PATH"),
+		"SLSH_TEST_DATA": "/scratch/data",
+		"SLSH_TEST_MODE": "fast",
+	} {
+		if got := env[key]; len(got) != 1 || got[0] != want {
+			t.Errorf("environment has %s=%q, want %q", key, got, want)
+		}
+	}
+}
+
+func TestRESTBackendRejectsBadToken(t *testing.T) {
+	server, _ := newRESTStandIn(t)
+	client := slurm.NewClientWithREST(slurm.NewRESTClient(server.URL, "", "alice", "wrong"))
+
+	if _, err := client.ListJobs(nil); err == nil || !strings.Contains(err.Error(), "token") {
+		t.Errorf("expected token error, got %v", err)
+	}
+}
+
+func TestParseMemoryMB(t *testing.T) {
+	tests := map[string]int64{
+		"512":   512,
+		"4G":    4096,
+		"4gb":   4096,
+		"2048K": 2,
+		"1T":    1024 * 1024,
+		"1.5G":  1536,
+	}
+	for size, want := range tests {
+		if got, err := slurm.ParseMemoryMB(size); err != nil || got != want {
+			t.Errorf("ParseMemoryMB(%q) = %d, %v; want %d", size, got, err, want)
+		}
+	}
+
+	for _, size := range []string{"", "4X", "16Q", "G", "-1G"} {
+		if got, err := slurm.ParseMemoryMB(size); err == nil {
+			t.Errorf("ParseMemoryMB(%q) = %d, expected an error", size, got)
+		}
+	}
+}
@@ -1 +1,128 @@
-package test
\ No newline at end of file
+package test
+
+import (
+	"strings"
+	"testing"
+
+	"slsh/commands"
+	"slsh/config"
+	"slsh/shell"
+	"slsh/slurm"
+)
+
+// newFakeShell creates a shell using the built-in fake cluster
+func newFakeShell(t *testing.T) *shell.Shell {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+
+	cfg := config.Default()
+	cfg.Backend = config.BackendFake
+	cfg.ColorOutput = false
+	return shell.NewWithConfig(cfg)
+}
+
+func TestShellRunsQueueOffline(t *testing.T) {
+	sh := newFakeShell(t)
+
+	var err error
+	out := captureOutput(t, func() { err = sh.ExecuteDirectCommand("queue alice") })
+	if err != nil {
+		t.Fatalf("queue failed: %v", err)
+	}
+	if !strings.Contains(out, "train") {
+		t.Errorf("expected fake queue output, got %q", out)
+	}
+}
+
+func TestShellClusterInfoFromFixtures(t *testing.T) {
+	sh := newFakeShell(t)
+
+	if name := sh.GetClient().GetClusterInfo(); name != "fakecluster" {
+		t.Errorf("expected fakecluster, got %q", name)
+	}
+}
+
+func TestShellExitStopsLoop(t *testing.T) {
+	sh := newFakeShell(t)
+
+	captureOutput(t, func() {
+		if err := sh.ExecuteDirectCommand("exit"); err != nil {
+			t.Errorf("exit failed: %v", err)
+		}
+	})
+	if sh.IsRunning() {
+		t.Error("shell should not be running after exit")
+	}
+}
+
+func TestParseCommandOptions(t *testing.T) {
+	cmd, err := shell.ParseCommand(`run -N 2 -p gpu "echo hi"`, nil)
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	if cmd.Name != "run" || cmd.Options["-N"] != "2" || cmd.Options["-p"] != "gpu" {
+		t.Errorf("unexpected command: %+v", cmd)
+	}
+	if len(cmd.Args) != 1 || cmd.Args[0] != "echo hi" {
+		t.Errorf("unexpected args: %v", cmd.Args)
+	}
+}
+
+func TestParseCommandFlagsPerCommand(t *testing.T) {
+	sh := newFakeShell(t)
+
+	// -a is --all for queue but --array for submit and sbatch
+	for _, line := range []string{"submit -a 1-10 job.sh", "sbatch -a 1-10 job.sh"} {
+		cmd, err := sh.ParseCommand(line)
+		if err != nil {
+			t.Fatalf("parse failed: %v", err)
+		}
+		if cmd.Options["-a"] != "1-10" || len(cmd.Args) != 1 || cmd.Args[0] != "job.sh" {
+			t.Errorf("%s: unexpected command: %+v", line, cmd)
+		}
+	}
+
+	cmd, err := sh.ParseCommand("queue -a alice")
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	if _, ok := cmd.Options["-a"]; !ok || len(cmd.Args) != 1 || cmd.Args[0] != "alice" {
+		t.Errorf("queue -a took a value: %+v", cmd)
+	}
+}
+
+// clientShell is a shell whose commands use a given client
+type clientShell struct {
+	*shell.Shell
+	client *slurm.Client
+}
+
+func (s *clientShell) GetClient() *slurm.Client {
+	return s.client
+}
+
+func TestPassthroughKeepsOptionOrder(t *testing.T) {
+	sh := newFakeShell(t)
+	registry := commands.NewRegistry()
+
+	// Options come back in the order given, with the command after "--"
+	for i := 0; i < 20; i++ {
+		client, runner := newFakeClient()
+		cmd, err := sh.ParseCommand("srun -N 2 -p debug --exclusive -- hostname -s")
+		if err != nil {
+			t.Fatalf("parse failed: %v", err)
+		}
+		if _, ok := cmd.Options["--"]; ok || !cmd.EndOfOptions {
+			t.Fatalf("-- should end the options, not be one: %+v", cmd)
+		}
+
+		captureOutput(t, func() {
+			if err := registry.Execute(cmd, &clientShell{Shell: sh, client: client}); err != nil {
+				t.Fatalf("srun failed: %v", err)
+			}
+		})
+		if got := strings.Join(callArgs(runner.Calls(), "srun"), " "); got != "-N 2 -p debug --exclusive -- hostname -s" {
+			t.Fatalf("unexpected srun arguments: %s", got)
+		}
+	}
+}
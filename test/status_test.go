@@ -0,0 +1,227 @@
+package test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"slsh/commands"
+	"slsh/config"
+	"slsh/shell"
+	"slsh/slurm"
+)
+
+func TestJobDetailRunningJob(t *testing.T) {
+	client, _ := newFakeClient()
+
+	detail, err := client.JobDetail("1001")
+	if err != nil {
+		t.Fatalf("detail failed: %v", err)
+	}
+	if got := strings.Join(detail.Sources, ","); got != "sacct,squeue,scontrol" {
+		t.Errorf("unexpected sources: %s", got)
+	}
+	if detail.State != "RUNNING" || detail.User != "alice" || detail.CPUs != 4 {
+		t.Errorf("unexpected job: %+v", detail.JobRecord)
+	}
+	if detail.StdOut != "/home/alice/train/slurm-1001.out" || detail.WorkDir != "/home/alice/train" {
+		t.Errorf("unexpected paths: stdout %q, workdir %q", detail.StdOut, detail.WorkDir)
+	}
+	if detail.TRES != "cpu=4,mem=16G,node=1,billing=4" {
+		t.Errorf("unexpected TRES: %q", detail.TRES)
+	}
+	if detail.Elapsed != time.Hour+2*time.Minute+3*time.Second || detail.TimeLimit != "1-00:00:00" {
+		t.Errorf("unexpected times: %v of %s", detail.Elapsed, detail.TimeLimit)
+	}
+	if len(detail.Steps) != 2 || detail.Steps[1].ID != "1001.0" || detail.Steps[1].Name != "python" {
+		t.Errorf("unexpected steps: %+v", detail.Steps)
+	}
+}
+
+func TestJobDetailFinishedJob(t *testing.T) {
+	client, _ := newFakeClient()
+
+	// The controller has forgotten job 999, only accounting knows it
+	detail, err := client.JobDetail("999")
+	if err != nil {
+		t.Fatalf("detail failed: %v", err)
+	}
+	if got := strings.Join(detail.Sources, ","); got != "sacct" {
+		t.Errorf("unexpected sources: %s", got)
+	}
+	if detail.State != "OUT_OF_MEMORY" || detail.ExitCode != 0 || detail.Signal != 125 {
+		t.Errorf("unexpected outcome: %s %d:%d", detail.State, detail.ExitCode, detail.Signal)
+	}
+	if detail.EndTime.IsZero() || detail.WorkDir != "/home/alice/model" {
+		t.Errorf("unexpected record: %+v", detail.JobRecord)
+	}
+
+	var ids []string
+	for _, step := range detail.Steps {
+		ids = append(ids, step.ID)
+	}
+	if strings.Join(ids, " ") != "999.batch 999.extern 999.0" {
+		t.Errorf("unexpected steps: %v", ids)
+	}
+}
+
+func TestJobDetailPendingJob(t *testing.T) {
+	client, _ := newFakeClient()
+
+	detail, err := client.JobDetail("1002")
+	if err != nil {
+		t.Fatalf("detail failed: %v", err)
+	}
+	if detail.Name != "eval, final" || detail.Reason != "Resources" || detail.NodeList != "" {
+		t.Errorf("unexpected job: %+v", detail.JobRecord)
+	}
+	if !detail.StartTime.IsZero() {
+		t.Errorf("pending job has a start time: %v", detail.StartTime)
+	}
+	if detail.TRES != "cpu=8,mem=64G,node=2,billing=8,gres/gpu=2" {
+		t.Errorf("expected requested TRES, got %q", detail.TRES)
+	}
+	if detail.StdErr != "/home/alice/eval/eval-1002.err" {
+		t.Errorf("unexpected stderr: %q", detail.StdErr)
+	}
+}
+
+func TestJobDetailUnknownJob(t *testing.T) {
+	client, _ := newFakeClient()
+
+	_, err := client.JobDetail("4242")
+	if err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("expected not found error, got %v", err)
+	}
+}
+
+func TestJobDetailFromJSONAccounting(t *testing.T) {
+	limit := map[string]interface{}{"set": true, "infinite": false, "number": 60}
+	tres := []map[string]interface{}{
+		{"type": "cpu", "name": "", "count": 8},
+		{"type": "mem", "name": "", "count": 32768},
+		{"type": "node", "name": "", "count": 1},
+		{"type": "gres", "name": "gpu", "count": 1},
+	}
+	exit := func(code, signal int) map[string]interface{} {
+		return map[string]interface{}{
+			"return_code": map[string]interface{}{"set": true, "number": code},
+			"signal":      map[string]interface{}{"id": map[string]interface{}{"set": true, "number": signal}},
+		}
+	}
+	resp := map[string]interface{}{
+		"jobs": []map[string]interface{}{{
+			"job_id":            999,
+			"name":              "big model",
+			"user":              "alice",
+			"account":           "physics",
+			"partition":         "gpu",
+			"nodes":             "gpu001",
+			"working_directory": "/home/alice/model",
+			"stdout":            "/home/alice/model/slurm-999.out",
+			"state":             map[string]interface{}{"current": []string{"OUT_OF_MEMORY"}, "reason": "None"},
+			"exit_code":         exit(0, 125),
+			"time": map[string]interface{}{
+				"elapsed": 312, "submission": 1705222800, "start": 1705222830, "end": 1705223142, "limit": limit,
+			},
+			"tres": map[string]interface{}{"allocated": tres},
+			"steps": []map[string]interface{}{
+				{
+					"step":      map[string]interface{}{"id": "999.batch", "name": "batch"},
+					"state":     []string{"OUT_OF_MEMORY"},
+					"exit_code": exit(0, 125),
+					"time":      map[string]interface{}{"elapsed": 312},
+					"nodes":     map[string]interface{}{"range": "gpu001"},
+				},
+				{
+					// Older releases split the step ID into its parts
+					"step":      map[string]interface{}{"id": map[string]interface{}{"job_id": 999, "step_id": "0"}, "name": "python"},
+					"state":     "OUT_OF_MEMORY",
+					"exit_code": exit(0, 125),
+					"time":      map[string]interface{}{"elapsed": 290},
+					"nodes":     map[string]interface{}{"range": "gpu001"},
+				},
+			},
+		}},
+	}
+	data, err := json.Marshal(resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client, _ := newJSONFakeClient(t, slurm.Fixture{
+		Command: "sacct",
+		Args:    []string{"--json", "-j", "999"},
+		Output:  string(data),
+	})
+
+	detail, err := client.JobDetail("999")
+	if err != nil {
+		t.Fatalf("detail failed: %v", err)
+	}
+	if detail.State != "OUT_OF_MEMORY" || detail.Signal != 125 || detail.CPUs != 8 {
+		t.Errorf("unexpected record: %+v", detail.JobRecord)
+	}
+	if detail.TRES != "cpu=8,mem=32G,node=1,gres/gpu=1" || detail.TimeLimit != "1:00:00" {
+		t.Errorf("unexpected resources: %q, limit %q", detail.TRES, detail.TimeLimit)
+	}
+	if detail.Elapsed != 312*time.Second || detail.StdOut != "/home/alice/model/slurm-999.out" {
+		t.Errorf("unexpected elapsed %v or stdout %q", detail.Elapsed, detail.StdOut)
+	}
+	if len(detail.Steps) != 2 || detail.Steps[1].ID != "999.0" || detail.Steps[1].State != "OUT_OF_MEMORY" {
+		t.Errorf("unexpected steps: %+v", detail.Steps)
+	}
+}
+
+func TestParseControlRecord(t *testing.T) {
+	fields := slurm.ParseControlRecord("JobId=7 JobName=eval, final\n" +
+		"   UserId=bob(1001) ReqTRES=cpu=2,mem=4G\n" +
+		"\n" +
+		"JobId=8 JobName=other\n")
+
+	if fields["JobName"] != "eval, final" || fields["UserId"] != "bob(1001)" {
+		t.Errorf("unexpected fields: %v", fields)
+	}
+	if fields["ReqTRES"] != "cpu=2,mem=4G" {
+		t.Errorf("unexpected TRES: %q", fields["ReqTRES"])
+	}
+	if fields["JobId"] != "7" {
+		t.Errorf("expected the first record, got job %s", fields["JobId"])
+	}
+}
+
+func TestStatusCommandShowsDetail(t *testing.T) {
+	client, _ := newFakeClient()
+	cfg := config.Default()
+	cfg.ColorOutput = false
+
+	cmd, err := shell.ParseCommand("status 1001 999", nil)
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+
+	status := commands.NewStatusCommand(client, cfg)
+	output := captureOutput(t, func() {
+		if err := status.Execute(cmd, nil); err != nil {
+			t.Fatalf("status failed: %v", err)
+		}
+	})
+
+	for _, want := range []string{
+		"Job 1001 (train)",
+		"Elapsed:    1:02:03 of 1-00:00:00 (4%)",
+		"Stdout:     /home/alice/train/slurm-1001.out",
+		"Job 999 (big model)",
+		"Exit code:  0 (signal 125)",
+		"Elapsed:    5:12 of 01:00:00 (8%)",
+		"999.extern",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("missing %q in output:\n%s", want, output)
+		}
+	}
+	if strings.Contains(output, "Stderr:") {
+		t.Errorf("stderr shown although it matches stdout:\n%s", output)
+	}
+}
@@ -0,0 +1,86 @@
+package test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"slsh/commands"
+	"slsh/config"
+	"slsh/slurm"
+)
+
+func TestExecuteStreamDeliversLinesWhileRunning(t *testing.T) {
+	client := slurm.NewClient()
+
+	start := time.Now()
+	var lines []slurm.OutputLine
+	var firstAt time.Duration
+	handler := func(line slurm.OutputLine) {
+		if len(lines) == 0 {
+			firstAt = time.Since(start)
+		}
+		lines = append(lines, line)
+	}
+
+	result, err := client.ExecuteStream(handler, "sh", "-c", "echo one; sleep 0.5; echo two >&2; printf three")
+	if err != nil {
+		t.Fatalf("ExecuteStream failed: %v", err)
+	}
+
+	if firstAt >= result.Duration-300*time.Millisecond {
+		t.Errorf("first line arrived after %s of %s, want it before the sleep", firstAt, result.Duration)
+	}
+
+	want := []slurm.OutputLine{
+		{Text: "one"},
+		{Text: "two", Stderr: true},
+		{Text: "three"},
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("got lines %+v, want %+v", lines, want)
+	}
+	for i := range want {
+		if lines[i] != want[i] {
+			t.Errorf("line %d = %+v, want %+v", i, lines[i], want[i])
+		}
+	}
+
+	if result.Output != "one\nthree" || result.Error != "two\n" {
+		t.Errorf("result kept output %q and error %q", result.Output, result.Error)
+	}
+}
+
+func TestRunLabelStreamsFixtureOutput(t *testing.T) {
+	client, runner := newFakeClient()
+	runner.Add(slurm.Fixture{
+		Command: "srun",
+		Args:    []string{"--label", "-v"},
+		Output:  "0: node001\n1: node002\n",
+	})
+	cfg := config.Default()
+	cfg.ColorOutput = false
+
+	run := commands.NewRunCommand(client, cfg)
+	cmd := &slurm.Command{
+		Name:    "run",
+		Args:    []string{"hostname"},
+		Options: map[string]string{"-l": "", "-N": "2"},
+	}
+
+	output := captureOutput(t, func() {
+		if err := run.Execute(cmd, nil); err != nil {
+			t.Fatalf("run failed: %v", err)
+		}
+	})
+
+	if !strings.Contains(output, "0: node001\n1: node002\n") {
+		t.Errorf("output missing labelled lines:\n%s", output)
+	}
+
+	calls := runner.Calls()
+	last := calls[len(calls)-1]
+	if last.Command != "srun" || !strings.Contains(strings.Join(last.Args, " "), "--label") {
+		t.Errorf("srun called with %v, want --label", last.Args)
+	}
+}
@@ -0,0 +1,205 @@
+package test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"slsh/commands"
+	"slsh/config"
+	"slsh/shell"
+	"slsh/slurm"
+)
+
+// runSubmit runs a submit command line against the fake cluster, returning
+// the output and the sbatch arguments
+func runSubmit(t *testing.T, line string, cfg *config.Config) (string, []string) {
+	t.Helper()
+	t.Setenv("USER", "alice")
+
+	client, runner := newFakeClient()
+	cfg.ColorOutput = false
+
+	submit := commands.NewSubmitCommand(client, cfg)
+	cmd, err := shell.ParseCommand(line, flagsOf(submit))
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+
+	output := captureOutput(t, func() {
+		if err := submit.Execute(cmd, nil); err != nil {
+			t.Fatalf("%s failed: %v", line, err)
+		}
+	})
+
+	for _, call := range runner.Calls() {
+		if call.Command == "sbatch" {
+			return output, call.Args
+		}
+	}
+	t.Fatalf("sbatch was not called:\n%s", output)
+	return output, nil
+}
+
+func TestSubmitGeneratesScript(t *testing.T) {
+	cfg := config.Default()
+	cfg.DefaultOutputDir = t.TempDir()
+	cfg.JobNameTemplate = "job_%j"
+
+	output, args := runSubmit(t, `submit -p gpu -- python train.py --lr 0.1 --note "first try"`, cfg)
+
+	joined := strings.Join(args, " ")
+	for _, want := range []string{
+		"--job-name=train",
+		"--partition=gpu",
+		"--time=01:00:00",
+		"--output=" + filepath.Join(cfg.DefaultOutputDir, "job_%j.out"),
+	} {
+		if !strings.Contains(joined, want) {
+			t.Errorf("sbatch arguments lack %s: %s", want, joined)
+		}
+	}
+
+	// The script is renamed after the job, next to its output
+	script := filepath.Join(cfg.DefaultOutputDir, "job_1003.sh")
+	data, err := os.ReadFile(script)
+	if err != nil {
+		t.Fatalf("script not saved: %v", err)
+	}
+	content := string(data)
+	if !strings.HasPrefix(content, "#!/bin/bash\n") || !strings.Contains(content, "#SBATCH --partition=gpu\n") || strings.Contains(content, "#SBATCH --\n") {
+		t.Errorf("unexpected script:\n%s", content)
+	}
+	if !strings.Contains(content, "\npython train.py --lr 0.1 --note 'first try'\n") {
+		t.Errorf("command not quoted in script:\n%s", content)
+	}
+
+	for _, want := range []string{"Partition:  gpu", "Time limit: 01:00:00", "Script: " + script} {
+		if !strings.Contains(output, want) {
+			t.Errorf("missing %q in output:\n%s", want, output)
+		}
+	}
+}
+
+func TestSubmitScriptKeepsDirectives(t *testing.T) {
+	script := filepath.Join(t.TempDir(), "job.sh")
+	content := "#!/bin/bash\n#SBATCH -p debug\n#SBATCH --time=10 # short\n\nsrun hostname\n#SBATCH --mem=1G\n"
+	if err := os.WriteFile(script, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := config.Default()
+	cfg.DefaultPartition = "compute"
+	cfg.DefaultMemory = "4G"
+
+	output, args := runSubmit(t, "submit "+script, cfg)
+
+	joined := strings.Join(args, " ")
+	if strings.Contains(joined, "--partition") || strings.Contains(joined, "--time") {
+		t.Errorf("defaults override the script: %s", joined)
+	}
+	// Directives after the first command are ignored, as sbatch does
+	if !strings.Contains(joined, "--mem=4G") || !strings.Contains(joined, "--nodes=1") {
+		t.Errorf("defaults missing: %s", joined)
+	}
+	if !strings.Contains(output, "Partition:  debug") {
+		t.Errorf("effective partition not shown:\n%s", output)
+	}
+}
+
+func TestBatchScriptJoinsOptionValues(t *testing.T) {
+	client, _ := newFakeClient()
+	script := client.BatchScript("hostname", &slurm.JobOptions{
+		Partition: "gpu",
+		ExtraArgs: []string{"--gres", "gpu:a100:1", "--exclusive", "-C", "a100", "--mail-type=END"},
+	})
+
+	for _, want := range []string{
+		"#SBATCH --partition=gpu\n",
+		"#SBATCH --gres=gpu:a100:1\n",
+		"#SBATCH --exclusive\n",
+		"#SBATCH -C a100\n",
+		"#SBATCH --mail-type=END\n",
+	} {
+		if !strings.Contains(script, want) {
+			t.Errorf("script lacks %q:\n%s", want, script)
+		}
+	}
+	if strings.Contains(script, "#SBATCH gpu:a100:1") || strings.Contains(script, "#SBATCH a100") {
+		t.Errorf("option values on lines of their own:\n%s", script)
+	}
+
+	directives := slurm.ParseScriptDirectives(script)
+	if directives["gres"] != "gpu:a100:1" || directives["C"] != "a100" {
+		t.Errorf("unexpected directives: %v", directives)
+	}
+}
+
+func TestParseCommandStopsAtDoubleDash(t *testing.T) {
+	cmd, err := shell.ParseCommand("submit -J x -- python train.py --lr 0.1", nil)
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	if strings.Join(cmd.Args, " ") != "python train.py --lr 0.1" {
+		t.Errorf("unexpected args: %q", cmd.Args)
+	}
+	if _, ok := cmd.Options["--lr"]; ok || cmd.Options["-J"] != "x" {
+		t.Errorf("unexpected options: %v", cmd.Options)
+	}
+}
+
+func TestParseScriptDirectives(t *testing.T) {
+	directives := slurm.ParseScriptDirectives("#!/bin/bash\n" +
+		"#SBATCH -N2 -c 4\n" +
+		"#SBATCH --job-name sweep --exclusive\n" +
+		"# a comment\n" +
+		"#SBATCH --mem-per-cpu=2G\n")
+
+	want := map[string]string{
+		"nodes": "2", "cpus-per-task": "4", "job-name": "sweep", "exclusive": "", "mem-per-cpu": "2G",
+	}
+	for key, value := range want {
+		if got, ok := directives[key]; !ok || got != value {
+			t.Errorf("%s = %q, want %q", key, got, value)
+		}
+	}
+}
+
+func TestExpandFilenamePattern(t *testing.T) {
+	fields := slurm.FilenameFields{JobID: "1234", Name: "train", User: "alice"}
+	tests := map[string]string{
+		"%x-%j.out":        "train-1234.out",
+		"/home/%u/%j.%%":   "/home/alice/1234.%",
+		"%A_%a.out":        "1234_4294967294.out",
+		"slurm-%N-%8j.err": "slurm-%N-00001234.err",
+	}
+	for pattern, want := range tests {
+		if got := slurm.ExpandFilenamePattern(pattern, fields); got != want {
+			t.Errorf("%s: got %q, want %q", pattern, got, want)
+		}
+	}
+
+	array := slurm.FilenameFields{JobID: "1240", ArrayJobID: "1234", ArrayTaskID: "6"}
+	if got := slurm.ExpandFilenamePattern("%A_%3a", array); got != "1234_006" {
+		t.Errorf("array pattern: got %q", got)
+	}
+}
+
+func TestSubmitExclusiveKeepsScript(t *testing.T) {
+	_, args := runSubmit(t, "submit --exclusive job.sh", config.Default())
+
+	joined := strings.Join(args, " ")
+	if !strings.Contains(joined, "--exclusive") || args[len(args)-1] != "job.sh" {
+		t.Errorf("--exclusive took the script as its value: %s", joined)
+	}
+}
+
+func TestSubmitKeepsExtraOptionOrder(t *testing.T) {
+	for i := 0; i < 20; i++ {
+		_, args := runSubmit(t, "submit --gres gpu:1 --constraint a100 --mail-type END --exclusive job.sh", config.Default())
+		if joined := strings.Join(args, " "); !strings.Contains(joined, "--gres gpu:1 --constraint a100 --mail-type END --exclusive job.sh") {
+			t.Fatalf("options out of order: %s", joined)
+		}
+	}
+}
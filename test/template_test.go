@@ -0,0 +1,171 @@
+package test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"slsh/commands"
+	"slsh/config"
+	"slsh/shell"
+	"slsh/slurm"
+)
+
+// runTemplate runs a template command line against the fake cluster,
+// returning the output and the calls made
+func runTemplate(t *testing.T, line string) (string, []slurm.Call, error) {
+	t.Helper()
+
+	client, runner := newFakeClient()
+	cfg := config.Default()
+	cfg.ColorOutput = false
+	cfg.DefaultOutputDir = filepath.Join(os.Getenv("HOME"), "jobs")
+
+	template := commands.NewTemplateCommand(client, cfg)
+	cmd, err := shell.ParseCommand(line, flagsOf(template))
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+
+	var runErr error
+	output := captureOutput(t, func() {
+		runErr = template.Execute(cmd, nil)
+	})
+	return output, runner.Calls(), runErr
+}
+
+// callArgs returns the arguments of the first call to command
+func callArgs(calls []slurm.Call, command string) []string {
+	for _, call := range calls {
+		if call.Command == command {
+			return call.Args
+		}
+	}
+	return nil
+}
+
+func TestTemplateSaveAndUse(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("USER", "alice")
+
+	if _, _, err := runTemplate(t, `template save gpu-train -p gpu -t 4:00:00 --gres gpu:1 -J train-{{epochs}} -- python train.py --epochs {{epochs}} --lr {{lr}}`); err != nil {
+		t.Fatalf("save failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(config.GetTemplateDir(), "gpu-train.json")); err != nil {
+		t.Fatalf("template not stored in the config directory: %v", err)
+	}
+
+	if _, _, err := runTemplate(t, "template use gpu-train --set epochs=10"); err == nil || !strings.Contains(err.Error(), "lr") {
+		t.Errorf("expected an error for the missing lr, got %v", err)
+	}
+
+	_, calls, err := runTemplate(t, "template use gpu-train --set epochs=10,lr=0.1 -p debug")
+	if err != nil {
+		t.Fatalf("use failed: %v", err)
+	}
+	args := strings.Join(callArgs(calls, "sbatch"), " ")
+	for _, want := range []string{"--partition=debug", "--time=4:00:00", "--job-name=train-10", "--gres gpu:1"} {
+		if !strings.Contains(args, want) {
+			t.Errorf("sbatch arguments lack %s: %s", want, args)
+		}
+	}
+
+	data, err := os.ReadFile(filepath.Join(os.Getenv("HOME"), "jobs", "job_1003.sh"))
+	if err != nil {
+		t.Fatalf("script not saved: %v", err)
+	}
+	if !strings.Contains(string(data), "\npython train.py --epochs 10 --lr 0.1\n") {
+		t.Errorf("placeholders not filled in:\n%s", data)
+	}
+
+	// The same template runs with srun
+	_, calls, err = runTemplate(t, "template use gpu-train --set epochs=1,lr=1 --run")
+	if err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	srun := callArgs(calls, "srun")
+	if len(srun) == 0 || srun[len(srun)-1] != "python train.py --epochs 1 --lr 1" {
+		t.Errorf("unexpected srun arguments: %q", srun)
+	}
+}
+
+func TestTemplateScriptDefaults(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("USER", "alice")
+
+	script := filepath.Join(t.TempDir(), "sim.sh")
+	if err := os.WriteFile(script, []byte("#!/bin/bash\n#SBATCH -N 2\nsrun ./sim --steps {{steps}}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := runTemplate(t, "template save mpi --set steps=100 --description 'MPI run' "+script); err != nil {
+		t.Fatalf("save failed: %v", err)
+	}
+	if _, _, err := runTemplate(t, "template save bad --set nope=1 "+script); err == nil {
+		t.Error("expected an error for a default of an unknown parameter")
+	}
+
+	output, _, err := runTemplate(t, "template")
+	if err != nil {
+		t.Fatalf("list failed: %v", err)
+	}
+	if !strings.Contains(output, "mpi") || !strings.Contains(output, "MPI run") || strings.Contains(output, "bad") {
+		t.Errorf("unexpected template list:\n%s", output)
+	}
+
+	output, _, err = runTemplate(t, "template show mpi")
+	if err != nil {
+		t.Fatalf("show failed: %v", err)
+	}
+	if !strings.Contains(output, "steps       default 100") {
+		t.Errorf("default not shown:\n%s", output)
+	}
+
+	output, calls, err := runTemplate(t, "template use mpi")
+	if err != nil {
+		t.Fatalf("use failed: %v", err)
+	}
+	// The script's own directives win over the configured defaults
+	if args := strings.Join(callArgs(calls, "sbatch"), " "); strings.Contains(args, "--nodes") {
+		t.Errorf("default node count overrides the script: %s", args)
+	}
+	if !strings.Contains(output, "Nodes:      2") {
+		t.Errorf("script's node count not shown:\n%s", output)
+	}
+
+	if _, _, err := runTemplate(t, "template delete mpi"); err != nil {
+		t.Fatalf("delete failed: %v", err)
+	}
+	if _, _, err := runTemplate(t, "template use mpi"); err == nil {
+		t.Error("expected an error for a deleted template")
+	}
+}
+
+func TestTemplateRender(t *testing.T) {
+	tmpl := &config.Template{
+		Name:     "sweep",
+		Options:  slurm.JobOptions{Name: "sweep-{{ lr }}"},
+		Script:   "python train.py --lr {{lr}} --layers {{layers}}",
+		Defaults: map[string]string{"layers": "4"},
+	}
+
+	if got := strings.Join(tmpl.Parameters(), ","); got != "lr,layers" {
+		t.Errorf("unexpected parameters: %s", got)
+	}
+
+	opts, script, err := tmpl.Render(map[string]string{"lr": "0.01"})
+	if err != nil {
+		t.Fatalf("render failed: %v", err)
+	}
+	if opts.Name != "sweep-0.01" || script != "python train.py --lr 0.01 --layers 4" {
+		t.Errorf("unexpected rendering: %q, %q", opts.Name, script)
+	}
+	if tmpl.Options.Name != "sweep-{{ lr }}" {
+		t.Errorf("rendering changed the template: %q", tmpl.Options.Name)
+	}
+
+	if _, _, err := tmpl.Render(map[string]string{"lr": "1", "epochs": "2"}); err == nil {
+		t.Error("expected an error for an unknown parameter")
+	}
+}
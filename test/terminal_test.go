@@ -0,0 +1,81 @@
+package test
+
+import (
+	"runtime"
+	"strings"
+	"testing"
+
+	"slsh/commands"
+	"slsh/config"
+	"slsh/shell"
+	"slsh/slurm"
+)
+
+func TestRunTerminalGivesCommandATTY(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("pseudo-terminals are only supported on Linux")
+	}
+
+	runner := slurm.NewExecRunner()
+	output := captureOutput(t, func() {
+		err := runner.RunTerminal("sh", "-c", "test -t 0 && test -t 1 && echo tty; stty size")
+		if err != nil {
+			t.Errorf("RunTerminal failed: %v", err)
+		}
+	})
+
+	if !strings.Contains(output, "tty") {
+		t.Errorf("command did not see a terminal, output %q", output)
+	}
+}
+
+func TestNeedsTerminal(t *testing.T) {
+	tests := []struct {
+		command string
+		args    []string
+		want    bool
+	}{
+		{"srun", []string{"--pty", "bash"}, true},
+		{"srun", []string{"-N", "2", "hostname"}, false},
+		{"salloc", []string{"-N", "1"}, true},
+		{"sattach", []string{"1001.0"}, true},
+		{"squeue", nil, false},
+	}
+
+	for _, tt := range tests {
+		if got := slurm.NeedsTerminal(tt.command, tt.args); got != tt.want {
+			t.Errorf("NeedsTerminal(%s %v) = %v, want %v", tt.command, tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestRunPTYUsesTerminalPath(t *testing.T) {
+	client, runner := newFakeClient()
+	cfg := config.Default()
+	cfg.ColorOutput = false
+
+	run := commands.NewRunCommand(client, cfg)
+	cmd, err := shell.ParseCommand("run --pty bash", flagsOf(run))
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	if len(cmd.Args) != 1 || cmd.Args[0] != "bash" {
+		t.Fatalf("--pty consumed the command: %+v", cmd)
+	}
+
+	captureOutput(t, func() {
+		if err := run.Execute(cmd, nil); err != nil {
+			t.Fatalf("run --pty failed: %v", err)
+		}
+	})
+
+	calls := runner.Calls()
+	last := calls[len(calls)-1]
+	args := strings.Join(last.Args, " ")
+	if last.Command != "srun" || !strings.Contains(args, "--pty") || !strings.HasSuffix(args, "bash") {
+		t.Errorf("srun called with %v, want --pty ... bash", last.Args)
+	}
+	if strings.Count(args, "--pty") != 1 {
+		t.Errorf("--pty passed more than once: %v", last.Args)
+	}
+}
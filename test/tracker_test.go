@@ -0,0 +1,170 @@
+package test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"slsh/config"
+	"slsh/shell"
+	"slsh/slurm"
+)
+
+// newTracker creates a job tracker for alice on the fake cluster
+func newTracker(t *testing.T, cfg *config.Config) *shell.JobTracker {
+	t.Helper()
+	t.Setenv("USER", "alice")
+
+	client, _ := newFakeClient()
+	return shell.NewJobTracker(client, cfg)
+}
+
+// messages returns the text of notifications
+func messages(notifications []shell.Notification) []string {
+	var msgs []string
+	for _, n := range notifications {
+		msgs = append(msgs, n.String())
+	}
+	return msgs
+}
+
+func TestTrackerReportsStateChanges(t *testing.T) {
+	tracker := newTracker(t, config.Default())
+	tracker.Track("1001", "train")
+	tracker.Track("1002", "")
+	tracker.Track("998", "prep")
+	tracker.Track("999", "")
+
+	if err := tracker.Poll(); err != nil {
+		t.Fatalf("poll failed: %v", err)
+	}
+
+	got := messages(tracker.Drain())
+	want := []string{
+		"job 1001 (train) RUNNING on node001",
+		"job 998 (prep) COMPLETED in 1h02m, exit 0",
+		"job 999 (big model) FAILED: OUT_OF_MEMORY after 5m12s",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got notifications\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	// Finished jobs are forgotten, unchanged jobs are not reported again
+	if tracked := strings.Join(tracker.Tracked(), " "); tracked != "1001 1002" {
+		t.Errorf("still tracking %s, want 1001 1002", tracked)
+	}
+	if err := tracker.Poll(); err != nil {
+		t.Fatalf("poll failed: %v", err)
+	}
+	if got := tracker.Drain(); len(got) != 0 {
+		t.Errorf("unexpected notifications: %v", messages(got))
+	}
+}
+
+func TestTrackerAllJobsWatchesExistingJobs(t *testing.T) {
+	cfg := config.Default()
+	cfg.NotifyAllJobs = true
+	tracker := newTracker(t, cfg)
+
+	if err := tracker.Poll(); err != nil {
+		t.Fatalf("poll failed: %v", err)
+	}
+	if got := tracker.Drain(); len(got) != 0 {
+		t.Errorf("jobs found on the first poll should not be reported: %v", messages(got))
+	}
+	if tracked := strings.Join(tracker.Tracked(), " "); tracked != "1001 1002" {
+		t.Errorf("tracking %s, want alice's jobs 1001 1002", tracked)
+	}
+}
+
+func TestTrackerRunsHook(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "hook.txt")
+	cfg := config.Default()
+	cfg.NotifyHook = `echo "$SLSH_JOB_ID $SLSH_JOB_STATE $SLSH_JOB_EXIT_CODE" > ` + out
+	tracker := newTracker(t, cfg)
+	tracker.Track("998", "prep")
+
+	if err := tracker.Poll(); err != nil {
+		t.Fatalf("poll failed: %v", err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if data, err := os.ReadFile(out); err == nil && len(data) > 0 {
+			if got := strings.TrimSpace(string(data)); got != "998 COMPLETED 0" {
+				t.Errorf("hook got %q", got)
+			}
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatal("hook did not run")
+}
+
+func TestSubmitTracksJob(t *testing.T) {
+	t.Setenv("USER", "alice")
+	sh := newFakeShell(t)
+
+	captureOutput(t, func() {
+		if err := sh.ExecuteDirectCommand("submit train.sh"); err != nil {
+			t.Errorf("submit failed: %v", err)
+		}
+	})
+
+	if tracked := strings.Join(sh.GetTracker().Tracked(), " "); tracked != "1003" {
+		t.Errorf("tracking %q, want the submitted job 1003", tracked)
+	}
+}
+
+func TestNotificationFormat(t *testing.T) {
+	tests := []struct {
+		n    shell.Notification
+		want string
+	}{
+		{shell.Notification{JobID: "7", Name: "a", State: "FAILED", ExitCode: 2, Elapsed: 90 * time.Second, Finished: true},
+			"job 7 (a) FAILED in 1m30s, exit 2"},
+		{shell.Notification{JobID: "7", State: "CANCELLED", Elapsed: 26 * time.Hour, Finished: true},
+			"job 7 CANCELLED after 1d02h"},
+		{shell.Notification{JobID: "7", State: "PENDING", Reason: "Priority"},
+			"job 7 PENDING (Priority)"},
+		{shell.Notification{JobID: "7", Finished: true},
+			"job 7 left the queue; its final state is unknown"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.n.String(); got != tt.want {
+			t.Errorf("got %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestParseSubmittedJobID(t *testing.T) {
+	for output, want := range map[string]string{
+		"Submitted batch job 4242\n":                            "4242",
+		"srun: job 77 queued and waiting for resources":         "77",
+		"salloc: Granted job allocation 9":                      "9",
+		"srun: job 12 has been allocated resources\nhello\n":    "12",
+		"srun: jobid 31: nodes(1):`node001', cpu counts: 4(x1)": "31",
+	} {
+		if got, ok := slurm.ParseSubmittedJobID(output); !ok || got != want {
+			t.Errorf("ParseSubmittedJobID(%q) = %q, want %q", output, got, want)
+		}
+	}
+}
+
+func TestIsSrunVerbose(t *testing.T) {
+	for line, want := range map[string]bool{
+		"srun: jobid 31: nodes(1):`node001', cpu counts: 4(x1)":   true,
+		"srun: launching StepId=31.0 on host node001, 1 tasks: 0": true,
+		"srun: error: node001: task 0: Exited with exit code 1":   false,
+		"srun: job 77 queued and waiting for resources":           false,
+		"srun: Force Terminated JobId=31":                         false,
+		"Traceback (most recent call last):":                      false,
+	} {
+		if got := slurm.IsSrunVerbose(line); got != want {
+			t.Errorf("IsSrunVerbose(%q) = %v, want %v", line, got, want)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package test
+
+import (
+	"strings"
+	"testing"
+
+	"slsh/commands"
+	"slsh/config"
+	"slsh/shell"
+	"slsh/slurm"
+)
+
+// runUsage runs a usage command line against the fake cluster
+func runUsage(t *testing.T, line string, cfg *config.Config) (string, []slurm.Call, error) {
+	t.Helper()
+	t.Setenv("USER", "alice")
+
+	client, runner := newFakeClient()
+	if cfg == nil {
+		cfg = config.Default()
+	}
+	cfg.ColorOutput = false
+
+	usage := commands.NewUsageCommand(client, cfg)
+	cmd, err := shell.ParseCommand(line, flagsOf(usage))
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+
+	output := captureOutput(t, func() { err = usage.Execute(cmd, nil) })
+	return output, runner.Calls(), err
+}
+
+func TestJobRecordTRESCount(t *testing.T) {
+	record := slurm.JobRecord{TRES: "billing=12,cpu=8,gres/gpu:a100=2,gres/gpu:v100=1,mem=32G,node=1"}
+	tests := map[string]float64{
+		"billing":  12,
+		"cpu":      8,
+		"gres/gpu": 3,
+		"node":     1,
+		"energy":   0,
+	}
+	for name, want := range tests {
+		if got := record.TRESCount(name); got != want {
+			t.Errorf("TRESCount(%s) = %v, want %v", name, got, want)
+		}
+	}
+
+	// The total wins over the typed counts
+	record.TRES = "gres/gpu=4,gres/gpu:a100=4"
+	if got := record.TRESCount("gres/gpu"); got != 4 {
+		t.Errorf("TRESCount(gres/gpu) = %v, want 4", got)
+	}
+}
+
+func TestUsageGroupsByDay(t *testing.T) {
+	output, calls, err := runUsage(t, "usage --since 2024-01-14 --until 2024-01-16 --by day", nil)
+	if err != nil {
+		t.Fatalf("usage failed: %v", err)
+	}
+
+	args := strings.Join(callArgs(calls, "sacct"), " ")
+	for _, want := range []string{"-u alice", "-S 2024-01-14T00:00:00", "-E 2024-01-16T00:00:00"} {
+		if !strings.Contains(args, want) {
+			t.Errorf("sacct arguments lack %q: %s", want, args)
+		}
+	}
+
+	// 998 and 999 ran on the 14th; the running 1001 counts until the end
+	// of the period; the pending 1002 has not used anything
+	for _, want := range []string{
+		"2024-01-14  2     4.8        0.1        4.8",
+		"2024-01-15  1     53.9       0.0        53.9",
+		"3 jobs: 58.8 CPU-hours, 0.1 GPU-hours, 58.8 billing-hours",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output lacks %q:\n%s", want, output)
+		}
+	}
+}
+
+func TestUsageChartsPartitions(t *testing.T) {
+	output, _, err := runUsage(t, "usage --since 2024-01-14 --until 2024-01-15 --by partition --chart", nil)
+	if err != nil {
+		t.Fatalf("usage failed: %v", err)
+	}
+	lines := strings.Split(output, "\n")
+	var bars []string
+	for _, line := range lines {
+		if strings.Contains(line, "CPU-hours") && !strings.Contains(line, "jobs:") {
+			bars = append(bars, line)
+		}
+	}
+	if len(bars) != 2 || !strings.HasPrefix(bars[0], "compute  "+strings.Repeat("█", 30)) || !strings.HasPrefix(bars[1], "gpu") {
+		t.Errorf("unexpected chart:\n%s", output)
+	}
+}
+
+func TestUsageBudgets(t *testing.T) {
+	cfg := config.Default()
+	cfg.SetBudget("physics", 50)
+	cfg.SetBudget("chem", 1000)
+
+	output, calls, err := runUsage(t, "usage --since 2024-01-14 --until 2024-01-16 --budget", cfg)
+	if err != nil {
+		t.Fatalf("usage failed: %v", err)
+	}
+
+	// Budgets cover everyone charging the accounts
+	args := strings.Join(callArgs(calls, "sacct"), " ")
+	if !strings.Contains(args, "-A chem,physics") || !strings.Contains(args, "--allusers") || strings.Contains(args, "-u alice") {
+		t.Errorf("unexpected sacct arguments: %s", args)
+	}
+	for _, want := range []string{
+		"chem     0     0.0        0.0        0.0   1000.0  0.0%    1000.0",
+		"physics  3     58.8       0.1        58.8  50.0    117.5%  0.0",
+		"physics is over its budget by 8.8 billing-hours",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output lacks %q:\n%s", want, output)
+		}
+	}
+
+	output, _, err = runUsage(t, "usage --since 2024-01-14 --until 2024-01-16 --budget --chart -A physics", cfg)
+	if err != nil {
+		t.Fatalf("usage failed: %v", err)
+	}
+	if !strings.Contains(output, "physics  "+strings.Repeat("█", 30)+"  117.5% of 50.0 billing-hours") || strings.Contains(output, "chem") {
+		t.Errorf("unexpected budget chart:\n%s", output)
+	}
+
+	if _, _, err := runUsage(t, "usage --budget", nil); err == nil {
+		t.Error("expected an error without budgets")
+	}
+	if _, _, err := runUsage(t, "usage --by week", nil); err == nil {
+		t.Error("expected an error for an unknown grouping")
+	}
+}
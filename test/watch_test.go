@@ -0,0 +1,102 @@
+package test
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"slsh/commands"
+	"slsh/config"
+	"slsh/shell"
+	"slsh/slurm"
+)
+
+// queueSequence answers squeue with the next of its outputs on every call
+type queueSequence struct {
+	mu      sync.Mutex
+	outputs []string
+	calls   int
+}
+
+func (q *queueSequence) Run(ctx context.Context, command string, args ...string) (*slurm.CommandResult, error) {
+	if command == "sinfo" {
+		return &slurm.CommandResult{Success: true, Output: "slurm 22.05.9\n"}, nil
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	output := q.outputs[min(q.calls, len(q.outputs)-1)]
+	q.calls++
+	return &slurm.CommandResult{Success: true, Output: output}, nil
+}
+
+func TestWatchHighlightsStateChanges(t *testing.T) {
+	pending := "1002|PENDING|gpu|alice|2|8|2:00:00|2024-01-15T11:00:00|N/A|N/A|0:00||/home/alice/eval|physics|Resources|eval\n"
+	running := "1002|RUNNING|gpu|alice|2|8|2:00:00|2024-01-15T11:00:00|2024-01-15T11:05:00|N/A|0:01|gpu001|/home/alice/eval|physics|None|eval\n"
+	runner := &queueSequence{outputs: []string{pending, running}}
+	client := slurm.NewClientWithRunner(runner)
+
+	cfg := config.Default()
+	cfg.ColorOutput = false
+	registry := commands.NewRegistry()
+	registry.Register("queue", commands.NewQueueCommand(client, cfg))
+	watch := commands.NewWatchCommand(registry, client, cfg)
+
+	cmd, err := shell.ParseCommand("watch 0.5 queue --all", flagsOf(watch))
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+
+	go func() {
+		time.Sleep(800 * time.Millisecond)
+		client.Interrupt()
+	}()
+
+	output := captureOutput(t, func() {
+		if err := watch.Execute(cmd, nil); err != nil {
+			t.Errorf("watch failed: %v", err)
+		}
+	})
+
+	frames := strings.Split(output, "\033[H\033[2J")
+	if len(frames) < 3 {
+		t.Fatalf("expected at least two refreshes, got output:\n%s", output)
+	}
+	if strings.Contains(frames[1], "*") || !strings.Contains(frames[1], "PENDING") {
+		t.Errorf("first refresh should show the pending job unmarked:\n%s", frames[1])
+	}
+	if !strings.Contains(frames[2], "RUNNING*") {
+		t.Errorf("second refresh should highlight the state change:\n%s", frames[2])
+	}
+	if !strings.HasPrefix(frames[1], "Every 500ms: queue --all") {
+		t.Errorf("unexpected header:\n%s", frames[1])
+	}
+}
+
+func TestQueueWatchKeepsUserArgument(t *testing.T) {
+	cmd, err := shell.ParseCommand("queue --watch alice", nil)
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+
+	client, _ := newFakeClient()
+	cfg := config.Default()
+	cfg.ColorOutput = false
+	queue := commands.NewQueueCommand(client, cfg)
+
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		client.Interrupt()
+	}()
+	output := captureOutput(t, func() {
+		if err := queue.Execute(cmd, nil); err != nil {
+			t.Errorf("queue --watch failed: %v", err)
+		}
+	})
+
+	if !strings.Contains(output, "1001") || strings.Contains(output, "1000") {
+		t.Errorf("expected alice's jobs only:\n%s", output)
+	}
+}
@@ -17,6 +17,7 @@ const (
 	ColorCyan   = "\033[36m"
 	ColorWhite  = "\033[37m"
 	ColorBold   = "\033[1m"
+	ColorInvert = "\033[7m"
 )
 
 // FormatJobState colorizes job states
@@ -153,7 +154,7 @@ func (t *Table) Print() {
 		for i, cell := range row {
 			if i < len(widths) {
 				// Account for ANSI color codes when padding
-				padding := widths[i] + 2 - (len(cell) - len(stripAnsiCodes(cell)))
+				padding := widths[i] + 2 + (len(cell) - len(stripAnsiCodes(cell)))
 				fmt.Printf("%-*s", padding, cell)
 			}
 		}
@@ -180,6 +181,14 @@ func stripAnsiCodes(s string) string {
 	return result
 }
 
+// Highlight marks a changed value, in inverse video or with a trailing '*'
+func Highlight(s string, useColor bool) string {
+	if useColor {
+		return ColorInvert + s + ColorReset
+	}
+	return s + "*"
+}
+
 // FormatSuccess formats success/error messages
 func FormatSuccess(msg string, useColor bool) string {
 	if useColor {
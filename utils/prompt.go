@@ -14,6 +14,10 @@ type Prompt struct {
 	showUser bool
 	showHost bool
 	showCwd  bool
+	
+	// The allocation the shell is inside of, if any, and when it ends
+	allocation    string
+	allocationEnd time.Time
 }
 
 // NewPrompt creates a new prompt with the given template
@@ -75,9 +79,36 @@ func (p *Prompt) Format() string {
 		prompt = strings.ReplaceAll(prompt, "%w", cwd)
 	}
 	
+	// Inside an allocation, show its job ID and remaining walltime
+	if p.allocation != "" {
+		prompt = p.formatAllocation() + prompt
+	}
+	
 	return prompt
 }
 
+// formatAllocation describes the allocation the shell is inside of, as
+// in "[alloc 1234 1:59:30] "
+func (p *Prompt) formatAllocation() string {
+	if p.allocationEnd.IsZero() {
+		return fmt.Sprintf("[alloc %s] ", p.allocation)
+	}
+	
+	remaining := time.Until(p.allocationEnd)
+	if remaining < 0 {
+		remaining = 0
+	}
+	secs := int64(remaining / time.Second)
+	return fmt.Sprintf("[alloc %s %d:%02d:%02d] ", p.allocation, secs/3600, secs/60%60, secs%60)
+}
+
+// SetAllocation shows an allocation in the prompt until it is cleared
+// with an empty job ID. A zero end means the allocation has no limit.
+func (p *Prompt) SetAllocation(jobID string, end time.Time) {
+	p.allocation = jobID
+	p.allocationEnd = end
+}
+
 // SetPrompt sets a new prompt template
 func (p *Prompt) SetPrompt(template string) {
 	p.template = template
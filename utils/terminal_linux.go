@@ -0,0 +1,90 @@
+//go:build linux
+
+package utils
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// TerminalState is a saved terminal mode
+type TerminalState struct {
+	termios syscall.Termios
+}
+
+// IsTerminal reports whether fd refers to a terminal
+func IsTerminal(fd int) bool {
+	_, err := getTermios(fd)
+	return err == nil
+}
+
+// MakeRaw puts the terminal in raw mode, as cfmakeraw(3) does, and
+// returns the previous mode
+func MakeRaw(fd int) (*TerminalState, error) {
+	old, err := getTermios(fd)
+	if err != nil {
+		return nil, err
+	}
+
+	raw := *old
+	raw.Iflag &^= syscall.IGNBRK | syscall.BRKINT | syscall.PARMRK | syscall.ISTRIP |
+		syscall.INLCR | syscall.IGNCR | syscall.ICRNL | syscall.IXON
+	raw.Oflag &^= syscall.OPOST
+	raw.Lflag &^= syscall.ECHO | syscall.ECHONL | syscall.ICANON | syscall.ISIG | syscall.IEXTEN
+	raw.Cflag &^= syscall.CSIZE | syscall.PARENB
+	raw.Cflag |= syscall.CS8
+	raw.Cc[syscall.VMIN] = 1
+	raw.Cc[syscall.VTIME] = 0
+
+	if err := setTermios(fd, &raw); err != nil {
+		return nil, err
+	}
+	return &TerminalState{termios: *old}, nil
+}
+
+// MakeKeyPoll makes single key presses, including Ctrl+C, readable
+// without echo while leaving output untouched. Reads return after a
+// tenth of a second when no key was pressed.
+func MakeKeyPoll(fd int) (*TerminalState, error) {
+	old, err := getTermios(fd)
+	if err != nil {
+		return nil, err
+	}
+
+	poll := *old
+	poll.Lflag &^= syscall.ECHO | syscall.ICANON | syscall.ISIG
+	poll.Cc[syscall.VMIN] = 0
+	poll.Cc[syscall.VTIME] = 1
+
+	if err := setTermios(fd, &poll); err != nil {
+		return nil, err
+	}
+	return &TerminalState{termios: *old}, nil
+}
+
+// RestoreTerminal returns the terminal to a saved mode
+func RestoreTerminal(fd int, state *TerminalState) error {
+	return setTermios(fd, &state.termios)
+}
+
+// getTermios returns the terminal attributes of fd
+func getTermios(fd int) (*syscall.Termios, error) {
+	var state syscall.Termios
+	if err := Ioctl(uintptr(fd), syscall.TCGETS, uintptr(unsafe.Pointer(&state))); err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+// setTermios sets the terminal attributes of fd
+func setTermios(fd int, state *syscall.Termios) error {
+	return Ioctl(uintptr(fd), syscall.TCSETS, uintptr(unsafe.Pointer(state)))
+}
+
+// Ioctl performs an ioctl system call
+func Ioctl(fd, request, arg uintptr) error {
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, request, arg); errno != 0 {
+		return errno
+	}
+	return nil
+}
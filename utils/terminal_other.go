@@ -0,0 +1,32 @@
+//go:build !linux
+
+package utils
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// TerminalState is a saved terminal mode
+type TerminalState struct{}
+
+// IsTerminal reports whether fd refers to a terminal. Terminal modes are
+// only supported on Linux.
+func IsTerminal(fd int) bool {
+	return false
+}
+
+// MakeRaw is only implemented on Linux
+func MakeRaw(fd int) (*TerminalState, error) {
+	return nil, fmt.Errorf("terminal modes are not supported on %s", runtime.GOOS)
+}
+
+// MakeKeyPoll is only implemented on Linux
+func MakeKeyPoll(fd int) (*TerminalState, error) {
+	return nil, fmt.Errorf("terminal modes are not supported on %s", runtime.GOOS)
+}
+
+// RestoreTerminal is only implemented on Linux
+func RestoreTerminal(fd int, state *TerminalState) error {
+	return nil
+}